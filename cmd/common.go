@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap/data"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
@@ -15,15 +17,23 @@ import (
 func Init() {
 	bootstrap.InitConfig()
 	bootstrap.Log()
+	bootstrap.InitTracing()
 	bootstrap.InitDB()
 	data.InitData()
 	bootstrap.InitStreamLimit()
+	bootstrap.InitDiskCache()
+	bootstrap.InitThumbnail()
+	bootstrap.InitHLS()
+	bootstrap.InitStorageHealth()
 	bootstrap.InitIndex()
 	bootstrap.InitUpgradePatch()
 }
 
 func Release() {
 	db.Close()
+	if err := tracing.Shutdown(context.Background()); err != nil {
+		log.Errorf("failed to flush traces: %+v", err)
+	}
 }
 
 var pid = -1
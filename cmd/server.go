@@ -17,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
@@ -155,6 +156,20 @@ the address is defined in config file`,
 				}
 			}()
 		}
+		var metricsSrv *http.Server
+		if conf.Conf.Metrics.Enable && conf.Conf.Metrics.Listen != "" {
+			fmt.Printf("start metrics server @ %s\n", conf.Conf.Metrics.Listen)
+			utils.Log.Infof("start metrics server @ %s", conf.Conf.Metrics.Listen)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler(conf.Conf.Metrics.Token))
+			metricsSrv = &http.Server{Addr: conf.Conf.Metrics.Listen, Handler: mux}
+			go func() {
+				err := metricsSrv.ListenAndServe()
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					utils.Log.Fatalf("failed to start metrics server: %s", err.Error())
+				}
+			}()
+		}
 		var ftpDriver *server.FtpMainDriver
 		var ftpServer *ftpserver.FtpServer
 		if conf.Conf.FTP.Listen != "" && conf.Conf.FTP.Enable {
@@ -243,6 +258,15 @@ the address is defined in config file`,
 				}
 			}()
 		}
+		if metricsSrv != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := metricsSrv.Shutdown(ctx); err != nil {
+					utils.Log.Fatal("metrics server shutdown err: ", err)
+				}
+			}()
+		}
 		if conf.Conf.FTP.Listen != "" && conf.Conf.FTP.Enable && ftpServer != nil && ftpDriver != nil {
 			wg.Add(1)
 			go func() {
@@ -16,6 +16,8 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive_open"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive_share"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/archive_mount"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/autoindex"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/azure_blob"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/baidu_netdisk"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/baidu_photo"
@@ -24,6 +26,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/cloudreve"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/cloudreve_v4"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/cnb_releases"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/compress"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/crypt"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/degoo"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/doubao"
@@ -65,16 +68,20 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/sftp"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/smb"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/strm"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/swift"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/teambition"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/teldrive"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/terabox"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunder"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunder_browser"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunderx"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/union"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/url_tree"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/uss"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/versioning"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/virtual"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/webdav"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/webhdfs"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/weiyun"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/wopan"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/yandex_disk"
@@ -0,0 +1,157 @@
+package archive_mount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+)
+
+// ArchiveMount presents the content of an archive file as a read-only storage
+type ArchiveMount struct {
+	model.Storage
+	Addition
+}
+
+func (d *ArchiveMount) Config() driver.Config {
+	return config
+}
+
+func (d *ArchiveMount) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *ArchiveMount) Init(ctx context.Context) error {
+	d.ArchivePath = utils.FixAndCleanPath(d.ArchivePath)
+	if d.ArchivePath == "/" {
+		return errors.New("archive path is required")
+	}
+	if utils.IsSubPath(d.MountPath, d.ArchivePath) {
+		return errors.New("the archive should not be in the storage itself")
+	}
+	return nil
+}
+
+func (d *ArchiveMount) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *ArchiveMount) innerArgs(innerPath string) model.ArchiveInnerArgs {
+	return model.ArchiveInnerArgs{
+		ArchiveArgs: model.ArchiveArgs{Password: d.Password},
+		InnerPath:   innerPath,
+	}
+}
+
+func (d *ArchiveMount) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	_, obj, err := op.ArchiveGet(ctx, storage, actualPath, model.ArchiveListArgs{
+		ArchiveInnerArgs: d.innerArgs(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.Object{
+		Path:     path,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Ctime:    obj.CreateTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}, nil
+}
+
+func (d *ArchiveMount) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := op.ListArchive(ctx, storage, actualPath, model.ArchiveListArgs{
+		ArchiveInnerArgs: d.innerArgs(dir.GetPath()),
+		Refresh:          args.Refresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, &model.Object{
+			Path:     stdpath.Join(dir.GetPath(), obj.GetName()),
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			Ctime:    obj.CreateTime(),
+			IsFolder: obj.IsDir(),
+			HashInfo: obj.GetHash(),
+		})
+	}
+	return result, nil
+}
+
+func (d *ArchiveMount) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// proxy || ftp,s3
+	if common.GetApiUrl(ctx) == "" {
+		args.Redirect = false
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	innerArgs := d.innerArgs(file.GetPath())
+	innerArgs.LinkArgs = args
+	// the storage of the archive may extract it by itself
+	link, _, err := op.DriverExtract(ctx, storage, actualPath, innerArgs)
+	if err == nil {
+		if link.URL != "" || !args.Redirect {
+			resultLink := *link
+			resultLink.SyncClosers = utils.NewSyncClosers(link)
+			if resultLink.ContentLength == 0 {
+				resultLink.ContentLength = file.GetSize()
+			}
+			return &resultLink, nil
+		}
+		_ = link.Close()
+	} else if !errors.Is(err, errs.DriverExtractNotSupported) {
+		return nil, err
+	}
+	if args.Redirect {
+		path := stdpath.Join(d.MountPath, file.GetPath())
+		return &model.Link{
+			URL: fmt.Sprintf("%s/p%s?sign=%s",
+				common.GetApiUrl(ctx),
+				utils.EncodePath(path, true),
+				sign.Sign(path)),
+		}, nil
+	}
+	innerArgs.LinkArgs = model.LinkArgs{}
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			return op.InternalExtractRange(ctx, storage, actualPath, innerArgs, httpRange)
+		}),
+		ContentLength: file.GetSize(),
+	}, nil
+}
+
+var _ driver.Driver = (*ArchiveMount)(nil)
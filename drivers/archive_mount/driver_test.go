@@ -0,0 +1,155 @@
+package archive_mount
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/archive"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/kdomanski/iso9660"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	dir, err := os.MkdirTemp("", "archive_mount")
+	if err != nil {
+		panic(err)
+	}
+	conf.Conf = conf.DefaultConfig(dir)
+	if err = os.MkdirAll(conf.Conf.TempDir, 0o777); err != nil {
+		panic(err)
+	}
+	db.Init(dB)
+}
+
+func writeISO(t *testing.T, path string, files map[string]string) {
+	w, err := iso9660.NewWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Cleanup()
+	for name, content := range files {
+		if err = w.AddFile(strings.NewReader(content), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = w.WriteTo(f, "test"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mount(t *testing.T) (string, *ArchiveMount) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	localId, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/am_local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeISO(t, dir+"/test.iso", map[string]string{"a.txt": "0123456789", "b.txt": "abcdefghij"})
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "ArchiveMount",
+		MountPath: "/am",
+		Addition:  `{"archive_path":"/am_local/test.iso"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = op.DeleteStorageById(context.Background(), id)
+		_ = op.DeleteStorageById(context.Background(), localId)
+	})
+	storage, err := op.GetStorageByMountPath("/am")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, storage.(*ArchiveMount)
+}
+
+func readRange(t *testing.T, d *ArchiveMount, path string, start, length int64) string {
+	ctx := context.Background()
+	obj, err := d.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := d.Link(ctx, obj, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.Close()
+	rc, err := link.RangeReader.RangeRead(ctx, http_range.Range{Start: start, Length: length})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRangeRead(t *testing.T) {
+	dir, d := mount(t)
+	objs, err := d.List(context.Background(), &model.Object{Path: "/", IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("listed %d objects", len(objs))
+	}
+	a := "/" + objs[0].GetName()
+	for _, c := range []struct {
+		start, length int64
+		want          string
+	}{
+		{0, -1, "0123456789"},
+		{3, 4, "3456"},
+		{8, 10, "89"},
+	} {
+		if got := readRange(t, d, a, c.start, c.length); got != c.want {
+			t.Errorf("range %d+%d: %q, want %q", c.start, c.length, got, c.want)
+		}
+	}
+	if got := readRange(t, d, "/"+objs[1].GetName(), 2, 3); got != "cde" {
+		t.Errorf("second file: %q", got)
+	}
+
+	// the located extents are dropped once the archive changes
+	writeISO(t, dir+"/test.iso", map[string]string{"a.txt": "changed content"})
+	modified := time.Now().Add(time.Minute)
+	if err = os.Chtimes(dir+"/test.iso", modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	archive, _, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op.Cache.DeleteDirectory(archive, "/")
+	if got := readRange(t, d, a, 0, -1); got != "changed content" {
+		t.Errorf("stale extent is read: %q", got)
+	}
+}
@@ -0,0 +1,27 @@
+package archive_mount
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	ArchivePath string `json:"archive_path" required:"true" help:"The archive file in OpenList, the first part of a multi-part archive"`
+	Password    string `json:"password" help:"Password of an encrypted archive"`
+}
+
+var config = driver.Config{
+	Name:             "ArchiveMount",
+	LocalSort:        true,
+	NoCache:          true,
+	NoUpload:         true,
+	DefaultRoot:      "/",
+	CheckStatus:      true,
+	ProxyRangeOption: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &ArchiveMount{}
+	})
+}
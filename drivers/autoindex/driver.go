@@ -0,0 +1,159 @@
+package autoindex
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+// AutoIndex is a read-only storage crawling the directory listings of a web server
+type AutoIndex struct {
+	model.Storage
+	Addition
+	base   *url.URL
+	header http.Header
+}
+
+func (d *AutoIndex) Config() driver.Config {
+	return config
+}
+
+func (d *AutoIndex) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *AutoIndex) Init(ctx context.Context) error {
+	if !strings.HasSuffix(d.URL, "/") {
+		d.URL += "/"
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return err
+	}
+	d.base = u
+	d.header = http.Header{}
+	for _, line := range strings.Split(d.Headers, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(k) != "" {
+			d.header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	if d.Username != "" || d.Password != "" {
+		d.header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(d.Username+":"+d.Password)))
+	}
+	_, err = d.index(ctx, d.base)
+	return err
+}
+
+func (d *AutoIndex) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *AutoIndex) request(ctx context.Context) *resty.Request {
+	return base.RestyClient.R().SetContext(ctx).SetHeaderMultiValues(d.header)
+}
+
+func (d *AutoIndex) index(ctx context.Context, dir *url.URL) ([]entry, error) {
+	res, err := d.request(ctx).
+		SetHeader("Accept", "text/html, application/json;q=0.9").
+		Get(dir.String())
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to get the index of %s: %s", dir, res.Status())
+	}
+	return parseIndex(dir, res.Header().Get("Content-Type"), res.Body())
+}
+
+// head fills the sizes and mtimes missing in the index by HEAD requests
+func (d *AutoIndex) head(ctx context.Context, entries []entry) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i := range entries {
+		e := &entries[i]
+		if e.IsDir || e.Size >= 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := d.request(ctx).Head(e.URL)
+			if err != nil || res.IsError() {
+				return
+			}
+			e.Size = max(res.RawResponse.ContentLength, 0)
+			if e.Modified.IsZero() {
+				e.Modified, _ = http.ParseTime(res.Header().Get("Last-Modified"))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *AutoIndex) dirURL(dir model.Obj) (*url.URL, error) {
+	if u, ok := model.GetUrl(dir); ok {
+		return url.Parse(u)
+	}
+	return d.base.Parse(strings.TrimPrefix(utils.EncodePath(dir.GetPath(), true), "/") + "/")
+}
+
+func (d *AutoIndex) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	u, err := d.dirURL(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := d.index(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if d.HeadSize {
+		d.head(ctx, entries)
+	}
+	objs := make([]model.Obj, 0, len(entries))
+	for _, e := range entries {
+		objs = append(objs, &model.ObjectURL{
+			Object: model.Object{
+				Path:     stdpath.Join(dir.GetPath(), e.Name),
+				Name:     e.Name,
+				Size:     max(e.Size, 0),
+				Modified: e.Modified,
+				IsFolder: e.IsDir,
+			},
+			Url: model.Url{Url: e.URL},
+		})
+	}
+	return objs, nil
+}
+
+func (d *AutoIndex) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	u, ok := model.GetUrl(file)
+	if !ok {
+		ref, err := d.base.Parse(strings.TrimPrefix(utils.EncodePath(file.GetPath(), true), "/"))
+		if err != nil {
+			return nil, err
+		}
+		u = ref.String()
+	}
+	return &model.Link{
+		URL:    u,
+		Header: d.header.Clone(),
+	}, nil
+}
+
+var _ driver.Driver = (*AutoIndex)(nil)
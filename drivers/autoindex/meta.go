@@ -0,0 +1,32 @@
+package autoindex
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	URL      string `json:"url" required:"true" help:"The dir of the index pages, e.g. https://mirror.example.com/pub/"`
+	Username string `json:"username" help:"Basic auth"`
+	Password string `json:"password"`
+	Headers  string `json:"headers" type:"text" help:"Sent with every request, one 'Key: Value' per line"`
+	HeadSize bool   `json:"head_size" type:"bool" default:"true" help:"Use head method to get the sizes missing in the index pages"`
+}
+
+var config = driver.Config{
+	Name:        "AutoIndex",
+	LocalSort:   true,
+	NoUpload:    true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &AutoIndex{
+			Addition: Addition{
+				HeadSize: true,
+			},
+		}
+	})
+}
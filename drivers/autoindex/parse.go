@@ -0,0 +1,229 @@
+package autoindex
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// entry is a file or dir found in an index page, Size is -1 if unknown
+type entry struct {
+	Name     string
+	URL      string
+	Size     int64
+	Modified time.Time
+	IsDir    bool
+}
+
+var (
+	dateRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(?::\d{2})?|\d{2}-[A-Za-z]{3}-\d{4} \d{2}:\d{2}(?::\d{2})?|\d{4}-[A-Za-z]{3}-\d{2} \d{2}:\d{2}(?::\d{2})?`)
+	sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGTP]?)(?:i?B)?$`)
+
+	dateLayouts = []string{
+		"2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02T15:04:05",
+		"02-Jan-2006 15:04", "02-Jan-2006 15:04:05",
+		"2006-Jan-02 15:04", "2006-Jan-02 15:04:05",
+	}
+)
+
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseSize parses the sizes like 1234, 1.2K or 3 MiB, it returns -1 for the others
+func parseSize(s string) int64 {
+	m := sizeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return -1
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return -1
+	}
+	if m[2] != "" {
+		n *= float64(int64(1) << (10 * (strings.Index("KMGTP", m[2]) + 1)))
+	}
+	return int64(n)
+}
+
+// parseDetails finds the mtime and size in the text following a link
+func parseDetails(e *entry, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	loc := dateRe.FindStringIndex(text)
+	if loc == nil {
+		return
+	}
+	if e.Modified.IsZero() {
+		e.Modified = parseDate(text[loc[0]:loc[1]])
+	}
+	if e.Size >= 0 || e.IsDir {
+		return
+	}
+	fields := strings.Fields(text[loc[1]:])
+	if len(fields) == 0 {
+		return
+	}
+	// the unit may be a separated field
+	if len(fields) > 1 && sizeRe.MatchString(fields[0]+fields[1]) && !sizeRe.MatchString(fields[1]) {
+		e.Size = parseSize(fields[0] + fields[1])
+		return
+	}
+	e.Size = parseSize(fields[0])
+}
+
+// child resolves the href against the dir, only the direct children of the dir are kept
+func child(dir *url.URL, href string) (name string, u *url.URL, isDir bool, ok bool) {
+	if href == "" || strings.HasPrefix(href, "?") || strings.HasPrefix(href, "#") {
+		return "", nil, false, false
+	}
+	ref, err := url.Parse(href)
+	if err != nil || ref.RawQuery != "" {
+		return "", nil, false, false
+	}
+	u = dir.ResolveReference(ref)
+	u.Fragment = ""
+	if u.Scheme != dir.Scheme || u.Host != dir.Host || !strings.HasPrefix(u.Path, dir.Path) {
+		return "", nil, false, false
+	}
+	rest := strings.TrimPrefix(u.Path, dir.Path)
+	isDir = strings.HasSuffix(rest, "/")
+	rest = strings.TrimSuffix(rest, "/")
+	if rest == "" || strings.Contains(rest, "/") {
+		return "", nil, false, false
+	}
+	return rest, u, isDir, true
+}
+
+// parseHTML parses the autoindex pages of Apache, nginx, lighttpd and Caddy,
+// the details of an entry are in the text between its link and the next one, or the end of its row
+func parseHTML(dir *url.URL, r io.Reader) ([]entry, error) {
+	z := html.NewTokenizer(r)
+	var entries []entry
+	seen := make(map[string]bool)
+	var cur *entry
+	var text strings.Builder
+	finish := func() {
+		if cur != nil {
+			parseDetails(cur, text.String())
+			entries = append(entries, *cur)
+			cur = nil
+		}
+		text.Reset()
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				finish()
+				return entries, nil
+			}
+			return nil, z.Err()
+		case html.TextToken:
+			if cur != nil {
+				text.Write(z.Text())
+				text.WriteByte(' ')
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := make(map[string]string)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+			switch string(name) {
+			case "a":
+				n, u, isDir, ok := child(dir, attrs["href"])
+				if !ok || seen[n] {
+					continue
+				}
+				finish()
+				seen[n] = true
+				cur = &entry{Name: n, URL: u.String(), Size: -1, IsDir: isDir}
+			case "tr":
+				finish()
+			case "time":
+				// caddy
+				if cur != nil && cur.Modified.IsZero() {
+					if t, err := time.Parse(time.RFC3339, attrs["datetime"]); err == nil {
+						cur.Modified = t
+					}
+				}
+			}
+			if s, ok := attrs["data-size"]; ok && cur != nil && !cur.IsDir {
+				if size, err := strconv.ParseInt(s, 10, 64); err == nil {
+					cur.Size = size
+				}
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "tr" {
+				finish()
+			}
+		}
+	}
+}
+
+type jsonEntry struct {
+	Name string `json:"name"`
+	// nginx
+	Type  string `json:"type"`
+	Mtime string `json:"mtime"`
+	Size  *int64 `json:"size"`
+	// caddy
+	URL     string    `json:"url"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+// parseJSON parses the json autoindex of nginx and the json browse of Caddy
+func parseJSON(dir *url.URL, data []byte) ([]entry, error) {
+	var items []jsonEntry
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(items))
+	for _, item := range items {
+		isDir := item.IsDir || item.Type == "directory"
+		href := item.URL
+		if href == "" {
+			href = url.PathEscape(strings.TrimSuffix(item.Name, "/"))
+			if isDir {
+				href += "/"
+			}
+		}
+		name, u, _, ok := child(dir, href)
+		if !ok {
+			continue
+		}
+		e := entry{Name: name, URL: u.String(), Size: -1, Modified: item.ModTime, IsDir: isDir}
+		if item.Size != nil && !isDir {
+			e.Size = *item.Size
+		}
+		if t, err := http.ParseTime(item.Mtime); err == nil {
+			e.Modified = t
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseIndex(dir *url.URL, contentType string, data []byte) ([]entry, error) {
+	if strings.Contains(contentType, "json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return parseJSON(dir, data)
+	}
+	return parseHTML(dir, bytes.NewReader(data))
+}
@@ -0,0 +1,80 @@
+package autoindex
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const apacheIndex = `<html><body><h1>Index of /pub</h1>
+<table>
+<tr><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=M;O=A">Last modified</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+<tr><td valign="top"><img src="/icons/back.gif" alt="[PARENTDIR]"></td><td><a href="/">Parent Directory</a></td><td>&nbsp;</td><td align="right">  - </td></tr>
+<tr><td valign="top"><img src="/icons/folder.gif" alt="[DIR]"></td><td><a href="docs/">docs/</a></td><td align="right">2024-03-01 10:20  </td><td align="right">  - </td></tr>
+<tr><td valign="top"><img src="/icons/tar.gif" alt="[   ]"></td><td><a href="a%20b.tar.gz">a b.tar.gz</a></td><td align="right">2024-03-02 11:30  </td><td align="right">1.5K</td></tr>
+</table></body></html>`
+
+const nginxIndex = `<html><head><title>Index of /pub/</title></head><body><h1>Index of /pub/</h1><hr><pre><a href="../">../</a>
+<a href="docs/">docs/</a>                                              01-Mar-2024 10:20                   -
+<a href="a%20b.tar.gz">a b.tar.gz</a>                                         02-Mar-2024 11:30                1536
+<a href="very-long-name-that-nginx-truncates.iso">very-long-name-that-nginx-trunc..&gt;</a> 02-Mar-2024 11:30          3221225472
+</pre><hr></body></html>`
+
+const lighttpdIndex = `<table summary="Directory Listing"><tbody>
+<tr class="d"><td class="n"><a href="../">Parent Directory</a>/</td><td class="m">&nbsp;</td><td class="s">- &nbsp;</td><td class="t">Directory</td></tr>
+<tr class="d"><td class="n"><a href="docs/">docs</a>/</td><td class="m">2024-Mar-01 10:20:00</td><td class="s">- &nbsp;</td><td class="t">Directory</td></tr>
+<tr><td class="n"><a href="a%20b.tar.gz">a b.tar.gz</a></td><td class="m">2024-Mar-02 11:30:00</td><td class="s">1.5K</td><td class="t">application/x-gzip</td></tr>
+</tbody></table>`
+
+const caddyIndex = `<table><tbody>
+<tr class="file"><td><a href="./docs/"><span class="name">docs</span></a></td><td data-order="-1">&mdash;</td><td class="timestamp"><time datetime="2024-03-01T10:20:00Z">03/01/2024</time></td></tr>
+<tr class="file"><td><a href="./a%20b.tar.gz"><span class="name">a b.tar.gz</span></a></td><td class="size" data-size="1536"><div class="sizebar">1.5 KiB</div></td><td class="timestamp"><time datetime="2024-03-02T11:30:00Z">03/02/2024</time></td></tr>
+</tbody></table>`
+
+const nginxJSON = `[
+{ "name":"docs", "type":"directory", "mtime":"Fri, 01 Mar 2024 10:20:00 GMT" },
+{ "name":"a b.tar.gz", "type":"file", "mtime":"Sat, 02 Mar 2024 11:30:00 GMT", "size":1536 }
+]`
+
+const caddyJSON = `[{"name":"docs/","size":4096,"url":"./docs/","mod_time":"2024-03-01T10:20:00Z","mode":2147484141,"is_dir":true,"is_symlink":false},
+{"name":"a b.tar.gz","size":1536,"url":"./a%20b.tar.gz","mod_time":"2024-03-02T11:30:00Z","mode":420,"is_dir":false,"is_symlink":false}]`
+
+func TestParseIndex(t *testing.T) {
+	dir, _ := url.Parse("https://mirror.example.com/pub/")
+	dirTime := time.Date(2024, 3, 1, 10, 20, 0, 0, time.UTC)
+	fileTime := time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name, contentType, body string
+	}{
+		{"apache", "text/html", apacheIndex},
+		{"nginx", "text/html", nginxIndex},
+		{"lighttpd", "text/html", lighttpdIndex},
+		{"caddy", "text/html", caddyIndex},
+		{"nginx json", "application/json", nginxJSON},
+		{"caddy json", "application/json", caddyJSON},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			entries, err := parseIndex(dir, c.contentType, []byte(c.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) < 2 {
+				t.Fatalf("entries = %+v", entries)
+			}
+			d, f := entries[0], entries[1]
+			if d.Name != "docs" || !d.IsDir || d.URL != "https://mirror.example.com/pub/docs/" || !d.Modified.Equal(dirTime) {
+				t.Errorf("dir = %+v", d)
+			}
+			if f.Name != "a b.tar.gz" || f.IsDir || f.Size != 1536 || !f.Modified.Equal(fileTime) ||
+				f.URL != "https://mirror.example.com/pub/a%20b.tar.gz" {
+				t.Errorf("file = %+v", f)
+			}
+			if strings.HasPrefix(c.name, "nginx") && len(entries) == 3 {
+				if l := entries[2]; l.Name != "very-long-name-that-nginx-truncates.iso" || l.Size != 3221225472 {
+					t.Errorf("truncated = %+v", l)
+				}
+			}
+		})
+	}
+}
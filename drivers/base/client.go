@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -26,6 +27,7 @@ func InitClient() {
 	).SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
 	NoRedirectClient.SetHeader("user-agent", UserAgent)
 	net.SetRestyProxyIfConfigured(NoRedirectClient)
+	NoRedirectClient.SetTransport(tracing.Transport(NoRedirectClient.GetClient().Transport))
 
 	RestyClient = NewRestyClient()
 	HttpClient = net.NewHttpClient()
@@ -40,5 +42,7 @@ func NewRestyClient() *resty.Client {
 		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
 
 	net.SetRestyProxyIfConfigured(client)
+	// the proxy and tls config above are set on the wrapped transport
+	client.SetTransport(tracing.Transport(client.GetClient().Transport))
 	return client
 }
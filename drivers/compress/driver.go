@@ -0,0 +1,314 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type Compress struct {
+	model.Storage
+	Addition
+	enc       encoder
+	skipExts  map[string]struct{}
+	frameSize int
+}
+
+func (d *Compress) Config() driver.Config {
+	return config
+}
+
+func (d *Compress) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Compress) Init(ctx context.Context) error {
+	d.RemotePath = utils.FixAndCleanPath(d.RemotePath)
+	d.Algorithm = utils.GetNoneEmpty(d.Algorithm, "zstd")
+	enc, err := newEncoder(d.Algorithm, d.Level)
+	if err != nil {
+		return err
+	}
+	d.enc = enc
+	if d.FrameSize <= 0 {
+		d.FrameSize = 1024
+	}
+	d.frameSize = d.FrameSize * utils.KB
+	if d.MinRatio <= 0 {
+		d.MinRatio = 90
+	}
+	d.skipExts = make(map[string]struct{})
+	for _, ext := range strings.Split(d.SkipExtensions, ",") {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			d.skipExts[ext] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (d *Compress) Drop(ctx context.Context) error {
+	return nil
+}
+
+// toObj converts an object of the remote storage in remoteDir
+func (d *Compress) toObj(remoteDir string, obj model.Obj) model.Obj {
+	rawName := model.UnwrapObj(obj).GetName()
+	res := &model.Object{
+		Path:     stdpath.Join(remoteDir, rawName),
+		Name:     rawName,
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Ctime:    obj.CreateTime(),
+		IsFolder: obj.IsDir(),
+	}
+	if obj.IsDir() {
+		return res
+	}
+	if info, ok := parseName(rawName); ok {
+		res.Name = info.name
+		res.Size = info.size
+		res.Modified = info.modified
+		return res
+	}
+	// stored as it is
+	res.HashInfo = obj.GetHash()
+	return res
+}
+
+func (d *Compress) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	remoteFullPath := dir.GetPath()
+	objs, err := fs.List(ctx, remoteFullPath, &fs.ListArgs{NoLog: true, Refresh: args.Refresh})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, d.toObj(remoteFullPath, obj))
+	}
+	return result, nil
+}
+
+func (d *Compress) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     d.RemotePath,
+		}, nil
+	}
+	remoteFullPath := stdpath.Join(d.RemotePath, path)
+	remoteDir, name := stdpath.Split(remoteFullPath)
+	remoteObj, err := fs.Get(ctx, remoteFullPath, &fs.GetArgs{NoLog: true})
+	if err == nil {
+		return d.toObj(remoteDir, remoteObj), nil
+	}
+	if !errs.IsObjectNotFound(err) {
+		return nil, err
+	}
+	// the name of a compressed file has a suffix
+	objs, lErr := fs.List(ctx, remoteDir, &fs.ListArgs{NoLog: true})
+	if lErr != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if obj.IsDir() {
+			continue
+		}
+		if info, ok := parseName(model.UnwrapObj(obj).GetName()); ok && info.name == name {
+			return d.toObj(remoteDir, obj), nil
+		}
+	}
+	return nil, err
+}
+
+func (d *Compress) Link(ctx context.Context, file model.Obj, _ model.LinkArgs) (*model.Link, error) {
+	remoteStorage, remoteActualPath, err := op.GetStorageAndActualPath(file.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	remoteLink, remoteFile, err := op.Link(ctx, remoteStorage, remoteActualPath, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	info, ok := parseName(stdpath.Base(file.GetPath()))
+	if !ok {
+		resultLink := *remoteLink
+		resultLink.SyncClosers = utils.NewSyncClosers(remoteLink)
+		return &resultLink, nil
+	}
+
+	remoteSize := remoteLink.ContentLength
+	if remoteSize <= 0 {
+		remoteSize = remoteFile.GetSize()
+	}
+	rrf, err := stream.GetRangeReaderFromLink(remoteSize, remoteLink)
+	if err != nil {
+		_ = remoteLink.Close()
+		return nil, err
+	}
+	enc, err := newEncoder(info.algorithm, "")
+	if err != nil {
+		_ = remoteLink.Close()
+		return nil, err
+	}
+	table, err := readSeekTable(ctx, rrf, remoteSize, enc)
+	if err != nil {
+		_ = remoteLink.Close()
+		return nil, fmt.Errorf("failed to read seek table of %s: %w", file.GetPath(), err)
+	}
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			return rangeRead(ctx, rrf, table, enc, httpRange)
+		}),
+		ContentLength:    table.size(),
+		SyncClosers:      utils.NewSyncClosers(remoteLink),
+		RequireReference: remoteLink.RequireReference,
+	}, nil
+}
+
+func (d *Compress) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	remoteStorage, remoteActualPath, err := op.GetStorageAndActualPath(parentDir.GetPath())
+	if err != nil {
+		return err
+	}
+	return op.MakeDir(ctx, remoteStorage, stdpath.Join(remoteActualPath, dirName))
+}
+
+func (d *Compress) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	_, err := fs.Move(ctx, srcObj.GetPath(), dstDir.GetPath())
+	return err
+}
+
+func (d *Compress) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	remoteStorage, remoteActualPath, err := op.GetStorageAndActualPath(srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	if info, ok := parseName(stdpath.Base(remoteActualPath)); ok && !srcObj.IsDir() {
+		info.name = newName
+		newName = info.rawName()
+	}
+	return op.Rename(ctx, remoteStorage, remoteActualPath, newName)
+}
+
+func (d *Compress) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	_, err := fs.Copy(ctx, srcObj.GetPath(), dstDir.GetPath())
+	return err
+}
+
+func (d *Compress) Remove(ctx context.Context, obj model.Obj) error {
+	remoteStorage, remoteActualPath, err := op.GetStorageAndActualPath(obj.GetPath())
+	if err != nil {
+		return err
+	}
+	return op.Remove(ctx, remoteStorage, remoteActualPath)
+}
+
+func (d *Compress) skip(name string) bool {
+	_, ok := d.skipExts[strings.ToLower(strings.TrimPrefix(stdpath.Ext(name), "."))]
+	return ok
+}
+
+func (d *Compress) Put(ctx context.Context, dstDir model.Obj, streamer model.FileStreamer, up driver.UpdateProgress) error {
+	remoteStorage, remoteActualPath, err := op.GetStorageAndActualPath(dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	rawName := streamer.GetName()
+	out := &stream.FileStream{
+		Obj:      streamer,
+		Reader:   streamer,
+		Mimetype: streamer.GetMimetype(),
+	}
+	if size := streamer.GetSize(); size > 0 && !d.skip(rawName) {
+		frameSize := d.frameSize
+		if d.Algorithm == "gzip" {
+			frameSize = max(frameSize, int((size+maxGzipFrames-1)/maxGzipFrames))
+		}
+		// sample the first frame to skip the incompressible files
+		first := make([]byte, min(int64(frameSize), size))
+		n, err := io.ReadFull(streamer, first)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		first = first[:n]
+		firstFrame, err := d.enc.frame(nil, first)
+		if err != nil {
+			return err
+		}
+		if len(firstFrame)*100 < len(first)*d.MinRatio {
+			tmp, err := os.CreateTemp(conf.Conf.TempDir, "compress-*")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = tmp.Close()
+				_ = os.Remove(tmp.Name())
+			}()
+			if _, err = compressFrames(tmp, streamer, d.enc, frameSize, firstFrame, n); err != nil {
+				return err
+			}
+			compressedSize, err := tmp.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			info := &compressedInfo{name: streamer.GetName(), size: size, modified: streamer.ModTime(), algorithm: d.Algorithm}
+			rawName = info.rawName()
+			out = &stream.FileStream{
+				Obj: &model.Object{
+					Name:     rawName,
+					Size:     compressedSize,
+					Modified: streamer.ModTime(),
+				},
+				Reader:   tmp,
+				Mimetype: "application/octet-stream",
+			}
+		} else {
+			out.Reader = io.MultiReader(bytes.NewReader(first), streamer)
+		}
+	}
+	if err = op.Put(ctx, remoteStorage, remoteActualPath, out, up); err != nil {
+		return err
+	}
+	// the overwritten file may be stored under another name
+	if exist := streamer.GetExist(); exist != nil && stdpath.Base(exist.GetPath()) != rawName {
+		if err := op.Remove(ctx, remoteStorage, stdpath.Join(remoteActualPath, stdpath.Base(exist.GetPath()))); err != nil {
+			log.Warnf("failed to remove the overwritten file %s: %+v", exist.GetPath(), err)
+		}
+	}
+	return nil
+}
+
+func (d *Compress) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	remoteStorage, _, err := op.GetStorageAndActualPath(d.RemotePath)
+	if err != nil {
+		return nil, errs.NotImplement
+	}
+	remoteDetails, err := op.GetStorageDetails(ctx, remoteStorage)
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		DiskUsage: remoteDetails.DiskUsage,
+	}, nil
+}
+
+var _ driver.Driver = (*Compress)(nil)
@@ -0,0 +1,312 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A compressed file is a series of independent frames followed by a seek table,
+// the layout of the table is the one of the zstd seekable format:
+//
+//	entries: compressed size uint32, decompressed size uint32 of each frame
+//	footer:  number of frames uint32, descriptor byte, magic uint32
+//
+// For zstd the table is held by a skippable frame, for gzip by the extra field of
+// a last empty member, so both can still be decompressed by the usual tools.
+const (
+	seekableMagic  = 0x8F92EAB1
+	skippableMagic = 0x184D2A5E
+	footerSize     = 9
+	entrySize      = 8
+	// the fixed tail of an empty gzip member: an empty final deflate block, crc32 and size
+	gzipTailSize = 10
+	// the extra field of a gzip header holds at most 65535 bytes, minus its subfield header
+	maxGzipFrames = (65535 - 4 - footerSize) / entrySize
+)
+
+type frame struct {
+	cOffset, cSize int64
+	dOffset, dSize int64
+}
+
+type seekTable []frame
+
+func (t seekTable) size() int64 {
+	if len(t) == 0 {
+		return 0
+	}
+	last := t[len(t)-1]
+	return last.dOffset + last.dSize
+}
+
+func (t seekTable) encode() []byte {
+	buf := make([]byte, 0, len(t)*entrySize+footerSize)
+	for _, f := range t {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(f.cSize))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(f.dSize))
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(t)))
+	buf = append(buf, 0)
+	return binary.LittleEndian.AppendUint32(buf, seekableMagic)
+}
+
+type encoder interface {
+	// frame compresses data as an independent frame
+	frame(dst, data []byte) ([]byte, error)
+	// trailer wraps the encoded seek table
+	trailer(table []byte) []byte
+	// tailSize is the number of bytes after the seek table
+	tailSize() int64
+	newReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type zstdEncoder struct {
+	enc *zstd.Encoder
+}
+
+func newZstdEncoder(level string) (*zstdEncoder, error) {
+	l := zstd.SpeedDefault
+	switch level {
+	case "fastest":
+		l = zstd.SpeedFastest
+	case "better":
+		l = zstd.SpeedBetterCompression
+	case "best":
+		l = zstd.SpeedBestCompression
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(l), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdEncoder{enc: enc}, nil
+}
+
+func (e *zstdEncoder) frame(dst, data []byte) ([]byte, error) {
+	return e.enc.EncodeAll(data, dst), nil
+}
+
+func (e *zstdEncoder) trailer(table []byte) []byte {
+	buf := binary.LittleEndian.AppendUint32(nil, skippableMagic)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(table)))
+	return append(buf, table...)
+}
+
+func (e *zstdEncoder) tailSize() int64 {
+	return 0
+}
+
+func (e *zstdEncoder) newReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+type gzipEncoder struct {
+	level int
+}
+
+func newGzipEncoder(level string) *gzipEncoder {
+	l := gzip.DefaultCompression
+	switch level {
+	case "fastest":
+		l = gzip.BestSpeed
+	case "better":
+		l = 7
+	case "best":
+		l = gzip.BestCompression
+	}
+	return &gzipEncoder{level: l}
+}
+
+func (e *gzipEncoder) frame(dst, data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w, err := gzip.NewWriterLevel(buf, e.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *gzipEncoder) trailer(table []byte) []byte {
+	// header with FEXTRA, no mtime, unknown os
+	buf := []byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff}
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(table)+4))
+	buf = append(buf, 'O', 'L')
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(table)))
+	buf = append(buf, table...)
+	return append(buf, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+}
+
+func (e *gzipEncoder) tailSize() int64 {
+	return gzipTailSize
+}
+
+func (e *gzipEncoder) newReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func newEncoder(algorithm, level string) (encoder, error) {
+	switch algorithm {
+	case "gzip":
+		return newGzipEncoder(level), nil
+	case "zstd":
+		return newZstdEncoder(level)
+	}
+	return nil, fmt.Errorf("unknown algorithm %s", algorithm)
+}
+
+// compressFrames compresses r frame by frame into w, first is the already compressed first frame
+func compressFrames(w io.Writer, r io.Reader, enc encoder, frameSize int, first []byte, firstSize int) (seekTable, error) {
+	var table seekTable
+	var offset, dOffset int64
+	add := func(data []byte, dSize int) error {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		table = append(table, frame{cOffset: offset, cSize: int64(len(data)), dOffset: dOffset, dSize: int64(dSize)})
+		offset += int64(len(data))
+		dOffset += int64(dSize)
+		return nil
+	}
+	if firstSize > 0 {
+		if err := add(first, firstSize); err != nil {
+			return nil, err
+		}
+	}
+	buf := make([]byte, frameSize)
+	var out []byte
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if out, err = enc.frame(out[:0], buf[:n]); err != nil {
+				return nil, err
+			}
+			if err = add(out, n); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := w.Write(enc.trailer(table.encode())); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// readSeekTable reads the seek table at the end of a compressed file of size bytes
+func readSeekTable(ctx context.Context, rr model.RangeReaderIF, size int64, enc encoder) (seekTable, error) {
+	footerStart := size - enc.tailSize() - footerSize
+	if footerStart < 0 {
+		return nil, errors.New("file is too small")
+	}
+	footer, err := readRange(ctx, rr, footerStart, footerSize)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != seekableMagic {
+		return nil, errors.New("seek table not found")
+	}
+	count := int64(binary.LittleEndian.Uint32(footer))
+	tableStart := footerStart - count*entrySize
+	if tableStart < 0 {
+		return nil, errors.New("invalid seek table")
+	}
+	entries, err := readRange(ctx, rr, tableStart, count*entrySize)
+	if err != nil {
+		return nil, err
+	}
+	table := make(seekTable, count)
+	var offset, dOffset int64
+	for i := range table {
+		cSize := int64(binary.LittleEndian.Uint32(entries[i*entrySize:]))
+		dSize := int64(binary.LittleEndian.Uint32(entries[i*entrySize+4:]))
+		table[i] = frame{cOffset: offset, cSize: cSize, dOffset: dOffset, dSize: dSize}
+		offset += cSize
+		dOffset += dSize
+	}
+	if offset > tableStart {
+		return nil, errors.New("invalid seek table")
+	}
+	return table, nil
+}
+
+func readRange(ctx context.Context, rr model.RangeReaderIF, start, length int64) ([]byte, error) {
+	rc, err := rr.RangeRead(ctx, http_range.Range{Start: start, Length: length})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	buf := make([]byte, length)
+	if _, err = io.ReadFull(rc, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// rangeRead decompresses a range of the original file, only the frames covering it are read
+func rangeRead(ctx context.Context, rr model.RangeReaderIF, table seekTable, enc encoder, httpRange http_range.Range) (io.ReadCloser, error) {
+	size := table.size()
+	if httpRange.Length < 0 || httpRange.Start+httpRange.Length > size {
+		httpRange.Length = size - httpRange.Start
+	}
+	if httpRange.Length <= 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	end := httpRange.Start + httpRange.Length
+	first, last := -1, -1
+	for i, f := range table {
+		if first < 0 && f.dOffset+f.dSize > httpRange.Start {
+			first = i
+		}
+		if f.dOffset < end {
+			last = i
+		}
+	}
+	start := table[first].cOffset
+	rc, err := rr.RangeRead(ctx, http_range.Range{
+		Start:  start,
+		Length: table[last].cOffset + table[last].cSize - start,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dec, err := enc.newReader(rc)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	if _, err = utils.CopyWithBufferN(io.Discard, dec, httpRange.Start-table[first].dOffset); err != nil {
+		_ = dec.Close()
+		_ = rc.Close()
+		return nil, err
+	}
+	return utils.ReadCloser{
+		Reader: io.LimitReader(dec, httpRange.Length),
+		Closer: utils.CloseFunc(func() error {
+			return errors.Join(dec.Close(), rc.Close())
+		}),
+	}, nil
+}
@@ -0,0 +1,93 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/klauspost/compress/zstd"
+)
+
+type bytesRangeReader []byte
+
+func (b bytesRangeReader) RangeRead(_ context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b[httpRange.Start : httpRange.Start+httpRange.Length])), nil
+}
+
+func TestSeekableFrames(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; data.Len() < 100_000; i++ {
+		fmt.Fprintf(&data, "%d line of the log\n", i)
+	}
+	for _, algorithm := range []string{"zstd", "gzip"} {
+		t.Run(algorithm, func(t *testing.T) {
+			enc, err := newEncoder(algorithm, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if _, err = compressFrames(&out, bytes.NewReader(data.Bytes()), enc, 4096, nil, 0); err != nil {
+				t.Fatal(err)
+			}
+			if out.Len()*5 > data.Len() {
+				t.Errorf("compressed %d bytes to %d", data.Len(), out.Len())
+			}
+			// the usual decompressors skip the seek table
+			var dec io.Reader
+			if algorithm == "zstd" {
+				d, err := zstd.NewReader(bytes.NewReader(out.Bytes()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer d.Close()
+				dec = d
+			} else if dec, err = gzip.NewReader(bytes.NewReader(out.Bytes())); err != nil {
+				t.Fatal(err)
+			}
+			all, err := io.ReadAll(dec)
+			if err != nil || !bytes.Equal(all, data.Bytes()) {
+				t.Fatalf("full decompression failed: %v", err)
+			}
+
+			rr := bytesRangeReader(out.Bytes())
+			table, err := readSeekTable(context.Background(), rr, int64(out.Len()), enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if table.size() != int64(data.Len()) {
+				t.Fatalf("table size %d, want %d", table.size(), data.Len())
+			}
+			for _, r := range []http_range.Range{{Start: 0, Length: 10}, {Start: 4090, Length: 20}, {Start: 12345, Length: 30000}, {Start: 99000, Length: -1}} {
+				rc, err := rangeRead(context.Background(), rr, table, enc, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(rc)
+				_ = rc.Close()
+				end := r.Start + r.Length
+				if r.Length < 0 {
+					end = int64(data.Len())
+				}
+				if err != nil || !bytes.Equal(got, data.Bytes()[r.Start:end]) {
+					t.Errorf("range %+v: %v", r, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressedName(t *testing.T) {
+	info := &compressedInfo{name: "app.2024.log", size: 123456789, modified: time.UnixMilli(1700000000123), algorithm: "zstd"}
+	got, ok := parseName(info.rawName())
+	if !ok || *got != *info {
+		t.Errorf("parseName(%s) = %+v", info.rawName(), got)
+	}
+	if _, ok = parseName("app.log.gz"); ok {
+		t.Error("plain gzip file parsed as compressed")
+	}
+}
@@ -0,0 +1,31 @@
+package compress
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	RemotePath     string `json:"remote_path" required:"true" help:"This is where the compressed data stores"`
+	Algorithm      string `json:"algorithm" type:"select" required:"true" options:"zstd,gzip" default:"zstd"`
+	Level          string `json:"level" type:"select" options:"fastest,default,better,best" default:"default"`
+	FrameSize      int    `json:"frame_size" type:"number" default:"1024" help:"Unit: KB, range reads decompress whole frames"`
+	SkipExtensions string `json:"skip_extensions" default:"7z,aac,avi,br,bz2,docx,flac,gif,gz,heic,jpeg,jpg,m4a,mkv,mov,mp3,mp4,ogg,png,pptx,rar,webm,webp,xlsx,xz,zip,zst" help:"Files with these extensions are stored as they are"`
+	MinRatio       int    `json:"min_ratio" type:"number" default:"90" help:"Files are stored as they are if their first frame does not shrink below this percentage"`
+}
+
+var config = driver.Config{
+	Name:        "Compress",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	NoCache:     true,
+	DefaultRoot: "/",
+	NoLinkURL:   true,
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Compress{}
+	})
+}
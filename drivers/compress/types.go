@@ -0,0 +1,54 @@
+package compress
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// the original size and modified time of a compressed file are kept in its name,
+// e.g. app.log.olc1a2b-lq3x9k0.zst
+var compressedName = regexp.MustCompile(`^(.+)\.olc([0-9a-z]+)-([0-9a-z]+)\.(zst|gz)$`)
+
+var suffixes = map[string]string{
+	"zstd": "zst",
+	"gzip": "gz",
+}
+
+var algorithms = map[string]string{
+	"zst": "zstd",
+	"gz":  "gzip",
+}
+
+type compressedInfo struct {
+	name      string
+	size      int64
+	modified  time.Time
+	algorithm string
+}
+
+func parseName(rawName string) (*compressedInfo, bool) {
+	m := compressedName.FindStringSubmatch(rawName)
+	if m == nil {
+		return nil, false
+	}
+	size, err := strconv.ParseInt(m[2], 36, 64)
+	if err != nil {
+		return nil, false
+	}
+	modified, err := strconv.ParseInt(m[3], 36, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &compressedInfo{
+		name:      m[1],
+		size:      size,
+		modified:  time.UnixMilli(modified),
+		algorithm: algorithms[m[4]],
+	}, true
+}
+
+func (i *compressedInfo) rawName() string {
+	return i.name + ".olc" + strconv.FormatInt(i.size, 36) + "-" +
+		strconv.FormatInt(i.modified.UnixMilli(), 36) + "." + suffixes[i.algorithm]
+}
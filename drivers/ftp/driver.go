@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	stdpath "path"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -13,6 +14,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/jlaffaye/ftp"
+	log "github.com/sirupsen/logrus"
 )
 
 type FTP struct {
@@ -166,10 +168,30 @@ func (d *FTP) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, u
 		return err
 	}
 	path := stdpath.Join(dstDir.GetPath(), s.GetName())
-	return d.conn.Stor(encode(path, d.Encoding), driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+	err := d.conn.Stor(encode(path, d.Encoding), driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
 		Reader:         s,
 		UpdateProgress: up,
 	}))
+	if err != nil {
+		return err
+	}
+	if !s.ModTime().IsZero() && d.conn.IsSetTimeSupported() {
+		if err := d.conn.SetTime(encode(path, d.Encoding), s.ModTime()); err != nil {
+			log.Errorf("[ftp] failed to change time of %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+func (d *FTP) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	if err := d.login(); err != nil {
+		return err
+	}
+	if !d.conn.IsSetTimeSupported() {
+		return errs.NotImplement
+	}
+	return d.conn.SetTime(encode(obj.GetPath(), d.Encoding), modTime)
 }
 
 var _ driver.Driver = (*FTP)(nil)
+var _ driver.SetModTime = (*FTP)(nil)
@@ -0,0 +1,62 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+	ftpserver "github.com/fclairamb/ftpserverlib"
+	"github.com/spf13/afero"
+)
+
+// testServer serves a temp dir to a single user
+type testServer struct {
+	fs afero.Fs
+}
+
+func (s *testServer) GetSettings() (*ftpserver.Settings, error) {
+	return &ftpserver.Settings{ListenAddr: "127.0.0.1:0"}, nil
+}
+
+func (s *testServer) ClientConnected(ftpserver.ClientContext) (string, error) {
+	return "drivertest", nil
+}
+
+func (s *testServer) ClientDisconnected(ftpserver.ClientContext) {}
+
+func (s *testServer) AuthUser(_ ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
+	if user != "openlist" || pass != "secret" {
+		return nil, errors.New("wrong password")
+	}
+	return s.fs, nil
+}
+
+func (s *testServer) GetTLSConfig() (*tls.Config, error) {
+	return nil, errors.New("no TLS")
+}
+
+func newTestServer(t *testing.T) string {
+	t.Helper()
+	server := ftpserver.NewFtpServer(&testServer{fs: afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())})
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve() }()
+	t.Cleanup(func() { _ = server.Stop() })
+	return server.Addr()
+}
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver { return &FTP{} },
+		Addition: Addition{
+			Address:  newTestServer(t),
+			Username: "openlist",
+			Password: "secret",
+			RootPath: driver.RootPath{RootFolderPath: "/"},
+		},
+		LargeSize: 16 << 20,
+	})
+}
@@ -433,6 +433,11 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	return nil
 }
 
+func (d *Local) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	// the zero access time is left unchanged
+	return os.Chtimes(obj.GetPath(), time.Time{}, modTime)
+}
+
 func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	du, err := getDiskUsage(d.RootFolderPath)
 	if err != nil {
@@ -444,3 +449,4 @@ func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 }
 
 var _ driver.Driver = (*Local)(nil)
+var _ driver.SetModTime = (*Local)(nil)
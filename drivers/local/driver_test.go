@@ -0,0 +1,21 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+)
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver { return &Local{directoryMap: DirectoryMap{}} },
+		Addition: Addition{
+			RootPath:       driver.RootPath{RootFolderPath: t.TempDir()},
+			ShowHidden:     true,
+			MkdirPerm:      "777",
+			RecycleBinPath: "delete permanently",
+		},
+		LargeSize: 16 << 20,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"path"
 	"sync"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -220,6 +221,19 @@ func (d *Onedrive) Put(ctx context.Context, dstDir model.Obj, stream model.FileS
 	return err
 }
 
+func (d *Onedrive) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	data := base.Json{
+		"fileSystemInfo": base.Json{
+			"lastModifiedDateTime": modTime.UTC().Format(time.RFC3339),
+		},
+	}
+	url := d.GetMetaUrl(false, obj.GetPath())
+	_, err := d.Request(url, http.MethodPatch, func(req *resty.Request) {
+		req.SetBody(data).SetContext(ctx)
+	}, nil)
+	return err
+}
+
 func (d *Onedrive) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	if d.DisableDiskUsage {
 		return nil, errs.NotImplement
@@ -252,3 +266,4 @@ func (d *Onedrive) GetDirectUploadInfo(ctx context.Context, _ string, dstDir mod
 }
 
 var _ driver.Driver = (*Onedrive)(nil)
+var _ driver.SetModTime = (*Onedrive)(nil)
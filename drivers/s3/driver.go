@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	stdpath "path"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -77,10 +79,17 @@ func (d *S3) Drop(ctx context.Context) error {
 }
 
 func (d *S3) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	var files []model.Obj
+	var err error
 	if d.ListObjectVersion == "v2" {
-		return d.listV2(dir.GetPath(), args)
+		files, err = d.listV2(dir.GetPath(), args)
+	} else {
+		files, err = d.listV1(dir.GetPath(), args)
+	}
+	if err != nil || !d.ReadModTime {
+		return files, err
 	}
-	return d.listV1(dir.GetPath(), args)
+	return files, d.readModTimes(ctx, dir.GetPath(), files)
 }
 
 func (d *S3) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
@@ -210,10 +219,60 @@ func (d *S3) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up
 		}),
 		ContentType: &contentType,
 	}
+	if !s.ModTime().IsZero() {
+		input.Metadata = map[string]*string{metaMtime: aws.String(formatMtime(s.ModTime()))}
+	}
 	_, err := uploader.UploadWithContext(ctx, input)
 	return err
 }
 
+// SetModTime keeps the time in the metadata of the object the way rclone does,
+// the object is copied onto itself to replace the metadata. The time is only
+// read back by listings with ReadModTime, so it's not set without it
+func (d *S3) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	if obj.IsDir() || !d.ReadModTime {
+		return errs.NotImplement
+	}
+	key := getKey(obj.GetPath(), false)
+	head, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return err
+	}
+	metadata := head.Metadata
+	if metadata == nil {
+		metadata = make(map[string]*string)
+	}
+	metadata[metaMtime] = aws.String(formatMtime(modTime))
+	// replacing the metadata replaces the headers and resets the storage class and
+	// encryption as well, so the ones of the object are given again
+	input := &s3.CopyObjectInput{
+		Bucket:               &d.Bucket,
+		CopySource:           aws.String(url.PathEscape(d.Bucket + "/" + key)),
+		Key:                  &key,
+		CacheControl:         head.CacheControl,
+		ContentDisposition:   head.ContentDisposition,
+		ContentEncoding:      head.ContentEncoding,
+		ContentLanguage:      head.ContentLanguage,
+		ContentType:          head.ContentType,
+		Metadata:             metadata,
+		MetadataDirective:    aws.String(s3.MetadataDirectiveReplace),
+		StorageClass:         head.StorageClass,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		BucketKeyEnabled:     head.BucketKeyEnabled,
+	}
+	if head.Expires != nil {
+		if expires, err := http.ParseTime(*head.Expires); err == nil {
+			input.Expires = &expires
+		}
+	}
+	_, err = d.client.CopyObjectWithContext(ctx, input)
+	return err
+}
+
 func (d *S3) GetDirectUploadTools() []string {
 	if !d.EnableDirectUpload {
 		return nil
@@ -244,3 +303,4 @@ func (d *S3) GetDirectUploadInfo(ctx context.Context, _ string, dstDir model.Obj
 }
 
 var _ driver.Driver = (*S3)(nil)
+var _ driver.SetModTime = (*S3)(nil)
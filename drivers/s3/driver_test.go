@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// newFakeS3 serves a fake s3 with a bucket named files
+func newFakeS3(t *testing.T) string {
+	backend := s3mem.New()
+	if err := backend.CreateBucket("files"); err != nil {
+		t.Fatal(err)
+	}
+	faker := gofakes3.New(backend).Server()
+	// the fake doesn't unescape the slash between the bucket and the key of a copy source
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			if unescaped, err := url.PathUnescape(source); err == nil {
+				r.Header.Set("X-Amz-Copy-Source", unescaped)
+			}
+		}
+		faker.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func testAddition(endpoint string) Addition {
+	return Addition{
+		RootPath:          driver.RootPath{RootFolderPath: "/"},
+		Bucket:            "files",
+		Endpoint:          endpoint,
+		Region:            "us-east-1",
+		AccessKeyID:       "openlist",
+		SecretAccessKey:   "secret",
+		SignURLExpire:     4,
+		ForcePathStyle:    true,
+		ListObjectVersion: "v2",
+		ReadModTime:       true,
+	}
+}
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver {
+			return &S3{config: driver.Config{Name: "S3", DefaultRoot: "/", LocalSort: true, CheckStatus: true}}
+		},
+		Addition:  testAddition(newFakeS3(t)),
+		LargeSize: 12 << 20,
+	})
+}
+
+func TestSetModTimeKeepsHeaders(t *testing.T) {
+	ctx := context.Background()
+	d := &S3{Addition: testAddition(newFakeS3(t))}
+	if err := d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:             &d.Bucket,
+		Key:                aws.String("a.txt"),
+		Body:               strings.NewReader("a"),
+		ContentEncoding:    aws.String("identity"),
+		ContentDisposition: aws.String("attachment"),
+		ContentType:        aws.String("text/plain"),
+		Metadata:           map[string]*string{"Owner": aws.String("me")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 7000, time.UTC)
+	if err = d.SetModTime(ctx, &model.Object{Path: "/a.txt", Name: "a.txt"}, modTime); err != nil {
+		t.Fatal(err)
+	}
+	head, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: &d.Bucket, Key: aws.String("a.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(head.ContentEncoding) != "identity" || aws.StringValue(head.ContentDisposition) != "attachment" ||
+		aws.StringValue(head.ContentType) != "text/plain" || aws.StringValue(head.Metadata["Owner"]) != "me" {
+		t.Errorf("the headers are lost: %+v", head)
+	}
+	objs, err := d.List(ctx, &model.Object{Path: "/", IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 || !objs[0].ModTime().Equal(modTime) {
+		t.Errorf("listed %+v", objs)
+	}
+}
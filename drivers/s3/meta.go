@@ -23,6 +23,7 @@ type Addition struct {
 	AddFilenameToDisposition bool   `json:"add_filename_to_disposition" help:"Add filename to Content-Disposition header."`
 	EnableDirectUpload       bool   `json:"enable_direct_upload" default:"false"`
 	DirectUploadHost         string `json:"direct_upload_host" required:"false"`
+	ReadModTime              bool   `json:"read_mod_time" help:"Read the modification time kept in the object metadata, takes a request for every file listed."`
 }
 
 func init() {
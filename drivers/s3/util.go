@@ -3,10 +3,13 @@ package s3
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
@@ -21,6 +24,56 @@ import (
 
 // do others that not defined in Driver interface
 
+// metaMtime is the metadata rclone keeps the modification time in
+const metaMtime = "Mtime"
+
+// formatMtime formats the time in seconds with nanoseconds like rclone,
+// without going through a float which doesn't hold the nanoseconds
+func formatMtime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+func parseMtime(s string) (time.Time, bool) {
+	sec, frac, _ := strings.Cut(s, ".")
+	secs, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsecs int64
+	if frac != "" {
+		frac = (frac + "000000000")[:9]
+		if nsecs, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(secs, nsecs), true
+}
+
+// readModTimes replaces the modification time of the files listed in the dir
+// with the one kept in their metadata, if any
+func (d *S3) readModTimes(ctx context.Context, dir string, files []model.Obj) error {
+	for _, file := range files {
+		obj, ok := file.(*model.Object)
+		if !ok || obj.IsFolder {
+			continue
+		}
+		key := getKey(path.Join(dir, obj.Name), false)
+		head, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: &d.Bucket,
+			Key:    &key,
+		})
+		if err != nil {
+			return err
+		}
+		if mtime, ok := head.Metadata[metaMtime]; ok && mtime != nil {
+			if modTime, ok := parseMtime(*mtime); ok {
+				obj.Modified = modTime
+			}
+		}
+	}
+	return nil
+}
+
 func (d *S3) initSession() error {
 	var err error
 	accessKeyID, secretAccessKey, sessionToken := d.AccessKeyID, d.SecretAccessKey, d.SessionToken
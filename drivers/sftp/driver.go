@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -120,7 +121,22 @@ func (d *SFTP) Put(ctx context.Context, dstDir model.Obj, stream model.FileStrea
 		_ = dstFile.Close()
 	}()
 	err = utils.CopyWithCtx(ctx, dstFile, driver.NewLimitedUploadStream(ctx, stream), stream.GetSize(), up)
-	return err
+	if err != nil {
+		return err
+	}
+	if !stream.ModTime().IsZero() {
+		if err := d.client.Chtimes(dstFile.Name(), stream.ModTime(), stream.ModTime()); err != nil {
+			log.Errorf("[sftp] failed to change time of %s: %s", dstFile.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (d *SFTP) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	if err := d.clientReconnectOnConnectionError(); err != nil {
+		return err
+	}
+	return d.client.Chtimes(obj.GetPath(), modTime, modTime)
 }
 
 func (d *SFTP) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
@@ -142,3 +158,4 @@ func (d *SFTP) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 }
 
 var _ driver.Driver = (*SFTP)(nil)
+var _ driver.SetModTime = (*SFTP)(nil)
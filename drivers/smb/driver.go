@@ -5,11 +5,13 @@ import (
 	"errors"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/cloudsoda/go-smb2"
 )
@@ -197,6 +199,27 @@ func (d *SMB) Put(ctx context.Context, dstDir model.Obj, stream model.FileStream
 	if err != nil {
 		return err
 	}
+	if !stream.ModTime().IsZero() {
+		// the server sets the time of the last write on close
+		if err = out.Close(); err != nil {
+			return err
+		}
+		if err := d.fs.Chtimes(fullPath, stream.ModTime(), stream.ModTime()); err != nil {
+			log.Errorf("[smb] failed to change time of %s: %s", fullPath, err)
+		}
+	}
+	return nil
+}
+
+func (d *SMB) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	if err := d.checkConn(ctx); err != nil {
+		return err
+	}
+	if err := d.fs.Chtimes(obj.GetPath(), modTime, modTime); err != nil {
+		d.cleanLastConnTime()
+		return err
+	}
+	d.updateLastConnTime()
 	return nil
 }
 
@@ -221,3 +244,4 @@ func (d *SMB) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 //}
 
 var _ driver.Driver = (*SMB)(nil)
+var _ driver.SetModTime = (*SMB)(nil)
@@ -0,0 +1,252 @@
+package swift
+
+import (
+	"context"
+	"errors"
+	"io"
+	stdpath "path"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/ncw/swift/v2"
+)
+
+type Swift struct {
+	model.Storage
+	Addition
+	conn             *swift.Connection
+	chunkSize        int64
+	segmentContainer string
+	tempURLKey       string
+}
+
+func (d *Swift) Config() driver.Config {
+	c := config
+	// without a TempURL key the objects are only readable with the token of the storage
+	c.NoLinkURL = d.tempURLKey == ""
+	return c
+}
+
+func (d *Swift) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Swift) Init(ctx context.Context) error {
+	authVersion := 0
+	if d.AuthVersion != "" && d.AuthVersion != "auto" {
+		v, err := strconv.Atoi(d.AuthVersion)
+		if err != nil {
+			return err
+		}
+		authVersion = v
+	}
+	d.conn = &swift.Connection{
+		AuthUrl:                     d.AuthURL,
+		AuthVersion:                 authVersion,
+		UserName:                    d.Username,
+		ApiKey:                      d.APIKey,
+		Domain:                      d.Domain,
+		Tenant:                      d.Tenant,
+		TenantDomain:                d.TenantDomain,
+		ApplicationCredentialId:     d.ApplicationCredentialID,
+		ApplicationCredentialSecret: d.ApplicationCredentialSecret,
+		Region:                      d.Region,
+		EndpointType:                swift.EndpointType(utils.GetNoneEmpty(d.EndpointType, "public")),
+	}
+	if err := d.conn.Authenticate(ctx); err != nil {
+		return err
+	}
+	_, containerHeaders, err := d.conn.Container(ctx, d.Container)
+	if err != nil {
+		return err
+	}
+	if d.ChunkSize <= 0 {
+		d.ChunkSize = 1024
+	}
+	d.chunkSize = d.ChunkSize * utils.MB
+	d.segmentContainer = utils.GetNoneEmpty(d.SegmentContainer, d.Container+"_segments")
+	if d.SignURLExpire <= 0 {
+		d.SignURLExpire = 4
+	}
+	d.tempURLKey = d.TempURLKey
+	if d.tempURLKey == "" {
+		d.tempURLKey = containerHeaders["X-Container-Meta-Temp-Url-Key"]
+	}
+	if d.tempURLKey == "" {
+		if _, accountHeaders, err := d.conn.Account(ctx); err == nil {
+			d.tempURLKey = accountHeaders["X-Account-Meta-Temp-Url-Key"]
+		}
+	}
+	return nil
+}
+
+func (d *Swift) Drop(ctx context.Context) error {
+	if d.conn != nil {
+		d.conn.UnAuthenticate()
+	}
+	return nil
+}
+
+func (d *Swift) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	prefix := getKey(dir.GetPath(), true)
+	objs, err := d.conn.ObjectsAll(ctx, d.Container, &swift.ObjectsOpts{
+		Prefix:    prefix,
+		Delimiter: '/',
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Obj, 0, len(objs))
+	dirs := make(map[string]bool)
+	for _, o := range objs {
+		name := strings.TrimSuffix(strings.TrimPrefix(o.Name, prefix), "/")
+		if name == "" {
+			continue
+		}
+		if o.PseudoDirectory || isDirMarker(o) {
+			// a dir may be both a pseudo dir and a marker
+			if !dirs[name] {
+				dirs[name] = true
+				res = append(res, &model.Object{
+					Path:     stdpath.Join(dir.GetPath(), name),
+					Name:     name,
+					Modified: o.LastModified,
+					IsFolder: true,
+				})
+			}
+			continue
+		}
+		obj := toObj(dir.GetPath(), o, name)
+		if o.Bytes == 0 {
+			// the manifest of a DLO is listed as empty
+			if info, headers, err := d.conn.Object(ctx, d.Container, o.Name); err == nil && headers.IsLargeObject() {
+				obj.Size = info.Bytes
+				obj.HashInfo = utils.HashInfo{}
+			}
+		} else if o.SLOHash != "" {
+			obj.HashInfo = utils.HashInfo{}
+		}
+		res = append(res, obj)
+	}
+	return res, nil
+}
+
+func (d *Swift) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	name := getKey(file.GetPath(), false)
+	if d.tempURLKey != "" {
+		u, err := d.tempURL(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &model.Link{URL: u}, nil
+	}
+	// read through the connection, which renews the token when it expires
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			h := swift.Headers{}
+			if r := http_range.ApplyRangeToHttpHeader(httpRange, nil).Get("Range"); r != "" {
+				h["Range"] = r
+			}
+			f, _, err := d.conn.ObjectOpen(ctx, d.Container, name, false, h)
+			if err != nil {
+				return nil, err
+			}
+			return f, nil
+		}),
+		ContentLength: file.GetSize(),
+	}, nil
+}
+
+func (d *Swift) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	name := getKey(stdpath.Join(parentDir.GetPath(), dirName), false)
+	_, err := d.conn.ObjectPut(ctx, d.Container, name, strings.NewReader(""), false, "", dirContentType, nil)
+	return err
+}
+
+func (d *Swift) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	dst := stdpath.Join(dstDir.GetPath(), srcObj.GetName())
+	if srcObj.IsDir() {
+		return d.transferDir(ctx, srcObj.GetPath(), dst, true)
+	}
+	return d.moveObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+}
+
+func (d *Swift) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	dst := stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName)
+	if srcObj.IsDir() {
+		return d.transferDir(ctx, srcObj.GetPath(), dst, true)
+	}
+	return d.moveObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+}
+
+func (d *Swift) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	dst := stdpath.Join(dstDir.GetPath(), srcObj.GetName())
+	if srcObj.IsDir() {
+		return d.transferDir(ctx, srcObj.GetPath(), dst, false)
+	}
+	return d.copyObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+}
+
+func (d *Swift) Remove(ctx context.Context, obj model.Obj) error {
+	if !obj.IsDir() {
+		return d.conn.LargeObjectDelete(ctx, d.Container, getKey(obj.GetPath(), false))
+	}
+	return d.walk(ctx, obj.GetPath(), func(o swift.Object) error {
+		err := d.conn.LargeObjectDelete(ctx, d.Container, o.Name)
+		if errors.Is(err, swift.ObjectNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *Swift) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	name := getKey(stdpath.Join(dstDir.GetPath(), s.GetName()), false)
+	if s.GetExist() != nil && s.GetSize() <= d.chunkSize {
+		// the segments of an overwritten large object would be left behind
+		if _, headers, err := d.conn.Object(ctx, d.Container, name); err == nil && headers.IsLargeObject() {
+			if err = d.conn.LargeObjectDelete(ctx, d.Container, name); err != nil {
+				return err
+			}
+		}
+	}
+	return d.upload(ctx, name, driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+		Reader:         s,
+		UpdateProgress: up,
+	}), s.GetSize(), s.GetMimetype())
+}
+
+// GetDetails reads the quota set by the account or container quota middleware
+func (d *Swift) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	info, headers, err := d.conn.Account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	used := info.BytesUsed
+	quota, _ := strconv.ParseInt(headers["X-Account-Meta-Quota-Bytes"], 10, 64)
+	if quota <= 0 {
+		container, headers, err := d.conn.Container(ctx, d.Container)
+		if err != nil {
+			return nil, err
+		}
+		quota, _ = strconv.ParseInt(headers["X-Container-Meta-Quota-Bytes"], 10, 64)
+		used = container.Bytes
+	}
+	if quota <= 0 {
+		return nil, errs.NotImplement
+	}
+	return &model.StorageDetails{
+		DiskUsage: model.DiskUsage{
+			TotalSpace: uint64(quota),
+			FreeSpace:  uint64(max(quota-used, 0)),
+		},
+	}, nil
+}
+
+var _ driver.Driver = (*Swift)(nil)
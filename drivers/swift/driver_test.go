@@ -0,0 +1,152 @@
+package swift
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/ncw/swift/v2"
+	"github.com/ncw/swift/v2/swifttest"
+)
+
+func newTestSwift(t *testing.T, tempURLKey string) *Swift {
+	t.Helper()
+	srv, err := swifttest.NewSwiftServer("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+	conn := &swift.Connection{AuthUrl: srv.AuthURL, UserName: swifttest.TEST_ACCOUNT, ApiKey: swifttest.TEST_ACCOUNT}
+	if err = conn.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.ContainerCreate(ctx, "files", nil); err != nil {
+		t.Fatal(err)
+	}
+	if tempURLKey != "" {
+		if err = conn.AccountUpdate(ctx, swift.Headers{"X-Account-Meta-Temp-Url-Key": tempURLKey}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := &Swift{Addition: Addition{
+		AuthURL:   srv.AuthURL,
+		Username:  swifttest.TEST_ACCOUNT,
+		APIKey:    swifttest.TEST_ACCOUNT,
+		Container: "files",
+		ChunkSize: 1,
+	}}
+	if err = d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func put(t *testing.T, d *Swift, dir, name string, data []byte) {
+	t.Helper()
+	err := d.Put(context.Background(), &model.Object{Path: dir, IsFolder: true}, &stream.FileStream{
+		Obj:    &model.Object{Name: name, Size: int64(len(data))},
+		Reader: bytes.NewReader(data),
+	}, func(float64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func list(t *testing.T, d *Swift, dir string) map[string]model.Obj {
+	t.Helper()
+	objs, err := d.List(context.Background(), &model.Object{Path: dir, IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string]model.Obj)
+	for _, o := range objs {
+		res[o.GetName()] = o
+	}
+	return res
+}
+
+func TestSwift(t *testing.T) {
+	d := newTestSwift(t, "secret")
+	ctx := context.Background()
+	root := &model.Object{Path: "/", IsFolder: true}
+	if err := d.MakeDir(ctx, root, "a"); err != nil {
+		t.Fatal(err)
+	}
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("0123456789"), 250*1024)
+	put(t, d, "/a", "small.txt", small)
+	put(t, d, "/a/b", "large.bin", large)
+
+	objs := list(t, d, "/")
+	if len(objs) != 1 || !objs["a"].IsDir() {
+		t.Fatalf("root = %v", objs)
+	}
+	objs = list(t, d, "/a")
+	if len(objs) != 2 || !objs["b"].IsDir() || objs["small.txt"].GetSize() != 5 {
+		t.Fatalf("dir a = %v", objs)
+	}
+	if data, err := d.conn.ObjectGetBytes(ctx, "files", "a/b/large.bin"); err != nil || !bytes.Equal(data, large) {
+		t.Fatalf("large object read %d bytes, %v", len(data), err)
+	}
+
+	if err := d.Rename(ctx, objs["b"], "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Copy(ctx, &model.Object{Path: "/a/c/large.bin", Name: "large.bin"}, root); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := d.conn.ObjectGetBytes(ctx, "files", "large.bin"); err != nil || !bytes.Equal(data, large) {
+		t.Fatalf("copied large object read %d bytes, %v", len(data), err)
+	}
+	// the emulator can't serve large objects with a TempURL
+	link, err := d.Link(ctx, &model.Object{Path: "/a/small.txt"}, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || !bytes.Equal(data, small) {
+		t.Fatalf("temp url read %d bytes with status %d, %v", len(data), resp.StatusCode, err)
+	}
+
+	if err = d.Remove(ctx, &model.Object{Path: "/a", IsFolder: true}); err != nil {
+		t.Fatal(err)
+	}
+	if objs = list(t, d, "/"); len(objs) != 1 || objs["large.bin"] == nil {
+		t.Fatalf("root after remove = %v", objs)
+	}
+}
+
+func TestLinkWithoutTempURL(t *testing.T) {
+	d := newTestSwift(t, "")
+	if !d.Config().MustProxy() {
+		t.Fatal("the links are not proxied without a TempURL key")
+	}
+	put(t, d, "/", "small.txt", []byte("hello world"))
+	link, err := d.Link(context.Background(), &model.Object{Path: "/small.txt", Size: 11}, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.URL != "" || link.Header.Get("X-Auth-Token") != "" {
+		t.Fatalf("the token of the storage is exposed: %+v", link)
+	}
+	rc, err := link.RangeReader.RangeRead(context.Background(), http_range.Range{Start: 6, Length: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(data) != "world" {
+		t.Fatalf("range read %q, %v", data, err)
+	}
+}
@@ -0,0 +1,40 @@
+package swift
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	driver.RootPath
+	AuthURL                     string `json:"auth_url" required:"true" help:"e.g. https://keystone.example.com/v3, or the /auth/v1.0 URL of TempAuth"`
+	AuthVersion                 string `json:"auth_version" type:"select" options:"auto,1,2,3" default:"auto" help:"1 is TempAuth, 2 and 3 are Keystone"`
+	Username                    string `json:"username"`
+	APIKey                      string `json:"api_key" help:"Password or API key of the user"`
+	Domain                      string `json:"domain" help:"User domain, Keystone v3 only"`
+	Tenant                      string `json:"tenant" help:"Tenant or project name, Keystone v2 and v3 only"`
+	TenantDomain                string `json:"tenant_domain" help:"Project domain, Keystone v3 only"`
+	ApplicationCredentialID     string `json:"application_credential_id" help:"Used instead of the username and api key, Keystone v3 only"`
+	ApplicationCredentialSecret string `json:"application_credential_secret"`
+	Region                      string `json:"region"`
+	EndpointType                string `json:"endpoint_type" type:"select" options:"public,internal,admin" default:"public"`
+	Container                   string `json:"container" required:"true"`
+	ChunkSize                   int64  `json:"chunk_size" type:"number" default:"1024" help:"Unit: MB, larger files are uploaded as large objects in segments of this size"`
+	LargeObjectType             string `json:"large_object_type" type:"select" options:"slo,dlo" default:"slo" help:"Static or Dynamic Large Object"`
+	SegmentContainer            string `json:"segment_container" help:"Container of the segments of large objects, <container>_segments by default"`
+	TempURLKey                  string `json:"temp_url_key" help:"Sign direct links with TempURL, the key of the container or account is used by default"`
+	SignURLExpire               int    `json:"sign_url_expire" type:"number" default:"4" help:"Unit: hour"`
+}
+
+var config = driver.Config{
+	Name:        "Swift",
+	LocalSort:   true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Swift{}
+	})
+}
@@ -0,0 +1,164 @@
+package swift
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/ncw/swift/v2"
+)
+
+// dirContentType marks the objects standing for dirs, as created by the swift client and rclone
+const dirContentType = "application/directory"
+
+func getKey(path string, dir bool) string {
+	path = strings.TrimPrefix(path, "/")
+	if path != "" && dir {
+		path += "/"
+	}
+	return path
+}
+
+func isDirMarker(o swift.Object) bool {
+	return o.ContentType == dirContentType || strings.HasSuffix(o.Name, "/")
+}
+
+// tempURL signs a GET of the object, like Connection.ObjectTempUrl but with the name escaped
+func (d *Swift) tempURL(ctx context.Context, name string) (string, error) {
+	storageURL, err := d.conn.GetStorageUrl(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(storageURL)
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(time.Hour * time.Duration(d.SignURLExpire)).Unix()
+	path := u.Path + "/" + d.Container + "/" + name
+	mac := hmac.New(sha1.New, []byte(d.tempURLKey))
+	mac.Write([]byte(fmt.Sprintf("GET\n%d\n%s", expires, path)))
+	return fmt.Sprintf("%s/%s/%s?temp_url_sig=%s&temp_url_expires=%d&filename=%s",
+		storageURL, utils.EncodePath(d.Container, true), utils.EncodePath(name, true),
+		hex.EncodeToString(mac.Sum(nil)), expires, url.QueryEscape(stdpath.Base(name))), nil
+}
+
+func (d *Swift) upload(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	if size <= d.chunkSize {
+		_, err := d.conn.ObjectPut(ctx, d.Container, name, r, false, "", contentType, nil)
+		return err
+	}
+	if err := d.conn.ContainerCreate(ctx, d.segmentContainer, nil); err != nil {
+		return err
+	}
+	opts := &swift.LargeObjectOpts{
+		Container:        d.Container,
+		ObjectName:       name,
+		Flags:            os.O_TRUNC,
+		ContentType:      contentType,
+		ChunkSize:        d.chunkSize,
+		SegmentContainer: d.segmentContainer,
+	}
+	var f swift.LargeObjectFile
+	var err error
+	if d.LargeObjectType == "dlo" {
+		f, err = d.conn.DynamicLargeObjectCreate(ctx, opts)
+	} else {
+		f, err = d.conn.StaticLargeObjectCreate(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = utils.CopyWithBuffer(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.CloseWithContext(ctx)
+}
+
+// walk calls fn with every object under the prefix of the dir, dir markers included
+func (d *Swift) walk(ctx context.Context, dir string, fn func(o swift.Object) error) error {
+	prefix := getKey(dir, true)
+	objs, err := d.conn.ObjectsAll(ctx, d.Container, &swift.ObjectsOpts{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		if err = fn(o); err != nil {
+			return err
+		}
+	}
+	// the marker of the dir itself
+	if o, _, err := d.conn.Object(ctx, d.Container, getKey(dir, false)); err == nil {
+		return fn(o)
+	}
+	return nil
+}
+
+func (d *Swift) copyObject(ctx context.Context, src, dst string) error {
+	_, headers, err := d.conn.Object(ctx, d.Container, src)
+	if err != nil {
+		return err
+	}
+	if !headers.IsLargeObject() {
+		_, err = d.conn.ObjectCopy(ctx, d.Container, src, d.Container, dst, nil)
+		return err
+	}
+	// a server side copy is limited to 5GB, and the segments can't be shared
+	f, headers, err := d.conn.ObjectOpen(ctx, d.Container, src, false, nil)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	size, err := f.Length(ctx)
+	if err != nil {
+		return err
+	}
+	return d.upload(ctx, dst, f, size, headers["Content-Type"])
+}
+
+func (d *Swift) moveObject(ctx context.Context, src, dst string) error {
+	_, headers, err := d.conn.Object(ctx, d.Container, src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case headers.IsLargeObjectSLO():
+		return d.conn.StaticLargeObjectMove(ctx, d.Container, src, d.Container, dst)
+	case headers.IsLargeObjectDLO():
+		return d.conn.DynamicLargeObjectMove(ctx, d.Container, src, d.Container, dst)
+	default:
+		return d.conn.ObjectMove(ctx, d.Container, src, d.Container, dst)
+	}
+}
+
+// transferDir moves or copies every object under the dir src to dst
+func (d *Swift) transferDir(ctx context.Context, src, dst string, move bool) error {
+	srcKey, dstKey := getKey(src, false), getKey(dst, false)
+	return d.walk(ctx, src, func(o swift.Object) error {
+		name := dstKey + strings.TrimPrefix(o.Name, srcKey)
+		if move {
+			return d.moveObject(ctx, o.Name, name)
+		}
+		return d.copyObject(ctx, o.Name, name)
+	})
+}
+
+func toObj(dir string, o swift.Object, name string) *model.Object {
+	return &model.Object{
+		Path:     stdpath.Join(dir, name),
+		Name:     name,
+		Size:     o.Bytes,
+		Modified: o.LastModified,
+		HashInfo: utils.NewHashInfo(utils.MD5, o.Hash),
+	}
+}
@@ -0,0 +1,380 @@
+package union
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdpath "path"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+)
+
+// Union pools the dirs of several storages into one, like mergerfs
+type Union struct {
+	model.Storage
+	Addition
+	branches  []branch
+	readOrder []branch
+
+	mu        sync.RWMutex
+	whiteouts map[string]struct{}
+}
+
+func (d *Union) Config() driver.Config {
+	return config
+}
+
+func (d *Union) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Union) Init(ctx context.Context) error {
+	branches, err := parseBranches(d.Branches)
+	if err != nil {
+		return err
+	}
+	d.branches = branches
+	d.readOrder = nil
+	if d.ReadOrder == "listed" {
+		d.readOrder = branches
+	} else {
+		for _, b := range branches {
+			if b.writable() {
+				d.readOrder = append(d.readOrder, b)
+			}
+		}
+		for _, b := range branches {
+			if !b.writable() {
+				d.readOrder = append(d.readOrder, b)
+			}
+		}
+	}
+	d.mu.Lock()
+	d.setWhiteouts(d.Whiteouts)
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Union) Drop(ctx context.Context) error {
+	d.branches = nil
+	d.readOrder = nil
+	return nil
+}
+
+func (d *Union) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	found := d.lookup(ctx, path)
+	if len(found) == 0 {
+		return nil, errs.ObjectNotFound
+	}
+	obj := found[0].obj
+	return &model.Object{
+		Path:     path,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}, nil
+}
+
+func (d *Union) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	path := dir.GetPath()
+	var objs []model.Obj
+	seen := make(map[string]struct{})
+	found := false
+	for _, b := range d.readOrder {
+		if !b.writable() && d.hidden(path) {
+			continue
+		}
+		list, err := fs.List(ctx, stdpath.Join(b.path, path), &fs.ListArgs{
+			NoLog:   true,
+			Refresh: args.Refresh,
+		})
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, obj := range list {
+			name := obj.GetName()
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			if !b.writable() && d.hidden(stdpath.Join(path, name)) {
+				continue
+			}
+			seen[name] = struct{}{}
+			objRes := model.Object{
+				Path:     stdpath.Join(path, name),
+				Name:     name,
+				Size:     obj.GetSize(),
+				Modified: obj.ModTime(),
+				IsFolder: obj.IsDir(),
+				HashInfo: obj.GetHash(),
+			}
+			if thumb, ok := model.GetThumb(obj); ok {
+				objs = append(objs, &model.ObjThumb{
+					Object:    objRes,
+					Thumbnail: model.Thumbnail{Thumbnail: thumb},
+				})
+			} else {
+				objs = append(objs, &objRes)
+			}
+		}
+	}
+	if !found {
+		return nil, errs.ObjectNotFound
+	}
+	return objs, nil
+}
+
+func (d *Union) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// proxy || ftp,s3
+	if common.GetApiUrl(ctx) == "" {
+		args.Redirect = false
+	}
+	for _, l := range d.lookup(ctx, file.GetPath()) {
+		if l.obj.IsDir() {
+			continue
+		}
+		storage, actualPath, err := op.GetStorageAndActualPath(l.path)
+		if err != nil {
+			continue
+		}
+		if args.Redirect && common.ShouldProxy(storage, stdpath.Base(l.path)) {
+			return &model.Link{
+				URL: fmt.Sprintf("%s/p%s?sign=%s",
+					common.GetApiUrl(ctx),
+					utils.EncodePath(l.path, true),
+					sign.Sign(l.path)),
+			}, nil
+		}
+		link, obj, err := op.Link(ctx, storage, actualPath, args)
+		if err != nil {
+			continue
+		}
+		resultLink := *link
+		resultLink.SyncClosers = utils.NewSyncClosers(link)
+		if !args.Redirect && resultLink.ContentLength == 0 {
+			resultLink.ContentLength = obj.GetSize()
+		}
+		return &resultLink, nil
+	}
+	return nil, errs.ObjectNotFound
+}
+
+func (d *Union) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	b, err := d.createBranch(ctx, parentDir.GetPath())
+	if err != nil {
+		return err
+	}
+	return fs.MakeDir(ctx, stdpath.Join(b.path, parentDir.GetPath(), dirName))
+}
+
+func (d *Union) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	rw, ro, err := d.split(ctx, srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	if err = d.checkModifyReadOnly(ro); err != nil {
+		return err
+	}
+	for _, l := range rw {
+		dst := stdpath.Join(l.branch.path, dstDir.GetPath())
+		if e := fs.MakeDir(ctx, dst); e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+		_, e := fs.Move(noTask(ctx), l.path, dst)
+		err = errors.Join(err, e)
+	}
+	if err != nil || len(ro) == 0 {
+		return err
+	}
+	if len(rw) == 0 {
+		b, err := d.createBranch(ctx, dstDir.GetPath())
+		if err != nil {
+			return err
+		}
+		err = d.copyUp(ctx, ro[0], stdpath.Join(b.path, dstDir.GetPath()), srcObj.GetName())
+		if err != nil {
+			return err
+		}
+	}
+	d.addWhiteout(srcObj.GetPath())
+	return nil
+}
+
+func (d *Union) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	rw, ro, err := d.split(ctx, srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	if err = d.checkModifyReadOnly(ro); err != nil {
+		return err
+	}
+	for _, l := range rw {
+		err = errors.Join(err, fs.Rename(ctx, l.path, newName))
+	}
+	if err != nil || len(ro) == 0 {
+		return err
+	}
+	if len(rw) == 0 {
+		dir := stdpath.Dir(srcObj.GetPath())
+		b, err := d.createBranch(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if err = d.copyUp(ctx, ro[0], stdpath.Join(b.path, dir), newName); err != nil {
+			return err
+		}
+	}
+	d.addWhiteout(srcObj.GetPath())
+	return nil
+}
+
+func (d *Union) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	found := d.lookup(ctx, srcObj.GetPath())
+	if len(found) == 0 {
+		return errs.ObjectNotFound
+	}
+	b, err := d.createBranch(ctx, dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	dst := stdpath.Join(b.path, dstDir.GetPath())
+	if err = fs.MakeDir(ctx, dst); err != nil {
+		return err
+	}
+	if !found[0].obj.IsDir() {
+		_, err = fs.Copy(noTask(ctx), found[0].path, dst)
+		return err
+	}
+	// a dir is merged from all the branches having it
+	for _, l := range found {
+		if l.obj.IsDir() {
+			_, e := fs.Copy(noTask(ctx), l.path, dst)
+			err = errors.Join(err, e)
+		}
+	}
+	return err
+}
+
+func (d *Union) Remove(ctx context.Context, obj model.Obj) error {
+	rw, ro, err := d.split(ctx, obj.GetPath())
+	if err != nil {
+		return err
+	}
+	if len(ro) > 0 && !d.Whiteout {
+		return errs.PermissionDenied
+	}
+	for _, l := range rw {
+		err = errors.Join(err, fs.Remove(ctx, l.path))
+	}
+	if err != nil || len(ro) == 0 {
+		return err
+	}
+	d.addWhiteout(obj.GetPath())
+	return nil
+}
+
+func (d *Union) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	b, shadowed, err := d.writeBranch(ctx, dstDir.GetPath(), s.GetName())
+	if err != nil {
+		return err
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(stdpath.Join(b.path, dstDir.GetPath()))
+	if err != nil {
+		return err
+	}
+	// the existing file is looked up again in the branch
+	s.SetExist(nil)
+	if err = op.Put(ctx, storage, actualPath, s, up); err != nil {
+		return err
+	}
+	if shadowed {
+		d.addWhiteout(stdpath.Join(dstDir.GetPath(), s.GetName()))
+	}
+	return nil
+}
+
+func (d *Union) PutURL(ctx context.Context, dstDir model.Obj, name, url string) error {
+	b, shadowed, err := d.writeBranch(ctx, dstDir.GetPath(), name)
+	if err != nil {
+		return err
+	}
+	dst := stdpath.Join(b.path, dstDir.GetPath())
+	if err = fs.MakeDir(ctx, dst); err != nil {
+		return err
+	}
+	if err = fs.PutURL(ctx, dst, name, url); err != nil {
+		return err
+	}
+	if shadowed {
+		d.addWhiteout(stdpath.Join(dstDir.GetPath(), name))
+	}
+	return nil
+}
+
+// GetDetails sums up the space of the storages of the branches, only writable branches count as free
+func (d *Union) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var details model.StorageDetails
+	counted := make(map[string]bool)
+	for _, b := range d.branches {
+		storage, err := fs.GetStorage(b.path, &fs.GetStoragesArgs{})
+		if err != nil {
+			continue
+		}
+		mountPath := storage.GetStorage().MountPath
+		if counted[mountPath] {
+			continue
+		}
+		usage := d.usage(ctx, b)
+		if usage == nil {
+			continue
+		}
+		counted[mountPath] = true
+		details.TotalSpace += usage.TotalSpace
+		if b.writable() {
+			details.FreeSpace += usage.FreeSpace
+		}
+	}
+	if len(counted) == 0 {
+		return nil, errs.NotImplement
+	}
+	return &details, nil
+}
+
+func (d *Union) ResolveLinkCacheMode(path string) driver.LinkCacheMode {
+	for _, b := range d.readOrder {
+		storage, actualPath, err := op.GetStorageAndActualPath(stdpath.Join(b.path, path))
+		if err != nil {
+			continue
+		}
+		if storage.Config().CheckStatus && storage.GetStorage().Status != op.WORK {
+			continue
+		}
+		mode := storage.Config().LinkCacheMode
+		if mode == -1 {
+			return storage.(driver.LinkCacheModeResolver).ResolveLinkCacheMode(actualPath)
+		}
+		return mode
+	}
+	return 0
+}
+
+var _ driver.Driver = (*Union)(nil)
@@ -0,0 +1,109 @@
+package union
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	dir, err := os.MkdirTemp("", "union")
+	if err != nil {
+		panic(err)
+	}
+	conf.Conf = conf.DefaultConfig(dir)
+	if err = os.MkdirAll(conf.Conf.TempDir, 0o777); err != nil {
+		panic(err)
+	}
+	db.Init(dB)
+}
+
+// mountLocal mounts a local storage of a temp dir at the mount path as a branch
+func mountLocal(t *testing.T, mountPath string) string {
+	dir := t.TempDir()
+	id, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q,"show_hidden":true,"mkdir_perm":"777","recycle_bin_path":"delete permanently"}`, dir),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(context.Background(), id) })
+	return dir
+}
+
+func TestConformance(t *testing.T) {
+	mountLocal(t, "/branch_rw")
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver { return &Union{} },
+		Addition: Addition{
+			Branches:     "/branch_rw",
+			CreatePolicy: "ff",
+			ReadOrder:    "writable_first",
+			CopyUp:       true,
+			Whiteout:     true,
+		},
+	})
+}
+
+func TestPutOverReadOnly(t *testing.T) {
+	roDir := mountLocal(t, "/shadow_ro")
+	mountLocal(t, "/shadow_rw")
+	if err := os.WriteFile(roDir+"/a.txt", []byte("old"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Union",
+		MountPath: "/shadow",
+		Addition:  `{"branches":"/shadow_ro=RO\n/shadow_rw","create_policy":"ff","read_order":"listed","copy_up":true,"whiteout":true}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(context.Background(), id) })
+	storage, err := op.GetStorageByMountPath("/shadow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := storage.(*Union)
+	err = d.Put(ctx, &model.Object{Path: "/", IsFolder: true}, &stream.FileStream{
+		Ctx:    ctx,
+		Obj:    &model.Object{Name: "a.txt", Size: 5},
+		Reader: strings.NewReader("new!!"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := d.Get(ctx, "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetSize() != 5 {
+		t.Errorf("the read-only copy of %d bytes is still served", obj.GetSize())
+	}
+	if d.Whiteouts != "/a.txt" {
+		t.Errorf("whiteouts: %q", d.Whiteouts)
+	}
+	if data, _ := os.ReadFile(roDir + "/a.txt"); string(data) != "old" {
+		t.Errorf("read-only branch is written: %s", data)
+	}
+}
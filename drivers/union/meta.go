@@ -0,0 +1,39 @@
+package union
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	// one branch per line, a path optionally followed by its mode, e.g. /115=RO
+	// RW: read and write, NC: read and write but never chosen for new files, RO: read only
+	Branches     string `json:"branches" required:"true" type:"text" help:"One branch per line as path=mode, the mode is RW (default), NC or RO"`
+	CreatePolicy string `json:"create_policy" type:"select" options:"ff,mfs,lus,epff,epmfs,eplus" default:"epmfs" help:"Branch of new files and dirs: first found, most free space, least used space, the ep variants only use branches having the parent dir"`
+	ReadOrder    string `json:"read_order" type:"select" options:"writable_first,listed" default:"writable_first" help:"Which branch a file is read from when several have it"`
+	CopyUp       bool   `json:"copy_up" type:"bool" default:"true" help:"Copy files of read-only branches to a writable branch when they are renamed, moved or overwritten"`
+	Whiteout     bool   `json:"whiteout" type:"bool" default:"true" help:"Hide the objects deleted from read-only branches"`
+	Whiteouts    string `json:"whiteouts" type:"text" help:"Paths hidden from read-only branches, one per line"`
+}
+
+var config = driver.Config{
+	Name:             "Union",
+	LocalSort:        true,
+	NoCache:          true,
+	DefaultRoot:      "/",
+	ProxyRangeOption: true,
+	LinkCacheMode:    driver.LinkCacheAuto,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Union{
+			Addition: Addition{
+				CreatePolicy: "epmfs",
+				ReadOrder:    "writable_first",
+				CopyUp:       true,
+				Whiteout:     true,
+			},
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package union
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+const (
+	modeRW = "RW" // read and write
+	modeNC = "NC" // read and write, but no new objects are created in it
+	modeRO = "RO" // read only
+)
+
+type branch struct {
+	path string
+	mode string
+}
+
+func (b branch) writable() bool {
+	return b.mode != modeRO
+}
+
+// located is an object found in a branch
+type located struct {
+	branch
+	path string // full path of the object
+	obj  model.Obj
+}
@@ -0,0 +1,240 @@
+package union
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdpath "path"
+	"slices"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func parseBranches(text string) ([]branch, error) {
+	var branches []branch
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		b := branch{path: line, mode: modeRW}
+		if i := strings.LastIndex(line, "="); i >= 0 {
+			b.path, b.mode = strings.TrimSpace(line[:i]), strings.ToUpper(strings.TrimSpace(line[i+1:]))
+		}
+		if b.mode != modeRW && b.mode != modeNC && b.mode != modeRO {
+			return nil, fmt.Errorf("invalid mode %s of branch %s", b.mode, b.path)
+		}
+		b.path = utils.FixAndCleanPath(b.path)
+		branches = append(branches, b)
+	}
+	if len(branches) == 0 {
+		return nil, errors.New("branches is required")
+	}
+	return branches, nil
+}
+
+// noTask makes fs transfer within the call instead of in a task, the union is done only when its branches are
+func noTask(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conf.NoTaskKey, struct{}{})
+}
+
+func (d *Union) setWhiteouts(text string) {
+	d.whiteouts = make(map[string]struct{})
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			d.whiteouts[utils.FixAndCleanPath(line)] = struct{}{}
+		}
+	}
+}
+
+// hidden reports whether the path is hidden from the read-only branches
+func (d *Union) hidden(path string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for {
+		if _, ok := d.whiteouts[path]; ok {
+			return true
+		}
+		if path == "/" {
+			return false
+		}
+		path = stdpath.Dir(path)
+	}
+}
+
+func (d *Union) addWhiteout(path string) {
+	d.mu.Lock()
+	d.whiteouts[path] = struct{}{}
+	paths := make([]string, 0, len(d.whiteouts))
+	for p := range d.whiteouts {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	d.Whiteouts = strings.Join(paths, "\n")
+	d.mu.Unlock()
+	op.MustSaveDriverStorage(d)
+}
+
+// lookup finds the object in every branch, in the read order
+func (d *Union) lookup(ctx context.Context, path string) []located {
+	var found []located
+	for _, b := range d.readOrder {
+		if !b.writable() && d.hidden(path) {
+			continue
+		}
+		full := stdpath.Join(b.path, path)
+		obj, err := fs.Get(ctx, full, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			continue
+		}
+		found = append(found, located{branch: b, path: full, obj: obj})
+	}
+	return found
+}
+
+// split finds the object and separates the writable copies from the read-only ones
+func (d *Union) split(ctx context.Context, path string) (rw, ro []located, err error) {
+	rw, ro = d.partition(d.lookup(ctx, path))
+	if len(rw) == 0 && len(ro) == 0 {
+		return nil, nil, errs.ObjectNotFound
+	}
+	return rw, ro, nil
+}
+
+func (d *Union) partition(found []located) (rw, ro []located) {
+	for _, l := range found {
+		if l.writable() {
+			rw = append(rw, l)
+		} else {
+			ro = append(ro, l)
+		}
+	}
+	return rw, ro
+}
+
+// checkModifyReadOnly checks whether the copies in read-only branches can be replaced by a copy-up
+func (d *Union) checkModifyReadOnly(ro []located) error {
+	if len(ro) == 0 {
+		return nil
+	}
+	if !d.CopyUp || !d.Whiteout {
+		return errs.PermissionDenied
+	}
+	for _, l := range ro {
+		if l.obj.IsDir() {
+			return errors.New("dirs of read-only branches cannot be changed, copy them instead")
+		}
+	}
+	return nil
+}
+
+// createBranch chooses the branch of a new object in dir by the create policy
+func (d *Union) createBranch(ctx context.Context, dir string) (branch, error) {
+	var candidates []branch
+	for _, b := range d.branches {
+		if b.mode == modeRW {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		return branch{}, errs.PermissionDenied
+	}
+	policy := d.CreatePolicy
+	if p, ok := strings.CutPrefix(policy, "ep"); ok {
+		// preserve the path, fall back to all branches if none has the dir
+		policy = p
+		var existing []branch
+		for _, b := range candidates {
+			obj, err := fs.Get(ctx, stdpath.Join(b.path, dir), &fs.GetArgs{NoLog: true})
+			if err == nil && obj.IsDir() {
+				existing = append(existing, b)
+			}
+		}
+		if len(existing) > 0 {
+			candidates = existing
+		}
+	}
+	if policy != "mfs" && policy != "lus" {
+		return candidates[0], nil
+	}
+	best, bestValue := -1, uint64(0)
+	for i, b := range candidates {
+		usage := d.usage(ctx, b)
+		if usage == nil {
+			continue
+		}
+		if policy == "mfs" {
+			if best < 0 || usage.FreeSpace > bestValue {
+				best, bestValue = i, usage.FreeSpace
+			}
+		} else if used := usage.TotalSpace - usage.FreeSpace; best < 0 || used < bestValue {
+			best, bestValue = i, used
+		}
+	}
+	return candidates[max(best, 0)], nil
+}
+
+func (d *Union) usage(ctx context.Context, b branch) *model.DiskUsage {
+	storage, err := fs.GetStorage(b.path, &fs.GetStoragesArgs{})
+	if err != nil {
+		return nil
+	}
+	details, err := op.GetStorageDetails(ctx, storage)
+	if err != nil || details == nil {
+		return nil
+	}
+	return &details.DiskUsage
+}
+
+// writeBranch chooses the branch to put the file name in dir, an existing writable copy is overwritten.
+// It reports whether copies in read-only branches have to be hidden after the write,
+// otherwise they would still be served before the new file with the listed read order.
+func (d *Union) writeBranch(ctx context.Context, dir, name string) (branch, bool, error) {
+	rw, ro := d.partition(d.lookup(ctx, stdpath.Join(dir, name)))
+	if err := d.checkModifyReadOnly(ro); err != nil {
+		return branch{}, false, err
+	}
+	if len(rw) > 0 {
+		return rw[0].branch, len(ro) > 0, nil
+	}
+	b, err := d.createBranch(ctx, dir)
+	return b, len(ro) > 0, err
+}
+
+// copyUp copies a file of a read-only branch into dstDir, the full path of a dir in a writable branch
+func (d *Union) copyUp(ctx context.Context, src located, dstDir, name string) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(src.path)
+	if err != nil {
+		return err
+	}
+	link, obj, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			HashInfo: obj.GetHash(),
+		},
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return err
+	}
+	dstStorage, dstActualPath, err := op.GetStorageAndActualPath(dstDir)
+	if err != nil {
+		_ = ss.Close()
+		return err
+	}
+	return op.Put(ctx, dstStorage, dstActualPath, ss, nil)
+}
@@ -0,0 +1,295 @@
+package versioning
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// Versioning keeps the previous revisions of the overwritten and deleted files of another storage
+type Versioning struct {
+	model.Storage
+	Addition
+	versionsPath string
+}
+
+func (d *Versioning) Config() driver.Config {
+	return config
+}
+
+func (d *Versioning) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Versioning) Init(ctx context.Context) error {
+	d.RemotePath = utils.FixAndCleanPath(d.RemotePath)
+	if d.VersionsPath == "" {
+		d.versionsPath = stdpath.Join(d.RemotePath, versionsDir)
+	} else {
+		d.versionsPath = utils.FixAndCleanPath(d.VersionsPath)
+	}
+	if d.versionsPath == d.RemotePath || strings.HasPrefix(d.RemotePath, d.versionsPath+"/") {
+		return fmt.Errorf("versions path %s should not contain the remote path", d.versionsPath)
+	}
+	return nil
+}
+
+func (d *Versioning) Drop(ctx context.Context) error {
+	return nil
+}
+
+// remote converts a path of the storage to the remote full path.
+// The versions are not in the paths of the storage but reached through their files by Other,
+// so that the metas of a file, e.g. its password, apply to its versions as well.
+func (d *Versioning) remote(path string) string {
+	return stdpath.Join(d.RemotePath, path)
+}
+
+func (d *Versioning) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     d.RemotePath,
+		}, nil
+	}
+	remoteFullPath := d.remote(path)
+	if d.isVersion(remoteFullPath) {
+		return nil, errs.ObjectNotFound
+	}
+	remoteObj, err := fs.Get(ctx, remoteFullPath, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, err
+	}
+	return &model.Object{
+		Path:     remoteFullPath,
+		Name:     stdpath.Base(path),
+		Size:     remoteObj.GetSize(),
+		Modified: remoteObj.ModTime(),
+		IsFolder: remoteObj.IsDir(),
+		HashInfo: remoteObj.GetHash(),
+	}, nil
+}
+
+func (d *Versioning) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	remoteFullPath := dir.GetPath()
+	if d.isVersion(remoteFullPath) {
+		return nil, errs.ObjectNotFound
+	}
+	objs, err := fs.List(ctx, remoteFullPath, &fs.ListArgs{NoLog: true, Refresh: args.Refresh})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		p := stdpath.Join(remoteFullPath, obj.GetName())
+		if p == d.versionsPath {
+			continue
+		}
+		objRes := model.Object{
+			Path:     p,
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			IsFolder: obj.IsDir(),
+			HashInfo: obj.GetHash(),
+		}
+		if thumb, ok := model.GetThumb(obj); ok {
+			result = append(result, &model.ObjThumb{
+				Object:    objRes,
+				Thumbnail: model.Thumbnail{Thumbnail: thumb},
+			})
+		} else {
+			result = append(result, &objRes)
+		}
+	}
+	return result, nil
+}
+
+func (d *Versioning) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// proxy || ftp,s3
+	if common.GetApiUrl(ctx) == "" {
+		args.Redirect = false
+	}
+	if d.isVersion(file.GetPath()) {
+		return nil, errs.ObjectNotFound
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(file.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	if args.Redirect && common.ShouldProxy(storage, stdpath.Base(actualPath)) {
+		return &model.Link{
+			URL: fmt.Sprintf("%s/p%s?sign=%s",
+				common.GetApiUrl(ctx),
+				utils.EncodePath(file.GetPath(), true),
+				sign.Sign(file.GetPath())),
+		}, nil
+	}
+	link, obj, err := op.Link(ctx, storage, actualPath, args)
+	if err != nil {
+		return nil, err
+	}
+	resultLink := *link
+	resultLink.SyncClosers = utils.NewSyncClosers(link)
+	if !args.Redirect && resultLink.ContentLength == 0 {
+		resultLink.ContentLength = obj.GetSize()
+	}
+	return &resultLink, nil
+}
+
+func (d *Versioning) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	if d.isVersion(parentDir.GetPath()) {
+		return errs.PermissionDenied
+	}
+	return fs.MakeDir(ctx, stdpath.Join(parentDir.GetPath(), dirName))
+}
+
+// moveVersions moves the versions of the object along with it, failures only lose the history
+func (d *Versioning) moveVersions(ctx context.Context, src, dst string) {
+	srcVersions, dstVersions := d.versionsOf(src), d.versionsOf(dst)
+	if _, err := fs.Get(ctx, srcVersions, &fs.GetArgs{NoLog: true}); err != nil {
+		return
+	}
+	var err error
+	if _, err = fs.Get(ctx, dstVersions, &fs.GetArgs{NoLog: true}); err == nil {
+		// the moved object has replaced another one, keep the versions of both
+		err = mergeVersions(ctx, srcVersions, dstVersions)
+	} else if stdpath.Dir(srcVersions) == stdpath.Dir(dstVersions) {
+		err = fs.Rename(ctx, srcVersions, stdpath.Base(dstVersions))
+	} else if err = fs.MakeDir(ctx, stdpath.Dir(dstVersions)); err == nil {
+		_, err = fs.Move(ctx, srcVersions, stdpath.Dir(dstVersions))
+	}
+	if err != nil {
+		log.Warnf("failed to move the versions of %s: %+v", src, err)
+	}
+}
+
+func (d *Versioning) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	if d.isVersion(srcObj.GetPath()) || d.isVersion(dstDir.GetPath()) {
+		return errs.PermissionDenied
+	}
+	if err := d.saveOverwritten(ctx, srcObj, stdpath.Join(dstDir.GetPath(), srcObj.GetName())); err != nil {
+		return err
+	}
+	if _, err := fs.Move(ctx, srcObj.GetPath(), dstDir.GetPath()); err != nil {
+		return err
+	}
+	d.moveVersions(ctx, srcObj.GetPath(), stdpath.Join(dstDir.GetPath(), srcObj.GetName()))
+	return nil
+}
+
+func (d *Versioning) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	if d.isVersion(srcObj.GetPath()) {
+		return errs.PermissionDenied
+	}
+	if err := d.saveOverwritten(ctx, srcObj, stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName)); err != nil {
+		return err
+	}
+	if err := fs.Rename(ctx, srcObj.GetPath(), newName); err != nil {
+		return err
+	}
+	d.moveVersions(ctx, srcObj.GetPath(), stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName))
+	return nil
+}
+
+func (d *Versioning) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	if d.isVersion(srcObj.GetPath()) || d.isVersion(dstDir.GetPath()) {
+		return errs.PermissionDenied
+	}
+	if err := d.saveOverwritten(ctx, srcObj, stdpath.Join(dstDir.GetPath(), srcObj.GetName())); err != nil {
+		return err
+	}
+	_, err := fs.Copy(ctx, srcObj.GetPath(), dstDir.GetPath())
+	return err
+}
+
+func (d *Versioning) Remove(ctx context.Context, obj model.Obj) error {
+	if d.isVersion(obj.GetPath()) {
+		return errs.PermissionDenied
+	}
+	if !obj.IsDir() {
+		return d.saveVersion(ctx, obj.GetPath(), true)
+	}
+	if err := d.saveDirVersions(ctx, obj.GetPath()); err != nil {
+		return err
+	}
+	return fs.Remove(ctx, obj.GetPath())
+}
+
+func (d *Versioning) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	if d.isVersion(dstDir.GetPath()) {
+		return errs.PermissionDenied
+	}
+	if err := d.saveOverwritten(ctx, s, stdpath.Join(dstDir.GetPath(), s.GetName())); err != nil {
+		return err
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	return op.Put(ctx, storage, actualPath, &stream.FileStream{
+		Obj:      s,
+		Mimetype: s.GetMimetype(),
+		Reader:   s,
+	}, up)
+}
+
+func (d *Versioning) Other(ctx context.Context, args model.OtherArgs) (interface{}, error) {
+	data, ok := args.Data.(model.FileVersionArgs)
+	if !ok || data.Name == "" || d.isVersion(args.Obj.GetPath()) {
+		return nil, errs.NotSupport
+	}
+	file := stdpath.Join(args.Obj.GetPath(), data.Name)
+	switch args.Method {
+	case model.OtherVersions:
+		versions := d.listVersions(ctx, d.versionsOf(file))
+		if versions == nil {
+			versions = []model.FileVersion{}
+		}
+		return versions, nil
+	case model.OtherRestoreVersion:
+		src, _, err := d.getVersion(ctx, file, data.Version)
+		if err != nil {
+			return nil, err
+		}
+		if obj, err := fs.Get(ctx, file, &fs.GetArgs{NoLog: true}); err == nil && !obj.IsDir() {
+			if err = d.saveVersion(ctx, file, true); err != nil {
+				return nil, err
+			}
+		}
+		return nil, transfer(ctx, src, args.Obj.GetPath(), data.Name, false)
+	case model.OtherVersionLink:
+		return d.versionLink(ctx, file, data)
+	default:
+		return nil, errs.NotSupport
+	}
+}
+
+func (d *Versioning) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	remoteStorage, _, err := op.GetStorageAndActualPath(d.RemotePath)
+	if err != nil {
+		return nil, errs.NotImplement
+	}
+	remoteDetails, err := op.GetStorageDetails(ctx, remoteStorage)
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		DiskUsage: remoteDetails.DiskUsage,
+	}, nil
+}
+
+var _ driver.Driver = (*Versioning)(nil)
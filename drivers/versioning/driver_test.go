@@ -0,0 +1,178 @@
+package versioning
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	dir, err := os.MkdirTemp("", "versioning")
+	if err != nil {
+		panic(err)
+	}
+	conf.Conf = conf.DefaultConfig(dir)
+	if err = os.MkdirAll(conf.Conf.TempDir, 0o777); err != nil {
+		panic(err)
+	}
+	db.Init(dB)
+}
+
+// mount mounts a versioning storage at /ver over a local storage of a temp dir, and returns the dir
+func mount(t *testing.T) string {
+	dir := t.TempDir()
+	ctx := context.Background()
+	localId, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/ver_remote",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q,"show_hidden":true,"mkdir_perm":"777","recycle_bin_path":"delete permanently"}`, dir),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Versioning",
+		MountPath: "/ver",
+		Addition:  `{"remote_path":"/ver_remote","max_versions":10,"max_age":0}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = op.DeleteStorageById(context.Background(), id)
+		_ = op.DeleteStorageById(context.Background(), localId)
+	})
+	return dir
+}
+
+// noTask runs the moves and copies in place
+func noTask() context.Context {
+	return context.WithValue(context.Background(), conf.NoTaskKey, struct{}{})
+}
+
+func put(t *testing.T, dir, name, content string) {
+	// the versions are named by the time in milliseconds
+	time.Sleep(5 * time.Millisecond)
+	err := fs.PutDirectly(noTask(), dir, &stream.FileStream{
+		Ctx:    context.Background(),
+		Obj:    &model.Object{Name: name, Size: int64(len(content)), Modified: time.Now()},
+		Reader: strings.NewReader(content),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func versions(t *testing.T, path string) []model.FileVersion {
+	vs, err := fs.Versions(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs
+}
+
+func readVersion(t *testing.T, path, version string) string {
+	ctx := context.Background()
+	link, obj, err := fs.VersionLink(ctx, path, version, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Length: obj.GetSize()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOverwriteSavesVersion(t *testing.T) {
+	mount(t)
+	put(t, "/ver", "a.txt", "")
+	put(t, "/ver", "a.txt", "v2")
+	vs := versions(t, "/ver/a.txt")
+	if len(vs) != 1 || vs[0].Size != 0 {
+		t.Fatalf("the empty file is not kept: %+v", vs)
+	}
+	put(t, "/ver", "b.txt", "b")
+	time.Sleep(5 * time.Millisecond)
+	if err := fs.Rename(noTask(), "/ver/b.txt", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if vs = versions(t, "/ver/a.txt"); len(vs) != 2 || readVersion(t, "/ver/a.txt", vs[0].Name) != "v2" {
+		t.Fatalf("overwriting rename: %+v", vs)
+	}
+	if err := fs.MakeDir(noTask(), "/ver/d"); err != nil {
+		t.Fatal(err)
+	}
+	put(t, "/ver/d", "a.txt", "d")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := fs.Move(noTask(), "/ver/d/a.txt", "/ver"); err != nil {
+		t.Fatal(err)
+	}
+	if vs = versions(t, "/ver/a.txt"); len(vs) != 3 || readVersion(t, "/ver/a.txt", vs[0].Name) != "b" {
+		t.Fatalf("overwriting move: %+v", vs)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := fs.Remove(noTask(), "/ver/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if vs = versions(t, "/ver/a.txt"); len(vs) != 4 || readVersion(t, "/ver/a.txt", vs[0].Name) != "d" {
+		t.Fatalf("remove: %+v", vs)
+	}
+}
+
+func TestVersionsHidden(t *testing.T) {
+	dir := mount(t)
+	put(t, "/ver", "a.txt", "v1")
+	put(t, "/ver", "a.txt", "v2")
+	if _, err := os.Stat(dir + "/" + versionsDir + "/a.txt"); err != nil {
+		t.Fatalf("no version is saved: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := fs.Get(ctx, "/ver/"+versionsDir, &fs.GetArgs{}); err == nil {
+		t.Error("the versions dir is reachable")
+	}
+	if _, err := fs.List(ctx, "/ver/"+versionsDir+"/a.txt", &fs.ListArgs{}); err == nil {
+		t.Error("the versions are listed")
+	}
+	objs, err := fs.List(ctx, "/ver", &fs.ListArgs{Refresh: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if obj.GetName() == versionsDir {
+			t.Error("the versions dir is listed")
+		}
+	}
+	if _, _, err = fs.VersionLink(ctx, "/ver/a.txt", "../a.txt", model.LinkArgs{}); err == nil {
+		t.Error("a path out of the versions is linked")
+	}
+}
@@ -0,0 +1,33 @@
+package versioning
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	RemotePath   string `json:"remote_path" required:"true" help:"This is where the files store"`
+	VersionsPath string `json:"versions_path" help:"This is where the previous revisions store, the .versions dir of the remote path by default"`
+	MaxVersions  int    `json:"max_versions" type:"number" default:"10" help:"Versions kept for each file, 0 means no limit"`
+	MaxAge       int    `json:"max_age" type:"number" default:"30" help:"Unit: day, older versions are removed, 0 means no limit"`
+}
+
+var config = driver.Config{
+	Name:             "Versioning",
+	LocalSort:        true,
+	NoCache:          true,
+	DefaultRoot:      "/",
+	CheckStatus:      true,
+	ProxyRangeOption: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Versioning{
+			Addition: Addition{
+				MaxVersions: 10,
+				MaxAge:      30,
+			},
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package versioning
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	versionsDir = ".versions"
+	// versions are named <time>_<name> so that they sort by time and keep the extension
+	timeLayout = "20060102T150405.000Z"
+)
+
+func versionName(t time.Time, name string) string {
+	return t.UTC().Format(timeLayout) + "_" + name
+}
+
+func parseVersionName(name string) (time.Time, bool) {
+	ts, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeLayout, ts)
+	return t, err == nil
+}
+
+// isVersion reports whether the remote path is in the versions area
+func (d *Versioning) isVersion(remotePath string) bool {
+	return remotePath == d.versionsPath || strings.HasPrefix(remotePath, d.versionsPath+"/")
+}
+
+// logical converts a remote path of a file to the path in the storage
+func (d *Versioning) logical(remotePath string) string {
+	if d.RemotePath == "/" {
+		return remotePath
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(remotePath, d.RemotePath), "/")
+}
+
+// versionsOf is the remote dir holding the versions of the file
+func (d *Versioning) versionsOf(remotePath string) string {
+	return stdpath.Join(d.versionsPath, d.logical(remotePath))
+}
+
+// transfer copies or moves the remote file src into the remote dir dstDir with the name
+func transfer(ctx context.Context, src, dstDir, name string, move bool) error {
+	srcStorage, srcActualPath, err := op.GetStorageAndActualPath(src)
+	if err != nil {
+		return err
+	}
+	dstStorage, dstActualPath, err := op.GetStorageAndActualPath(dstDir)
+	if err != nil {
+		return err
+	}
+	if err = op.MakeDir(ctx, dstStorage, dstActualPath); err != nil {
+		return err
+	}
+	if srcStorage.GetStorage().MountPath == dstStorage.GetStorage().MountPath {
+		if move {
+			err = op.Move(ctx, srcStorage, srcActualPath, dstActualPath)
+		} else {
+			err = op.Copy(ctx, srcStorage, srcActualPath, dstActualPath)
+		}
+		if err == nil {
+			if stdpath.Base(srcActualPath) == name {
+				return nil
+			}
+			return op.Rename(ctx, dstStorage, stdpath.Join(dstActualPath, stdpath.Base(srcActualPath)), name)
+		}
+		if !errs.IsNotImplementError(err) {
+			return err
+		}
+	}
+	// copy the content between the storages
+	link, obj, err := op.Link(ctx, srcStorage, srcActualPath, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			HashInfo: obj.GetHash(),
+		},
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return err
+	}
+	if err = op.Put(ctx, dstStorage, dstActualPath, ss, nil); err != nil {
+		return err
+	}
+	if move {
+		return op.Remove(ctx, srcStorage, srcActualPath)
+	}
+	return nil
+}
+
+// saveVersion keeps the current content of the remote file as a version,
+// it's moved if the file is going to be deleted
+func (d *Versioning) saveVersion(ctx context.Context, remotePath string, move bool) error {
+	dir := d.versionsOf(remotePath)
+	if err := transfer(ctx, remotePath, dir, versionName(time.Now(), stdpath.Base(remotePath)), move); err != nil {
+		return err
+	}
+	d.prune(ctx, dir)
+	return nil
+}
+
+// saveOverwritten keeps the versions of the remote files which are going to be replaced by src at dst,
+// a dir replacing a dir is merged into it so only the files with the same paths are overwritten
+func (d *Versioning) saveOverwritten(ctx context.Context, src model.Obj, dst string) error {
+	if src.GetPath() == dst {
+		return nil
+	}
+	obj, err := fs.Get(ctx, dst, &fs.GetArgs{NoLog: true})
+	if err != nil || obj.IsDir() != src.IsDir() {
+		return nil
+	}
+	if !src.IsDir() {
+		// the old content is copied rather than moved, so it's kept if the overwriting fails
+		if err = d.saveVersion(ctx, dst, false); err != nil {
+			return fmt.Errorf("failed to save the version of %s: %w", stdpath.Base(dst), err)
+		}
+		return nil
+	}
+	objs, err := fs.List(ctx, src.GetPath(), &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		child := &model.Object{
+			Path:     stdpath.Join(src.GetPath(), obj.GetName()),
+			Name:     obj.GetName(),
+			IsFolder: obj.IsDir(),
+		}
+		if err = d.saveOverwritten(ctx, child, stdpath.Join(dst, obj.GetName())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeVersions moves the versions in the remote dir src into dst which already exists
+func mergeVersions(ctx context.Context, src, dst string) error {
+	objs, err := fs.List(ctx, src, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		p, q := stdpath.Join(src, obj.GetName()), stdpath.Join(dst, obj.GetName())
+		if obj.IsDir() {
+			if _, err = fs.Get(ctx, q, &fs.GetArgs{NoLog: true}); err == nil {
+				if err = mergeVersions(ctx, p, q); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err = transfer(ctx, p, dst, obj.GetName(), true); err != nil {
+			return err
+		}
+	}
+	return fs.Remove(ctx, src)
+}
+
+// getVersion finds the version of the remote file
+func (d *Versioning) getVersion(ctx context.Context, remotePath, version string) (string, model.Obj, error) {
+	if version == "" || strings.Contains(version, "/") {
+		return "", nil, errs.ObjectNotFound
+	}
+	p := stdpath.Join(d.versionsOf(remotePath), version)
+	obj, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return "", nil, err
+	}
+	if obj.IsDir() {
+		return "", nil, errs.ObjectNotFound
+	}
+	return p, obj, nil
+}
+
+// versionLink links a version of the remote file, the object is named after the file
+func (d *Versioning) versionLink(ctx context.Context, remotePath string, data model.FileVersionArgs) (*model.VersionLink, error) {
+	p, obj, err := d.getVersion(ctx, remotePath, data.Version)
+	if err != nil {
+		return nil, err
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(p)
+	if err != nil {
+		return nil, err
+	}
+	link, _, err := op.Link(ctx, storage, actualPath, data.Link)
+	if err != nil {
+		return nil, err
+	}
+	resultLink := *link
+	resultLink.SyncClosers = utils.NewSyncClosers(link)
+	modified, _ := parseVersionName(data.Version)
+	return &model.VersionLink{
+		Link: &resultLink,
+		Obj: &model.Object{
+			Name:     data.Name,
+			Size:     obj.GetSize(),
+			Modified: modified,
+			HashInfo: obj.GetHash(),
+		},
+	}, nil
+}
+
+// saveDirVersions keeps the versions of all the files in the remote dir
+func (d *Versioning) saveDirVersions(ctx context.Context, remoteDir string) error {
+	objs, err := fs.List(ctx, remoteDir, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		p := stdpath.Join(remoteDir, obj.GetName())
+		if obj.IsDir() {
+			err = d.saveDirVersions(ctx, p)
+		} else {
+			err = d.saveVersion(ctx, p, true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listVersions lists the versions in the remote dir, newest first
+func (d *Versioning) listVersions(ctx context.Context, dir string) []model.FileVersion {
+	objs, err := fs.List(ctx, dir, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return nil
+	}
+	var versions []model.FileVersion
+	for _, obj := range objs {
+		t, ok := parseVersionName(obj.GetName())
+		if !ok || obj.IsDir() {
+			continue
+		}
+		versions = append(versions, model.FileVersion{
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: t,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Modified.After(versions[j].Modified)
+	})
+	return versions
+}
+
+// prune removes the versions beyond the count and age limits
+func (d *Versioning) prune(ctx context.Context, dir string) {
+	expire := time.Now().AddDate(0, 0, -d.MaxAge)
+	for i, v := range d.listVersions(ctx, dir) {
+		if (d.MaxVersions > 0 && i >= d.MaxVersions) || (d.MaxAge > 0 && v.Modified.Before(expire)) {
+			if err := fs.Remove(ctx, stdpath.Join(dir, v.Name)); err != nil {
+				log.Warnf("failed to remove expired version %s: %+v", v.Name, err)
+			}
+		}
+	}
+}
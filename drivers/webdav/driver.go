@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -97,6 +98,10 @@ func (d *WebDav) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer
 	callback := func(r *http.Request) {
 		r.Header.Set("Content-Type", s.GetMimetype())
 		r.ContentLength = s.GetSize()
+		if modTime := s.ModTime(); !modTime.IsZero() {
+			// honored by ownCloud, Nextcloud and OpenList
+			r.Header.Set("X-OC-Mtime", strconv.FormatInt(modTime.Unix(), 10))
+		}
 	}
 	reader := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
 		Reader:         s,
@@ -106,4 +111,9 @@ func (d *WebDav) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer
 	return err
 }
 
+func (d *WebDav) SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error {
+	return d.client.SetModTime(obj.GetPath(), modTime)
+}
+
 var _ driver.Driver = (*WebDav)(nil)
+var _ driver.SetModTime = (*WebDav)(nil)
@@ -0,0 +1,30 @@
+package webdav
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+	"golang.org/x/net/webdav"
+)
+
+func TestConformance(t *testing.T) {
+	srv := httptest.NewServer(&webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+	t.Cleanup(srv.Close)
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver { return &WebDav{} },
+		Addition: Addition{
+			Vendor:   "other",
+			Address:  srv.URL,
+			RootPath: driver.RootPath{RootFolderPath: "/"},
+		},
+		LargeSize: 16 << 20,
+		Skip: map[string]string{
+			"SetModTime": "x/net/webdav can't change the modification time",
+		},
+	})
+}
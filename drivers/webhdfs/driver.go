@@ -0,0 +1,183 @@
+package webhdfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/go-resty/resty/v2"
+)
+
+type WebHDFS struct {
+	model.Storage
+	Addition
+	chunkSize int64
+}
+
+func (d *WebHDFS) Config() driver.Config {
+	return config
+}
+
+func (d *WebHDFS) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *WebHDFS) Init(ctx context.Context) error {
+	d.Address = strings.TrimSuffix(d.Address, "/")
+	d.chunkSize = d.ChunkSize * utils.MB
+	var resp FileStatusResp
+	if err := d.request(ctx, http.MethodGet, d.GetRootPath(), "GETFILESTATUS", nil, &resp); err != nil {
+		return err
+	}
+	if resp.FileStatus.Type != "DIRECTORY" {
+		return errs.NotFolder
+	}
+	return nil
+}
+
+func (d *WebHDFS) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *WebHDFS) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	statuses, err := d.list(ctx, dir.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	return utils.SliceConvert(statuses, func(src FileStatus) (model.Obj, error) {
+		return src.toObj(dir.GetPath()), nil
+	})
+}
+
+func (d *WebHDFS) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	path := file.GetPath()
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			return d.open(ctx, path, httpRange)
+		}),
+		ContentLength: file.GetSize(),
+	}, nil
+}
+
+func (d *WebHDFS) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	path := stdpath.Join(parentDir.GetPath(), dirName)
+	var resp BooleanResp
+	if err := d.request(ctx, http.MethodPut, path, "MKDIRS", nil, &resp); err != nil {
+		return err
+	}
+	return checkBoolean(resp, "MKDIRS", path)
+}
+
+func (d *WebHDFS) rename(ctx context.Context, src, dst string) error {
+	var resp BooleanResp
+	err := d.request(ctx, http.MethodPut, src, "RENAME", func(req *resty.Request) {
+		req.SetQueryParam("destination", dst)
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	return checkBoolean(resp, "RENAME", src)
+}
+
+func (d *WebHDFS) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.rename(ctx, srcObj.GetPath(), stdpath.Join(dstDir.GetPath(), srcObj.GetName()))
+}
+
+func (d *WebHDFS) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.rename(ctx, srcObj.GetPath(), stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName))
+}
+
+func (d *WebHDFS) delete(ctx context.Context, path string, recursive bool) error {
+	var resp BooleanResp
+	err := d.request(ctx, http.MethodDelete, path, "DELETE", func(req *resty.Request) {
+		req.SetQueryParam("recursive", strconv.FormatBool(recursive))
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	return checkBoolean(resp, "DELETE", path)
+}
+
+func (d *WebHDFS) Remove(ctx context.Context, obj model.Obj) error {
+	return d.delete(ctx, obj.GetPath(), true)
+}
+
+// upload creates the file with the first chunk and appends the others
+func (d *WebHDFS) upload(ctx context.Context, path string, s model.FileStreamer, up driver.UpdateProgress) error {
+	reader := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+		Reader:         s,
+		UpdateProgress: up,
+	})
+	size := s.GetSize()
+	first := size
+	if d.chunkSize > 0 && size > d.chunkSize {
+		first = d.chunkSize
+	}
+	loc, err := d.location(ctx, http.MethodPut, path, "CREATE", map[string]string{"overwrite": "false"})
+	if err != nil {
+		return err
+	}
+	if err = d.write(ctx, http.MethodPut, loc, io.LimitReader(reader, first), first); err != nil {
+		return err
+	}
+	for offset := first; offset < size; offset += d.chunkSize {
+		n := min(d.chunkSize, size-offset)
+		if loc, err = d.location(ctx, http.MethodPost, path, "APPEND", nil); err != nil {
+			return err
+		}
+		if err = d.write(ctx, http.MethodPost, loc, io.LimitReader(reader, n), n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put uploads to a temp file and renames it into place, so the file is never left half written
+func (d *WebHDFS) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	path := stdpath.Join(dstDir.GetPath(), s.GetName())
+	tmp := stdpath.Join(dstDir.GetPath(), "."+s.GetName()+"."+random.String(8)+".uploading")
+	err := d.upload(ctx, tmp, s, up)
+	if err == nil {
+		// RENAME never overwrites
+		if err = d.rename(ctx, tmp, path); err != nil {
+			if err = d.delete(ctx, path, false); err == nil {
+				err = d.rename(ctx, tmp, path)
+			}
+		}
+	}
+	if err != nil {
+		// the upload may have been canceled
+		_ = d.delete(context.WithoutCancel(ctx), tmp, false)
+	}
+	return err
+}
+
+// GetDetails reads the space quota of the root dir
+func (d *WebHDFS) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	var resp ContentSummaryResp
+	if err := d.request(ctx, http.MethodGet, d.GetRootPath(), "GETCONTENTSUMMARY", nil, &resp); err != nil {
+		return nil, err
+	}
+	summary := resp.ContentSummary
+	if summary.SpaceQuota <= 0 {
+		return nil, errs.NotImplement
+	}
+	return &model.StorageDetails{
+		DiskUsage: model.DiskUsage{
+			TotalSpace: uint64(summary.SpaceQuota),
+			FreeSpace:  uint64(max(summary.SpaceQuota-summary.SpaceConsumed, 0)),
+		},
+	}, nil
+}
+
+var _ driver.Driver = (*WebHDFS)(nil)
@@ -0,0 +1,284 @@
+package webhdfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver/drivertest"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+type fakeNode struct {
+	dir      bool
+	data     []byte
+	modified time.Time
+}
+
+// fakeHDFS is an in-memory namenode and datanode of the WebHDFS REST API,
+// CREATE and APPEND are redirected to /data like a namenode does
+type fakeHDFS struct {
+	mu    sync.Mutex
+	nodes map[string]*fakeNode
+	url   string
+}
+
+func newFakeHDFS(t *testing.T) *fakeHDFS {
+	f := &fakeHDFS{nodes: map[string]*fakeNode{"/": {dir: true, modified: time.Now()}}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f
+}
+
+func (f *fakeHDFS) reply(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeHDFS) fail(w http.ResponseWriter, status int, exception, message string) {
+	var e RemoteExceptionResp
+	e.RemoteException.Exception = exception
+	e.RemoteException.Message = message
+	f.reply(w, status, e)
+}
+
+func (f *fakeHDFS) status(name string, n *fakeNode) FileStatus {
+	s := FileStatus{PathSuffix: name, Type: "FILE", Length: int64(len(n.data)), ModificationTime: n.modified.UnixMilli()}
+	if n.dir {
+		s.Type, s.Length = "DIRECTORY", 0
+	}
+	return s
+}
+
+func (f *fakeHDFS) children(path string) []string {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var names []string
+	for p := range f.nodes {
+		if p != "/" && strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			names = append(names, p[len(prefix):])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *fakeHDFS) mkdirs(path string) bool {
+	for p := path; p != "/"; p = stdpath.Dir(p) {
+		if n, ok := f.nodes[p]; ok {
+			if !n.dir {
+				return false
+			}
+			continue
+		}
+		f.nodes[p] = &fakeNode{dir: true, modified: time.Now()}
+	}
+	return true
+}
+
+func (f *fakeHDFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	if p, ok := strings.CutPrefix(r.URL.Path, "/data"); ok {
+		f.serveData(w, r, stdpath.Clean(p), q)
+		return
+	}
+	path := stdpath.Clean("/" + strings.TrimPrefix(r.URL.Path, "/webhdfs/v1"))
+	n := f.nodes[path]
+	op := q.Get("op")
+	if n == nil && op != "MKDIRS" && op != "CREATE" && op != "RENAME" && op != "DELETE" {
+		f.fail(w, 404, "FileNotFoundException", "File does not exist: "+path)
+		return
+	}
+	switch op {
+	case "GETFILESTATUS":
+		f.reply(w, 200, FileStatusResp{FileStatus: f.status("", n)})
+	case "LISTSTATUS":
+		var resp ListStatusResp
+		resp.FileStatuses.FileStatus = []FileStatus{}
+		for _, name := range f.children(path) {
+			resp.FileStatuses.FileStatus = append(resp.FileStatuses.FileStatus, f.status(name, f.nodes[stdpath.Join(path, name)]))
+		}
+		f.reply(w, 200, resp)
+	case "MKDIRS":
+		f.reply(w, 200, BooleanResp{Boolean: f.mkdirs(path)})
+	case "CREATE":
+		if n != nil && (n.dir || q.Get("overwrite") != "true") {
+			f.fail(w, 403, "FileAlreadyExistsException", path+" already exists")
+			return
+		}
+		w.Header().Set("Location", f.url+"/data"+path+"?"+q.Encode())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	case "APPEND":
+		w.Header().Set("Location", f.url+"/data"+path+"?"+q.Encode())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	case "RENAME":
+		dst := q.Get("destination")
+		parent := f.nodes[stdpath.Dir(dst)]
+		if n == nil || f.nodes[dst] != nil || parent == nil || !parent.dir {
+			f.reply(w, 200, BooleanResp{Boolean: false})
+			return
+		}
+		for p, node := range f.nodes {
+			if p == path || strings.HasPrefix(p, path+"/") {
+				delete(f.nodes, p)
+				f.nodes[dst+strings.TrimPrefix(p, path)] = node
+			}
+		}
+		f.reply(w, 200, BooleanResp{Boolean: true})
+	case "DELETE":
+		if n == nil {
+			f.reply(w, 200, BooleanResp{Boolean: false})
+			return
+		}
+		if n.dir && q.Get("recursive") != "true" && len(f.children(path)) > 0 {
+			f.fail(w, 403, "PathIsNotEmptyDirectoryException", path+" is non empty")
+			return
+		}
+		for p := range f.nodes {
+			if p == path || strings.HasPrefix(p, path+"/") {
+				delete(f.nodes, p)
+			}
+		}
+		f.reply(w, 200, BooleanResp{Boolean: true})
+	case "OPEN":
+		if n.dir {
+			f.fail(w, 404, "FileNotFoundException", path+" is a directory")
+			return
+		}
+		offset, _ := strconv.ParseInt(q.Get("offset"), 10, 64)
+		data := n.data[min(offset, int64(len(n.data))):]
+		if l := q.Get("length"); l != "" {
+			length, _ := strconv.ParseInt(l, 10, 64)
+			data = data[:min(length, int64(len(data)))]
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	default:
+		f.fail(w, 400, "UnsupportedOperationException", op+" is not supported")
+	}
+}
+
+func (f *fakeHDFS) serveData(w http.ResponseWriter, r *http.Request, path string, q map[string][]string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		// the client went away in the middle of the upload, the data received is kept like a datanode does
+		data = append(data, "<partial>"...)
+	}
+	switch q["op"][0] {
+	case "CREATE":
+		if !f.mkdirs(stdpath.Dir(path)) {
+			f.fail(w, 403, "ParentNotDirectoryException", stdpath.Dir(path)+" is not a directory")
+			return
+		}
+		f.nodes[path] = &fakeNode{data: data, modified: time.Now()}
+		w.WriteHeader(http.StatusCreated)
+	case "APPEND":
+		n := f.nodes[path]
+		if n == nil || n.dir {
+			f.fail(w, 404, "FileNotFoundException", "File does not exist: "+path)
+			return
+		}
+		n.data = append(n.data, data...)
+		n.modified = time.Now()
+	}
+}
+
+func (f *fakeHDFS) read(path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.nodes[path]
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(n.data), true
+}
+
+func TestConformance(t *testing.T) {
+	f := newFakeHDFS(t)
+	drivertest.Run(t, drivertest.Harness{
+		New: func() driver.Driver { return &WebHDFS{} },
+		Addition: Addition{
+			RootPath:  driver.RootPath{RootFolderPath: "/"},
+			Address:   f.url,
+			Username:  "openlist",
+			ChunkSize: 1,
+		},
+		LargeSize: 5 << 19,
+	})
+}
+
+// failingReader fails after some bytes
+type failingReader struct {
+	r     io.Reader
+	after int
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.after <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	p = p[:min(len(p), r.after)]
+	n, err := r.r.Read(p)
+	r.after -= n
+	return n, err
+}
+
+func TestPutKeepsFileOnFailure(t *testing.T) {
+	if base.RestyClient == nil {
+		base.InitClient()
+	}
+	f := newFakeHDFS(t)
+	d := &WebHDFS{Addition: Addition{
+		RootPath:  driver.RootPath{RootFolderPath: "/"},
+		Address:   f.url,
+		ChunkSize: 1,
+	}}
+	ctx := context.Background()
+	if err := d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	root := &model.Object{Path: "/", IsFolder: true}
+	put := func(r io.Reader, size int) error {
+		return d.Put(ctx, root, &stream.FileStream{
+			Ctx:    ctx,
+			Obj:    &model.Object{Name: "a.bin", Size: int64(size)},
+			Reader: r,
+		}, func(float64) {})
+	}
+	if err := put(strings.NewReader("old"), 3); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("x"), 3<<20)
+	if err := put(&failingReader{r: bytes.NewReader(data), after: 3 << 19}, len(data)); err == nil {
+		t.Fatal("the failed upload succeeded")
+	}
+	if got, _ := f.read("/a.bin"); string(got) != "old" {
+		t.Fatalf("the file is replaced by %d bytes of a failed upload", len(got))
+	}
+	if names := f.children("/"); len(names) != 1 {
+		t.Fatalf("the temp file is left: %v", names)
+	}
+	if err := put(bytes.NewReader(data), len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.read("/a.bin"); !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes after replacing the file", len(got))
+	}
+}
@@ -0,0 +1,33 @@
+package webhdfs
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	driver.RootPath
+	Address         string `json:"address" required:"true" help:"e.g. http://namenode:9870 for WebHDFS or http://httpfs:14000 for HttpFS"`
+	Username        string `json:"username" help:"The user of the simple authentication"`
+	DelegationToken string `json:"delegation_token" help:"Used instead of the username if set"`
+	ChunkSize       int64  `json:"chunk_size" type:"number" default:"256" help:"Unit: MB, larger files are created with the first chunk and appended chunk by chunk, 0 to disable"`
+}
+
+var config = driver.Config{
+	Name:        "WebHDFS",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	NoLinkURL:   true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &WebHDFS{
+			Addition: Addition{
+				ChunkSize: 256,
+			},
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package webhdfs
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+type FileStatus struct {
+	AccessTime       int64  `json:"accessTime"`
+	BlockSize        int64  `json:"blockSize"`
+	Group            string `json:"group"`
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"`
+	Owner            string `json:"owner"`
+	PathSuffix       string `json:"pathSuffix"`
+	Permission       string `json:"permission"`
+	Replication      int    `json:"replication"`
+	Type             string `json:"type"`
+}
+
+func (f FileStatus) toObj(dir string) *model.Object {
+	return &model.Object{
+		Path:     stdpath.Join(dir, f.PathSuffix),
+		Name:     f.PathSuffix,
+		Size:     f.Length,
+		Modified: time.UnixMilli(f.ModificationTime),
+		IsFolder: f.Type == "DIRECTORY",
+	}
+}
+
+type FileStatusResp struct {
+	FileStatus FileStatus `json:"FileStatus"`
+}
+
+type FileStatuses struct {
+	FileStatus []FileStatus `json:"FileStatus"`
+}
+
+type ListStatusResp struct {
+	FileStatuses FileStatuses `json:"FileStatuses"`
+}
+
+type ListStatusBatchResp struct {
+	DirectoryListing struct {
+		PartialListing struct {
+			FileStatuses FileStatuses `json:"FileStatuses"`
+		} `json:"partialListing"`
+		RemainingEntries int `json:"remainingEntries"`
+	} `json:"DirectoryListing"`
+}
+
+type BooleanResp struct {
+	Boolean bool `json:"boolean"`
+}
+
+type ContentSummaryResp struct {
+	ContentSummary struct {
+		DirectoryCount int64 `json:"directoryCount"`
+		FileCount      int64 `json:"fileCount"`
+		Length         int64 `json:"length"`
+		Quota          int64 `json:"quota"`
+		SpaceConsumed  int64 `json:"spaceConsumed"`
+		SpaceQuota     int64 `json:"spaceQuota"`
+	} `json:"ContentSummary"`
+}
+
+type LocationResp struct {
+	Location string `json:"Location"`
+}
+
+type RemoteExceptionResp struct {
+	RemoteException struct {
+		Exception     string `json:"exception"`
+		JavaClassName string `json:"javaClassName"`
+		Message       string `json:"message"`
+	} `json:"RemoteException"`
+}
+
+func (e *RemoteExceptionResp) toError(status int) error {
+	switch e.RemoteException.Exception {
+	case "FileNotFoundException":
+		return errs.ObjectNotFound
+	case "AccessControlException", "SecurityException":
+		return errs.PermissionDenied
+	case "":
+		return fmt.Errorf("webhdfs: status %d", status)
+	}
+	return fmt.Errorf("webhdfs: %s: %s", e.RemoteException.Exception, e.RemoteException.Message)
+}
@@ -0,0 +1,165 @@
+package webhdfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+func (d *WebHDFS) url(path string) string {
+	return d.Address + "/webhdfs/v1" + utils.EncodePath(path, true)
+}
+
+func (d *WebHDFS) query(op string) url.Values {
+	q := url.Values{"op": []string{op}}
+	if d.DelegationToken != "" {
+		q.Set("delegation", d.DelegationToken)
+	} else if d.Username != "" {
+		q.Set("user.name", d.Username)
+	}
+	return q
+}
+
+func (d *WebHDFS) request(ctx context.Context, method, path, op string, callback base.ReqCallback, resp interface{}) error {
+	var e RemoteExceptionResp
+	req := base.RestyClient.R().
+		SetContext(ctx).
+		SetQueryParamsFromValues(d.query(op)).
+		SetError(&e)
+	if callback != nil {
+		callback(req)
+	}
+	if resp != nil {
+		req.SetResult(resp)
+	}
+	res, err := req.Execute(method, d.url(path))
+	if err != nil {
+		return err
+	}
+	if res.IsError() {
+		return e.toError(res.StatusCode())
+	}
+	return nil
+}
+
+func decodeError(res *http.Response) error {
+	var e RemoteExceptionResp
+	_ = json.NewDecoder(res.Body).Decode(&e)
+	return e.toError(res.StatusCode)
+}
+
+func (d *WebHDFS) list(ctx context.Context, path string) ([]FileStatus, error) {
+	var statuses []FileStatus
+	startAfter := ""
+	for {
+		var resp ListStatusBatchResp
+		err := d.request(ctx, http.MethodGet, path, "LISTSTATUS_BATCH", func(req *resty.Request) {
+			if startAfter != "" {
+				req.SetQueryParam("startAfter", startAfter)
+			}
+		}, &resp)
+		if err != nil {
+			if startAfter != "" || errors.Is(err, errs.ObjectNotFound) || errors.Is(err, errs.PermissionDenied) {
+				return nil, err
+			}
+			// HttpFS and old clusters can only list all at once
+			var resp ListStatusResp
+			if err = d.request(ctx, http.MethodGet, path, "LISTSTATUS", nil, &resp); err != nil {
+				return nil, err
+			}
+			return resp.FileStatuses.FileStatus, nil
+		}
+		part := resp.DirectoryListing.PartialListing.FileStatuses.FileStatus
+		statuses = append(statuses, part...)
+		if resp.DirectoryListing.RemainingEntries == 0 || len(part) == 0 {
+			return statuses, nil
+		}
+		startAfter = part[len(part)-1].PathSuffix
+	}
+}
+
+// location asks the namenode where to write the data, the first step of CREATE and APPEND
+func (d *WebHDFS) location(ctx context.Context, method, path, op string, params map[string]string) (string, error) {
+	var e RemoteExceptionResp
+	var loc LocationResp
+	res, err := base.NoRedirectClient.R().
+		SetContext(ctx).
+		SetQueryParamsFromValues(d.query(op)).
+		SetQueryParams(params).
+		SetError(&e).
+		SetResult(&loc).
+		Execute(method, d.url(path))
+	if err != nil {
+		return "", err
+	}
+	if l := res.Header().Get("Location"); l != "" && res.StatusCode() >= 300 && res.StatusCode() < 400 {
+		return l, nil
+	}
+	if res.IsError() {
+		return "", e.toError(res.StatusCode())
+	}
+	if loc.Location == "" {
+		return "", fmt.Errorf("webhdfs: no location to %s %s", op, path)
+	}
+	return loc.Location, nil
+}
+
+// write sends the data to the location returned by the namenode
+func (d *WebHDFS) write(ctx context.Context, method, location string, r io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, method, location, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := base.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return decodeError(res)
+	}
+	return nil
+}
+
+func (d *WebHDFS) open(ctx context.Context, path string, httpRange http_range.Range) (io.ReadCloser, error) {
+	q := d.query("OPEN")
+	q.Set("offset", strconv.FormatInt(httpRange.Start, 10))
+	if httpRange.Length >= 0 {
+		q.Set("length", strconv.FormatInt(httpRange.Length, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url(path)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the namenode redirects to a datanode
+	res, err := base.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		return nil, decodeError(res)
+	}
+	return res.Body, nil
+}
+
+func checkBoolean(resp BooleanResp, op, path string) error {
+	if !resp.Boolean {
+		return fmt.Errorf("webhdfs: failed to %s %s", strings.ToLower(op), path)
+	}
+	return nil
+}
@@ -70,6 +70,27 @@ func (ISO9660) Extract(ss []*stream.SeekableStream, args model.ArchiveInnerArgs)
 	return io.NopCloser(obj.Reader()), obj.Size(), nil
 }
 
+func (ISO9660) LocateExtent(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) (int64, int64, error) {
+	img, err := getImage(ss[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	obj, err := getObj(img, args.InnerPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if obj.IsDir() {
+		return 0, 0, errs.NotFile
+	}
+	// the content of a file is a section of the image
+	sr, ok := obj.Reader().(*io.SectionReader)
+	if !ok {
+		return 0, 0, errs.NotSupport
+	}
+	_, offset, size := sr.Outer()
+	return offset, size, nil
+}
+
 func (ISO9660) Decompress(ss []*stream.SeekableStream, outputPath string, args model.ArchiveInnerArgs, up model.UpdateProgress) error {
 	img, err := getImage(ss[0])
 	if err != nil {
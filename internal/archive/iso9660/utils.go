@@ -26,10 +26,12 @@ func getObj(img *iso9660.Image, path string) (*iso9660.File, error) {
 	if err != nil {
 		return nil, err
 	}
-	if path == "/" {
+	// the parent of a file in the root is "" when getting the file
+	path = strings.Trim(path, "/")
+	if path == "" {
 		return obj, nil
 	}
-	paths := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	paths := strings.Split(path, "/")
 	for _, p := range paths {
 		if !obj.IsDir() {
 			return nil, errs.ObjectNotFound
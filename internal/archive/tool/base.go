@@ -21,3 +21,9 @@ type Tool interface {
 	Extract(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error)
 	Decompress(ss []*stream.SeekableStream, outputPath string, args model.ArchiveInnerArgs, up model.UpdateProgress) error
 }
+
+// ExtentLocator is implemented by the tools of the archives storing an inner file as is in a single extent,
+// so a range of the inner file is read as a range of the archive
+type ExtentLocator interface {
+	LocateExtent(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) (offset, size int64, err error)
+}
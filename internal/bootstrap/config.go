@@ -133,6 +133,8 @@ func InitConfig() {
 	convertAbsPath(&conf.Conf.Log.Name)
 	convertAbsPath(&conf.Conf.TempDir)
 	convertAbsPath(&conf.Conf.BleveDir)
+	convertAbsPath(&conf.Conf.DiskCache.Dir)
+	convertAbsPath(&conf.Conf.Thumbnail.Dir)
 	convertAbsPath(&conf.Conf.DistDir)
 
 	err := os.MkdirAll(conf.Conf.TempDir, 0o777)
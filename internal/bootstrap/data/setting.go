@@ -184,9 +184,13 @@ func InitialSettings() []model.SettingItem {
 		// single settings
 		{Key: conf.Token, Value: token, Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
 		{Key: conf.SearchIndex, Value: "none", Type: conf.TypeSelect, Options: "database,database_non_full_text,bleve,meilisearch,none", Group: model.INDEX},
-		{Key: conf.AutoUpdateIndex, Value: "false", Type: conf.TypeBool, Group: model.INDEX},
+		{Key: conf.AutoUpdateIndex, Value: "false", Type: conf.TypeBool, Group: model.INDEX, Help: `update the index on writes made through openlist, not supported by bleve which has to be rebuilt instead`},
 		{Key: conf.IgnorePaths, Value: "", Type: conf.TypeText, Group: model.INDEX, Flag: model.PRIVATE, Help: `one path per line`},
 		{Key: conf.MaxIndexDepth, Value: "20", Type: conf.TypeNumber, Group: model.INDEX, Flag: model.PRIVATE, Help: `max depth of index`},
+		{Key: conf.ContentIndex, Value: "false", Type: conf.TypeBool, Group: model.INDEX, Flag: model.PRIVATE, Help: `also index text of documents on storages with content index enabled, only bleve and meilisearch support it`},
+		{Key: conf.ContentIndexTypes, Value: "pdf,docx,odt,odp,ods,epub,md,markdown,csv,log,rst,tex,ts,jsx,kt,swift,rb,pl,lua,cs,toml", Type: conf.TypeText, Group: model.INDEX, Flag: model.PRIVATE, Help: `extensions to extract text from, in addition to text types`},
+		{Key: conf.ContentIndexMaxSize, Value: "10", Type: conf.TypeNumber, Group: model.INDEX, Flag: model.PRIVATE, Help: `max size of files to extract text from, in MB`},
+		{Key: conf.MediaIndex, Value: "false", Type: conf.TypeBool, Group: model.INDEX, Flag: model.PRIVATE, Help: `extract metadata of images, audio and video when indexing, so that search can filter and sort on it`},
 		{Key: conf.IndexProgress, Value: "{}", Type: conf.TypeText, Group: model.SINGLE, Flag: model.PRIVATE},
 
 		// SSO settings
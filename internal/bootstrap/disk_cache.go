@@ -0,0 +1,26 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/diskcache"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+func InitDiskCache() {
+	c := conf.Conf.DiskCache
+	if c.MaxSize <= 0 || c.Dir == "" {
+		log.Infof("disk cache is disabled")
+		return
+	}
+	blockSize := c.BlockSize
+	if blockSize <= 0 {
+		blockSize = 4096
+	}
+	cache, err := diskcache.New(c.Dir, int64(c.MaxSize)*utils.MB, int64(blockSize)*utils.KB)
+	if err != nil {
+		log.Errorf("failed to init disk cache: %+v", err)
+		return
+	}
+	diskcache.SetDefault(cache)
+}
@@ -0,0 +1,16 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/hls"
+	log "github.com/sirupsen/logrus"
+)
+
+func InitHLS() {
+	if !conf.Conf.HLS.Enable {
+		return
+	}
+	if err := hls.Init(conf.Conf.HLS); err != nil {
+		log.Errorf("failed to init hls: %+v", err)
+	}
+}
@@ -15,4 +15,5 @@ func InitIndex() {
 		progress.IsDone = true
 		search.WriteProgress(progress)
 	}
+	search.InitIndexQueue()
 }
@@ -0,0 +1,13 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/health"
+)
+
+func InitStorageHealth() {
+	if !conf.Conf.StorageHealth.Enable {
+		return
+	}
+	health.Init(conf.Conf.StorageHealth)
+}
@@ -2,8 +2,10 @@ package bootstrap
 
 import (
 	"context"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -12,9 +14,13 @@ import (
 
 type blockBurstLimiter struct {
 	*rate.Limiter
+	name string
 }
 
 func (l blockBurstLimiter) WaitN(ctx context.Context, total int) error {
+	if l.Limiter.Limit() != rate.Inf {
+		defer metrics.LimiterWait(l.name, time.Now())
+	}
 	for total > 0 {
 		n := l.Burst()
 		if l.Limiter.Limit() == rate.Inf || n > total {
@@ -36,9 +42,9 @@ func streamFilterNegative(limit int) (rate.Limit, int) {
 	return rate.Limit(limit) * 1024.0, limit * 1024
 }
 
-func initLimiter(limiter *stream.Limiter, s string) {
+func initLimiter(limiter *stream.Limiter, s, name string) {
 	clientDownLimit, burst := streamFilterNegative(setting.GetInt(s, -1))
-	*limiter = blockBurstLimiter{Limiter: rate.NewLimiter(clientDownLimit, burst)}
+	*limiter = blockBurstLimiter{Limiter: rate.NewLimiter(clientDownLimit, burst), name: name}
 	op.RegisterSettingChangingCallback(func() {
 		newLimit, newBurst := streamFilterNegative(setting.GetInt(s, -1))
 		(*limiter).SetLimit(newLimit)
@@ -47,8 +53,8 @@ func initLimiter(limiter *stream.Limiter, s string) {
 }
 
 func InitStreamLimit() {
-	initLimiter(&stream.ClientDownloadLimit, conf.StreamMaxClientDownloadSpeed)
-	initLimiter(&stream.ClientUploadLimit, conf.StreamMaxClientUploadSpeed)
-	initLimiter(&stream.ServerDownloadLimit, conf.StreamMaxServerDownloadSpeed)
-	initLimiter(&stream.ServerUploadLimit, conf.StreamMaxServerUploadSpeed)
+	initLimiter(&stream.ClientDownloadLimit, conf.StreamMaxClientDownloadSpeed, "client_download")
+	initLimiter(&stream.ClientUploadLimit, conf.StreamMaxClientUploadSpeed, "client_upload")
+	initLimiter(&stream.ServerDownloadLimit, conf.StreamMaxServerDownloadSpeed, "server_download")
+	initLimiter(&stream.ServerUploadLimit, conf.StreamMaxServerUploadSpeed, "server_upload")
 }
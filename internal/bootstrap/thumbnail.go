@@ -0,0 +1,17 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/thumbnail"
+	log "github.com/sirupsen/logrus"
+)
+
+func InitThumbnail() {
+	c := conf.Conf.Thumbnail
+	if !c.Enable || c.MaxSize <= 0 || c.Dir == "" {
+		return
+	}
+	if err := thumbnail.Init(c); err != nil {
+		log.Errorf("failed to init thumbnail cache: %+v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+func InitTracing() {
+	if err := tracing.Init(context.Background(), conf.Conf.Tracing); err != nil {
+		log.Errorf("failed to init tracing: %+v", err)
+	}
+}
@@ -106,37 +106,104 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+type Metrics struct {
+	Enable bool   `json:"enable" env:"ENABLE"`
+	Listen string `json:"listen" env:"LISTEN"` // serve on a separate listener instead of the main one
+	Token  string `json:"token" env:"TOKEN"`   // required when served on the main listener
+}
+
+type Tracing struct {
+	Enable      bool    `json:"enable" env:"ENABLE"`
+	Exporter    string  `json:"exporter" env:"EXPORTER"` // otlp, stdout or file
+	Endpoint    string  `json:"endpoint" env:"ENDPOINT"` // url of the OTLP/HTTP traces endpoint
+	File        string  `json:"file" env:"FILE"`
+	SampleRatio float64 `json:"sample_ratio" env:"SAMPLE_RATIO"`
+	ServiceName string  `json:"service_name" env:"SERVICE_NAME"`
+	// continue the traces of the incoming requests, only for the clients behind a trusted gateway
+	TrustIncoming bool `json:"trust_incoming" env:"TRUST_INCOMING"`
+}
+
+type DiskCache struct {
+	Dir       string `json:"dir" env:"DIR"`
+	MaxSize   int    `json:"max_sizeMB" env:"MAX_SIZE_MB"` // 0 disables the cache
+	BlockSize int    `json:"block_sizeKB" env:"BLOCK_SIZE_KB"`
+}
+
+type Thumbnail struct {
+	Enable        bool   `json:"enable" env:"ENABLE"`
+	Dir           string `json:"dir" env:"DIR"`
+	MaxSize       int    `json:"max_sizeMB" env:"MAX_SIZE_MB"`
+	MaxSourceSize int    `json:"max_source_sizeMB" env:"MAX_SOURCE_SIZE_MB"` // larger images are skipped, 0 for no limit
+	Concurrency   int    `json:"concurrency" env:"CONCURRENCY"`
+}
+
+type HLSProfile struct {
+	Name         string `json:"name"`
+	Height       int    `json:"height"`
+	VideoBitrate int    `json:"video_bitrate"` // kbps
+	AudioBitrate int    `json:"audio_bitrate"` // kbps
+}
+
+type HLS struct {
+	Enable      bool         `json:"enable" env:"ENABLE"`
+	SegmentTime int          `json:"segment_time" env:"SEGMENT_TIME"` // seconds
+	Concurrency int          `json:"concurrency" env:"CONCURRENCY"`   // ffmpeg processes running at the same time
+	CacheExpire int          `json:"cache_expire" env:"CACHE_EXPIRE"` // minutes before unused segments are removed
+	Remux       bool         `json:"remux" env:"REMUX"`               // offer the original h264 stream without transcoding
+	Profiles    []HLSProfile `json:"profiles"`
+}
+
+type StorageHealth struct {
+	Enable           bool   `json:"enable" env:"ENABLE"`
+	Interval         int    `json:"interval" env:"INTERVAL"`                   // seconds between the probes of a storage
+	MaxBackoff       int    `json:"max_backoff" env:"MAX_BACKOFF"`             // seconds, the interval doubles on failures up to it
+	Timeout          int    `json:"timeout" env:"TIMEOUT"`                     // seconds a probe may take
+	FailureThreshold int    `json:"failure_threshold" env:"FAILURE_THRESHOLD"` // failures in a row before the storage is marked down
+	Concurrency      int    `json:"concurrency" env:"CONCURRENCY"`
+	HistoryDays      int    `json:"history_days" env:"HISTORY_DAYS"` // 0 keeps the status history forever
+	NotifyURL        string `json:"notify_url" env:"NOTIFY_URL"`     // receives a POST when a storage goes down or comes back
+}
+
 type Config struct {
-	Force                 bool        `json:"force" env:"FORCE"`
-	SiteURL               string      `json:"site_url" env:"SITE_URL"`
-	Cdn                   string      `json:"cdn" env:"CDN"`
-	JwtSecret             string      `json:"jwt_secret" env:"JWT_SECRET"`
-	TokenExpiresIn        int         `json:"token_expires_in" env:"TOKEN_EXPIRES_IN"`
-	Database              Database    `json:"database" envPrefix:"DB_"`
-	Meilisearch           Meilisearch `json:"meilisearch" envPrefix:"MEILISEARCH_"`
-	Scheme                Scheme      `json:"scheme"`
-	TempDir               string      `json:"temp_dir" env:"TEMP_DIR"`
-	BleveDir              string      `json:"bleve_dir" env:"BLEVE_DIR"`
-	DistDir               string      `json:"dist_dir"`
-	Log                   LogConfig   `json:"log" envPrefix:"LOG_"`
-	DelayedStart          int         `json:"delayed_start" env:"DELAYED_START"`
-	MaxBufferLimit        int         `json:"max_buffer_limitMB" env:"MAX_BUFFER_LIMIT_MB"`
-	MmapThreshold         int         `json:"mmap_thresholdMB" env:"MMAP_THRESHOLD_MB"`
-	MaxConnections        int         `json:"max_connections" env:"MAX_CONNECTIONS"`
-	MaxConcurrency        int         `json:"max_concurrency" env:"MAX_CONCURRENCY"`
-	TlsInsecureSkipVerify bool        `json:"tls_insecure_skip_verify" env:"TLS_INSECURE_SKIP_VERIFY"`
-	Tasks                 TasksConfig `json:"tasks" envPrefix:"TASKS_"`
-	Cors                  Cors        `json:"cors" envPrefix:"CORS_"`
-	S3                    S3          `json:"s3" envPrefix:"S3_"`
-	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
-	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
-	LastLaunchedVersion   string      `json:"last_launched_version"`
-	ProxyAddress          string      `json:"proxy_address" env:"PROXY_ADDRESS"`
+	Force                 bool          `json:"force" env:"FORCE"`
+	SiteURL               string        `json:"site_url" env:"SITE_URL"`
+	Cdn                   string        `json:"cdn" env:"CDN"`
+	JwtSecret             string        `json:"jwt_secret" env:"JWT_SECRET"`
+	TokenExpiresIn        int           `json:"token_expires_in" env:"TOKEN_EXPIRES_IN"`
+	RefreshExpiresIn      int           `json:"refresh_expires_in" env:"REFRESH_EXPIRES_IN"`
+	Database              Database      `json:"database" envPrefix:"DB_"`
+	Meilisearch           Meilisearch   `json:"meilisearch" envPrefix:"MEILISEARCH_"`
+	Scheme                Scheme        `json:"scheme"`
+	TempDir               string        `json:"temp_dir" env:"TEMP_DIR"`
+	BleveDir              string        `json:"bleve_dir" env:"BLEVE_DIR"`
+	DiskCache             DiskCache     `json:"disk_cache" envPrefix:"DISK_CACHE_"`
+	Thumbnail             Thumbnail     `json:"thumbnail" envPrefix:"THUMBNAIL_"`
+	HLS                   HLS           `json:"hls" envPrefix:"HLS_"`
+	StorageHealth         StorageHealth `json:"storage_health" envPrefix:"STORAGE_HEALTH_"`
+	DistDir               string        `json:"dist_dir"`
+	Log                   LogConfig     `json:"log" envPrefix:"LOG_"`
+	DelayedStart          int           `json:"delayed_start" env:"DELAYED_START"`
+	MaxBufferLimit        int           `json:"max_buffer_limitMB" env:"MAX_BUFFER_LIMIT_MB"`
+	MmapThreshold         int           `json:"mmap_thresholdMB" env:"MMAP_THRESHOLD_MB"`
+	MaxConnections        int           `json:"max_connections" env:"MAX_CONNECTIONS"`
+	MaxConcurrency        int           `json:"max_concurrency" env:"MAX_CONCURRENCY"`
+	TlsInsecureSkipVerify bool          `json:"tls_insecure_skip_verify" env:"TLS_INSECURE_SKIP_VERIFY"`
+	Tasks                 TasksConfig   `json:"tasks" envPrefix:"TASKS_"`
+	Cors                  Cors          `json:"cors" envPrefix:"CORS_"`
+	S3                    S3            `json:"s3" envPrefix:"S3_"`
+	FTP                   FTP           `json:"ftp" envPrefix:"FTP_"`
+	SFTP                  SFTP          `json:"sftp" envPrefix:"SFTP_"`
+	Metrics               Metrics       `json:"metrics" envPrefix:"METRICS_"`
+	Tracing               Tracing       `json:"tracing" envPrefix:"TRACING_"`
+	LastLaunchedVersion   string        `json:"last_launched_version"`
+	ProxyAddress          string        `json:"proxy_address" env:"PROXY_ADDRESS"`
 }
 
 func DefaultConfig(dataDir string) *Config {
 	tempDir := filepath.Join(dataDir, "temp")
 	indexDir := filepath.Join(dataDir, "bleve")
+	diskCacheDir := filepath.Join(dataDir, "disk_cache")
+	thumbnailDir := filepath.Join(dataDir, "thumbnails")
 	logPath := filepath.Join(dataDir, "log/log.log")
 	dbPath := filepath.Join(dataDir, "data.db")
 	return &Config{
@@ -149,9 +216,10 @@ func DefaultConfig(dataDir string) *Config {
 			CertFile:   "",
 			KeyFile:    "",
 		},
-		JwtSecret:      random.String(16),
-		TokenExpiresIn: 48,
-		TempDir:        tempDir,
+		JwtSecret:        random.String(16),
+		TokenExpiresIn:   48,
+		RefreshExpiresIn: 720,
+		TempDir:          tempDir,
 		Database: Database{
 			Type:        "sqlite3",
 			Port:        0,
@@ -163,6 +231,40 @@ func DefaultConfig(dataDir string) *Config {
 			Index: "openlist",
 		},
 		BleveDir: indexDir,
+		DiskCache: DiskCache{
+			Dir:       diskCacheDir,
+			MaxSize:   10240,
+			BlockSize: 4096,
+		},
+		Thumbnail: Thumbnail{
+			Enable:        false,
+			Dir:           thumbnailDir,
+			MaxSize:       1024,
+			MaxSourceSize: 50,
+			Concurrency:   2,
+		},
+		HLS: HLS{
+			Enable:      false,
+			SegmentTime: 6,
+			Concurrency: 2,
+			CacheExpire: 30,
+			Remux:       true,
+			Profiles: []HLSProfile{
+				{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+				{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+				{Name: "480p", Height: 480, VideoBitrate: 1200, AudioBitrate: 128},
+				{Name: "360p", Height: 360, VideoBitrate: 700, AudioBitrate: 96},
+			},
+		},
+		StorageHealth: StorageHealth{
+			Enable:           false,
+			Interval:         300,
+			MaxBackoff:       3600,
+			Timeout:          30,
+			FailureThreshold: 3,
+			Concurrency:      4,
+			HistoryDays:      30,
+		},
 		Log: LogConfig{
 			Enable:     true,
 			Name:       logPath,
@@ -244,6 +346,20 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		Metrics: Metrics{
+			Enable: false,
+			Listen: "",
+			Token:  "",
+		},
+		Tracing: Tracing{
+			Enable:        false,
+			Exporter:      "otlp",
+			Endpoint:      "http://localhost:4318/v1/traces",
+			File:          filepath.Join(dataDir, "log/traces.json"),
+			SampleRatio:   1,
+			ServiceName:   "openlist",
+			TrustIncoming: false,
+		},
 		LastLaunchedVersion: "",
 		ProxyAddress:        "",
 	}
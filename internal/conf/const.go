@@ -66,6 +66,12 @@ const (
 	IgnorePaths     = "ignore_paths"
 	MaxIndexDepth   = "max_index_depth"
 
+	ContentIndex        = "content_index"
+	ContentIndexTypes   = "content_index_types"
+	ContentIndexMaxSize = "content_index_max_size"
+
+	MediaIndex = "media_index"
+
 	// aria2
 	Aria2Uri    = "aria2_uri"
 	Aria2Secret = "aria2_secret"
@@ -186,4 +192,7 @@ const (
 	UserAgentKey
 	PathKey
 	SharingIDKey
+	DirectLinkKey
+	SessionKey
+	ProtocolKey
 )
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetDirectLinkById(id string) (*model.DirectLink, error) {
+	l := model.DirectLink{ID: id}
+	if err := db.Where(l).First(&l).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get direct link")
+	}
+	return &l, nil
+}
+
+func GetDirectLinks(pageIndex, pageSize int) (links []model.DirectLink, count int64, err error) {
+	linkDB := db.Model(&model.DirectLink{})
+	if err := linkDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get direct links count")
+	}
+	if err := linkDB.Order(columnName("created_at")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&links).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find direct links")
+	}
+	return links, count, nil
+}
+
+func GetDirectLinksByCreatorId(creator uint, pageIndex, pageSize int) (links []model.DirectLink, count int64, err error) {
+	linkDB := db.Model(&model.DirectLink{})
+	cond := model.DirectLink{CreatorId: creator}
+	if err := linkDB.Where(cond).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get direct links count")
+	}
+	if err := linkDB.Where(cond).Order(columnName("created_at")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&links).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find direct links")
+	}
+	return links, count, nil
+}
+
+func CreateDirectLink(l *model.DirectLink) error {
+	for i := 0; i < 3; i++ {
+		l.ID = random.String(16)
+		old := model.DirectLink{ID: l.ID}
+		if err := db.Where(old).First(&old).Error; err != nil {
+			return errors.WithStack(db.Create(l).Error)
+		}
+	}
+	return errors.New("failed find valid id")
+}
+
+// UpdateDirectLink saves the given columns of the link, the editable ones by default,
+// so the downloads counter is never overwritten
+func UpdateDirectLink(l *model.DirectLink, columns ...string) error {
+	if len(columns) == 0 {
+		columns = model.DirectLinkEditableColumns
+	}
+	return errors.WithStack(db.Model(l).Select(columns).Updates(l).Error)
+}
+
+// CountDirectLinkDownload increase the downloads counter in place if the limit is not reached,
+// so concurrent downloads are neither lost nor allowed beyond the limit
+func CountDirectLinkDownload(id, ip string) (bool, error) {
+	res := db.Model(&model.DirectLink{}).
+		Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Where(fmt.Sprintf("%s <= 0 OR %s < %s", columnName("max_downloads"), columnName("downloads"), columnName("max_downloads"))).
+		Updates(map[string]any{
+			"downloads":     gorm.Expr(fmt.Sprintf("%s + ?", columnName("downloads")), 1),
+			"last_accessed": time.Now(),
+			"last_ip":       ip,
+		})
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func DeleteDirectLinkById(id string) error {
+	l := model.DirectLink{ID: id}
+	return errors.WithStack(db.Where(l).Delete(&l).Error)
+}
+
+func DeleteDirectLinksByCreatorId(creatorId uint) error {
+	return errors.WithStack(db.Where("creator_id = ?", creatorId).Delete(&model.DirectLink{}).Error)
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetIndexEvents() ([]model.IndexEvent, error) {
+	var events []model.IndexEvent
+	if err := db.Order(columnName("id")).Find(&events).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get index events")
+	}
+	return events, nil
+}
+
+// SaveIndexEvent replace the pending event of the same path
+func SaveIndexEvent(e *model.IndexEvent) error {
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("path")), e.Path).
+		Not(fmt.Sprintf("%s = ?", columnName("id")), e.ID).
+		Delete(&model.IndexEvent{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Save(e).Error)
+}
+
+func DeleteIndexEventById(id uint) error {
+	return errors.WithStack(db.Delete(&model.IndexEvent{}, id).Error)
+}
+
+func ClearIndexEvents() error {
+	return errors.WithStack(db.Where("1 = 1").Delete(&model.IndexEvent{}).Error)
+}
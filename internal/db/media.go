@@ -0,0 +1,110 @@
+package db
+
+import (
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the fields search results can be ordered by, besides name and size
+var mediaOrders = []string{"name", "size", "taken_at", "duration", "artist", "album", "year", "track", "width", "height"}
+
+func GetMediaInfo(path string) (*model.MediaInfo, error) {
+	dir, name := stdpath.Split(utils.FixAndCleanPath(path))
+	var info model.MediaInfo
+	if err := db.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("parent"), columnName("name")),
+		utils.FixAndCleanPath(dir), name).First(&info).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get media info")
+	}
+	return &info, nil
+}
+
+// SaveMediaInfo replace the info of the same file
+func SaveMediaInfo(info *model.MediaInfo) error {
+	if err := db.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("parent"), columnName("name")),
+		info.Parent, info.Name).Delete(&model.MediaInfo{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Create(info).Error)
+}
+
+// DeleteMediaInfos deletes the info of the file or everything under the dir
+func DeleteMediaInfos(path string) error {
+	path = utils.FixAndCleanPath(path)
+	if err := whereParentUnder(path).Delete(&model.MediaInfo{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	dir, name := stdpath.Split(path)
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("parent"), columnName("name")),
+		utils.FixAndCleanPath(dir), name).Delete(&model.MediaInfo{}).Error)
+}
+
+func ClearMediaInfos() error {
+	return errors.WithStack(db.Where("1 = 1").Delete(&model.MediaInfo{}).Error)
+}
+
+// SearchMediaInfos searches the files by their media info, the keywords match the names
+func SearchMediaInfos(req model.SearchReq) ([]model.SearchNode, int64, error) {
+	f := req.Media
+	like := func(column string) string {
+		return fmt.Sprintf("%s LIKE ? ESCAPE '!'", columnName(column))
+	}
+	searchDB := db.Model(&model.MediaInfo{}).Where(whereParentUnder(req.Parent))
+	for _, keyword := range strings.Fields(req.Keywords) {
+		searchDB = searchDB.Where(like("name"), "%"+escapeLike(keyword)+"%")
+	}
+	if f.Kind != "" {
+		searchDB = searchDB.Where(fmt.Sprintf("%s = ?", columnName("kind")), f.Kind)
+	}
+	if f.Camera != "" {
+		camera := "%" + escapeLike(f.Camera) + "%"
+		searchDB = searchDB.Where(db.Where(like("make"), camera).Or(like("model"), camera))
+	}
+	for column, value := range map[string]string{"artist": f.Artist, "album": f.Album, "genre": f.Genre} {
+		if value != "" {
+			searchDB = searchDB.Where(like(column), "%"+escapeLike(value)+"%")
+		}
+	}
+	if f.TakenAfter != nil {
+		searchDB = searchDB.Where(fmt.Sprintf("%s >= ?", columnName("taken_at")), *f.TakenAfter)
+	}
+	if f.TakenBefore != nil {
+		searchDB = searchDB.Where(fmt.Sprintf("%s < ?", columnName("taken_at")), *f.TakenBefore)
+	}
+	if f.DurationMin > 0 {
+		searchDB = searchDB.Where(fmt.Sprintf("%s >= ?", columnName("duration")), f.DurationMin)
+	}
+	if f.DurationMax > 0 {
+		searchDB = searchDB.Where(fmt.Sprintf("%s <= ?", columnName("duration")), f.DurationMax)
+	}
+	if f.HasLocation {
+		searchDB = searchDB.Where(fmt.Sprintf("%s IS NOT NULL", columnName("latitude")))
+	}
+
+	var count int64
+	if err := searchDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get media search items count")
+	}
+	order := "name"
+	if utils.SliceContains(mediaOrders, req.OrderBy) {
+		order = req.OrderBy
+	}
+	direction := "asc"
+	if strings.ToLower(req.OrderDirection) == "desc" {
+		direction = "desc"
+	}
+	var infos []model.MediaInfo
+	if err := searchDB.Order(fmt.Sprintf("%s %s", columnName(order), direction)).
+		Offset((req.Page - 1) * req.PerPage).Limit(req.PerPage).Find(&infos).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	nodes := make([]model.SearchNode, len(infos))
+	for i, info := range infos {
+		nodes[i] = model.SearchNode{Parent: info.Parent, Name: info.Name, Size: info.Size}
+	}
+	return nodes, count, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestMediaInfosEscape(t *testing.T) {
+	t.Cleanup(func() { _ = ClearMediaInfos() })
+	for _, info := range []model.MediaInfo{
+		{Parent: "/a_b", Name: "1.mp3", Artist: "100% Band"},
+		{Parent: "/a_b/c", Name: "2.mp3", Artist: "1000 Band"},
+		{Parent: "/axb", Name: "3_x.mp3", Artist: "100% Band"},
+		{Parent: "/ab/c", Name: "4.mp3"},
+	} {
+		if err := SaveMediaInfo(&info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func(req model.SearchReq) string {
+		req.PageReq = model.PageReq{Page: 1, PerPage: 10}
+		if req.Media == nil {
+			req.Media = &model.MediaFilter{}
+		}
+		nodes, _, err := SearchMediaInfos(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := search(model.SearchReq{Parent: "/a_b"}); got != "1.mp3,2.mp3" {
+		t.Errorf("under /a_b: %s", got)
+	}
+	if got := search(model.SearchReq{Parent: "/", Keywords: "_"}); got != "3_x.mp3" {
+		t.Errorf("keyword _: %s", got)
+	}
+	if got := search(model.SearchReq{Parent: "/", Media: &model.MediaFilter{Artist: "100%"}}); got != "1.mp3,3_x.mp3" {
+		t.Errorf("artist 100%%: %s", got)
+	}
+	if err := DeleteMediaInfos("/a_b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(model.SearchReq{Parent: "/"}); got != "3_x.mp3,4.mp3" {
+		t.Errorf("left %s", got)
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func CreateS3Upload(u *model.S3Upload) error {
+	return errors.WithStack(db.Create(u).Error)
+}
+
+func GetS3UploadById(id string) (*model.S3Upload, error) {
+	var u model.S3Upload
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&u).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 upload")
+	}
+	return &u, nil
+}
+
+func GetS3UploadsByBucket(bucket string) ([]model.S3Upload, error) {
+	var uploads []model.S3Upload
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("bucket")), bucket).
+		Order(columnName("key")).Order(columnName("id")).Find(&uploads).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 uploads")
+	}
+	return uploads, nil
+}
+
+func GetS3UploadsCreatedBefore(t time.Time) ([]model.S3Upload, error) {
+	var uploads []model.S3Upload
+	if err := db.Where(fmt.Sprintf("%s < ?", columnName("created_at")), t).Find(&uploads).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 uploads")
+	}
+	return uploads, nil
+}
+
+// DeleteS3Upload deletes the upload with its parts
+func DeleteS3Upload(id string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("upload_id")), id).Delete(&model.S3UploadPart{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.S3Upload{}).Error
+	}))
+}
+
+// SaveS3UploadPart creates the part or replaces the one with the same number
+func SaveS3UploadPart(p *model.S3UploadPart) error {
+	return errors.WithStack(db.Save(p).Error)
+}
+
+func GetS3UploadParts(uploadId string) ([]model.S3UploadPart, error) {
+	var parts []model.S3UploadPart
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("upload_id")), uploadId).
+		Order(columnName("part_number")).Find(&parts).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 upload parts")
+	}
+	return parts, nil
+}
+
+func GetS3ObjectMeta(path string) (*model.S3ObjectMeta, error) {
+	var m model.S3ObjectMeta
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).First(&m).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 object meta")
+	}
+	return &m, nil
+}
+
+func SaveS3ObjectMeta(m *model.S3ObjectMeta) error {
+	if err := checkPathKey(m.Path); err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(m).Error)
+}
+
+// DeleteS3ObjectMeta deletes the meta of the path and everything under it
+func DeleteS3ObjectMeta(path string) error {
+	return errors.WithStack(wherePathUnder(path).Delete(&model.S3ObjectMeta{}).Error)
+}
+
+// MoveS3ObjectMeta moves the meta of the path and everything under it to dst
+func MoveS3ObjectMeta(src, dst string) error {
+	var metas []model.S3ObjectMeta
+	src = strings.TrimSuffix(src, "/")
+	if err := wherePathUnder(src).Find(&metas).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if len(metas) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range metas {
+			if err := tx.Delete(&model.S3ObjectMeta{}, fmt.Sprintf("%s = ?", columnName("path")), m.Path).Error; err != nil {
+				return err
+			}
+			m.Path = strings.TrimSuffix(dst, "/") + strings.TrimPrefix(m.Path, src)
+			if err := checkPathKey(m.Path); err != nil {
+				// the object is kept, only its metadata is lost
+				continue
+			}
+			if err := tx.Save(&m).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
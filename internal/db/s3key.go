@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetS3AccessKeysByUserId(userId uint, pageIndex, pageSize int) (keys []model.S3AccessKey, count int64, err error) {
+	keyDB := db.Model(&model.S3AccessKey{})
+	query := model.S3AccessKey{UserId: userId}
+	if err := keyDB.Where(query).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get user's s3 keys count")
+	}
+	if err := keyDB.Where(query).Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&keys).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get find user's s3 keys")
+	}
+	return keys, count, nil
+}
+
+func GetS3AccessKeyById(id uint) (*model.S3AccessKey, error) {
+	var k model.S3AccessKey
+	if err := db.First(&k, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 key")
+	}
+	return &k, nil
+}
+
+func GetS3AccessKeyByAccessKeyId(accessKeyId string) (*model.S3AccessKey, error) {
+	var k model.S3AccessKey
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("access_key_id")), accessKeyId).First(&k).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get s3 key")
+	}
+	return &k, nil
+}
+
+func CreateS3AccessKey(k *model.S3AccessKey) error {
+	return errors.WithStack(db.Create(k).Error)
+}
+
+func UpdateS3AccessKeyLastUsedTime(id uint, t time.Time) error {
+	return errors.WithStack(db.Model(&model.S3AccessKey{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Update("last_used_time", t).Error)
+}
+
+func DeleteS3AccessKeyById(id uint) error {
+	return errors.WithStack(db.Delete(&model.S3AccessKey{}, id).Error)
+}
+
+func DeleteS3AccessKeysByUserId(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.S3AccessKey{}).Error)
+}
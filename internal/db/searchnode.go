@@ -36,9 +36,10 @@ func DeleteSearchNodesByParent(path string) error {
 		return err
 	}
 	dir, name := stdpath.Split(path)
+	// the parents are kept without the trailing slash
 	return db.Where(fmt.Sprintf("%s = ? AND %s = ?",
 		columnName("parent"), columnName("name")),
-		dir, name).Delete(&model.SearchNode{}).Error
+		utils.FixAndCleanPath(dir), name).Delete(&model.SearchNode{}).Error
 }
 
 func ClearSearchNodes() error {
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateSession(s *model.Session) error {
+	return errors.WithStack(db.Create(s).Error)
+}
+
+func GetSessionById(id string) (*model.Session, error) {
+	var s model.Session
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&s).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get session")
+	}
+	return &s, nil
+}
+
+func GetSessionsByUserId(userId uint, pageIndex, pageSize int) (sessions []model.Session, count int64, err error) {
+	sessionDB := db.Model(&model.Session{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userId)
+	if err := sessionDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get user's sessions count")
+	}
+	if err := sessionDB.Order(fmt.Sprintf("%s DESC", columnName("last_seen"))).
+		Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&sessions).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find user's sessions")
+	}
+	return sessions, count, nil
+}
+
+func GetSessionIdsByUserId(userId uint) ([]string, error) {
+	var ids []string
+	if err := db.Model(&model.Session{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userId).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get user's sessions")
+	}
+	return ids, nil
+}
+
+func UpdateSession(s *model.Session) error {
+	return errors.WithStack(db.Save(s).Error)
+}
+
+// RotateSession saves the rotated session if its refresh token is still the old one,
+// it reports false when a concurrent refresh has rotated it first
+func RotateSession(s *model.Session, oldRefreshToken string) (bool, error) {
+	res := db.Model(s).Where(fmt.Sprintf("%s = ?", columnName("refresh_token")), oldRefreshToken).
+		Select("refresh_token", "prev_refresh_token", "rotated_at", "last_seen", "expires_at", "ip").Updates(s)
+	if res.Error != nil {
+		return false, errors.Wrapf(res.Error, "failed rotate session")
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func UpdateSessionLastSeen(id, ip string, t time.Time) error {
+	return errors.WithStack(db.Model(&model.Session{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Updates(map[string]any{"last_seen": t, "ip": ip}).Error)
+}
+
+func DeleteSessionById(id string) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.Session{}).Error)
+}
+
+func DeleteSessionsByUserId(userId uint) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userId).Delete(&model.Session{}).Error)
+}
+
+func DeleteExpiredSessions(now time.Time) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("expires_at")), now).Delete(&model.Session{}).Error)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateStorageStatusHistory(h *model.StorageStatusHistory) error {
+	return errors.WithStack(db.Create(h).Error)
+}
+
+// GetLastStorageStatus returns the latest record of the storage, nil if there is none
+func GetLastStorageStatus(storageId uint) (*model.StorageStatusHistory, error) {
+	var hs []model.StorageStatusHistory
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("storage_id")), storageId).
+		Order(fmt.Sprintf("%s DESC", columnName("id"))).Limit(1).Find(&hs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get last storage status")
+	}
+	if len(hs) == 0 {
+		return nil, nil
+	}
+	return &hs[0], nil
+}
+
+func GetStorageStatusHistory(storageId uint, pageIndex, pageSize int) (hs []model.StorageStatusHistory, count int64, err error) {
+	historyDB := db.Model(&model.StorageStatusHistory{}).Where(fmt.Sprintf("%s = ?", columnName("storage_id")), storageId)
+	if err := historyDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get storage status history count")
+	}
+	if err := historyDB.Order(fmt.Sprintf("%s DESC", columnName("id"))).
+		Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&hs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find storage status history")
+	}
+	return hs, count, nil
+}
+
+func DeleteStorageStatusHistoryBefore(t time.Time) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("time")), t).Delete(&model.StorageStatusHistory{}).Error)
+}
@@ -2,8 +2,12 @@ package db
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
@@ -17,3 +21,63 @@ func columnName(name string) string {
 func addStorageOrder(db *gorm.DB) *gorm.DB {
 	return db.Order(fmt.Sprintf("%s, %s", columnName("order"), columnName("id")))
 }
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// escapeLike escapes the wildcards of a LIKE pattern, to be used with ESCAPE '!'
+// which unlike the backslash is taken literally by every database
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// pathUnder is the condition matching the rows of the path and everything under it by their path column
+func pathUnder(path string) (string, []any) {
+	return columnUnder("path", path)
+}
+
+// columnUnder is the condition matching the rows whose column is the path or under it
+func columnUnder(column, path string) (string, []any) {
+	path = strings.TrimSuffix(path, "/")
+	return fmt.Sprintf("(%[1]s = ? OR %[1]s LIKE ? ESCAPE '!')", columnName(column)), []any{path, escapeLike(path) + "/%"}
+}
+
+// wherePathUnder matches the rows of the path and everything under it by their path column
+func wherePathUnder(path string) *gorm.DB {
+	query, args := pathUnder(path)
+	return db.Where(query, args...)
+}
+
+// whereParentUnder matches the rows in the dir and its sub dirs by their parent column
+func whereParentUnder(dir string) *gorm.DB {
+	query, args := columnUnder("parent", dir)
+	return db.Where(query, args...)
+}
+
+// replacePathPrefix is the expression of the path column of the rows matched by pathUnder(src)
+// with src replaced by dst, only the prefix is replaced unlike REPLACE()
+func replacePathPrefix(src, dst string) (string, []any) {
+	src, dst = strings.TrimSuffix(src, "/"), strings.TrimSuffix(dst, "/")
+	rest := fmt.Sprintf("SUBSTR(%s, %d)", columnName("path"), utf8.RuneCountInString(src)+1)
+	if conf.Conf.Database.Type == "mysql" {
+		return fmt.Sprintf("CONCAT(?, %s)", rest), []any{dst}
+	}
+	return fmt.Sprintf("CAST(? AS TEXT) || %s", rest), []any{dst}
+}
+
+// pathKeyFits is the condition matching the rows whose path still fits in the path key
+// once it grows by the given number of characters, see checkPathKey
+func pathKeyFits(grow int) (string, []any) {
+	length := "LENGTH"
+	if conf.Conf.Database.Type == "mysql" {
+		length = "CHAR_LENGTH"
+	}
+	return fmt.Sprintf("%s(%s) <= ?", length, columnName("path")), []any{model.MaxPathKeyLength - grow}
+}
+
+// checkPathKey checks that the path fits in a path key column, see model.MaxPathKeyLength
+func checkPathKey(path string) error {
+	if utf8.RuneCountInString(path) > model.MaxPathKeyLength {
+		return errors.Errorf("path is longer than %d characters", model.MaxPathKeyLength)
+	}
+	return nil
+}
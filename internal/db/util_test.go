@@ -0,0 +1,47 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	Init(dB)
+}
+
+func TestPathUnderEscape(t *testing.T) {
+	for _, p := range []string{"/a_b", "/a_b/c", "/axb/c", "/a%/c", "/a!/c", "/ab/c"} {
+		if err := SaveS3ObjectMeta(&model.S3ObjectMeta{Path: p, MetaRaw: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"/a_b", "/a%", "/a!"} {
+		if err := DeleteS3ObjectMeta(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var left []model.S3ObjectMeta
+	if err := db.Order("path").Find(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range left {
+		paths = append(paths, m.Path)
+	}
+	if got := strings.Join(paths, ","); got != "/ab/c,/axb/c" {
+		t.Errorf("left %s", got)
+	}
+	if SaveS3ObjectMeta(&model.S3ObjectMeta{Path: "/" + strings.Repeat("a", model.MaxPathKeyLength)}) == nil {
+		t.Error("saved a path longer than the key")
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetWebdavProps(path string) (*model.WebdavProps, error) {
+	var p model.WebdavProps
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).First(&p).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get webdav props")
+	}
+	return &p, nil
+}
+
+func SaveWebdavProps(p *model.WebdavProps) error {
+	if err := checkPathKey(p.Path); err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(p).Error)
+}
+
+func DeleteWebdavProps(path string) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).Delete(&model.WebdavProps{}).Error)
+}
+
+// DeleteWebdavPropsUnder deletes the props of the path and everything under it
+func DeleteWebdavPropsUnder(path string) error {
+	return errors.WithStack(wherePathUnder(path).Delete(&model.WebdavProps{}).Error)
+}
+
+// MoveWebdavProps moves the props of the path and everything under it to dst
+func MoveWebdavProps(src, dst string) error {
+	return transferWebdavProps(src, dst, true)
+}
+
+// CopyWebdavProps copies the props of the path and everything under it to dst
+func CopyWebdavProps(src, dst string) error {
+	return transferWebdavProps(src, dst, false)
+}
+
+// transferWebdavProps replaces the props under dst with the ones under src in a few statements,
+// the props whose path no longer fits in the key are dropped
+func transferWebdavProps(src, dst string, move bool) error {
+	src, dst = strings.TrimSuffix(src, "/"), strings.TrimSuffix(dst, "/")
+	under, underArgs := pathUnder(src)
+	path, pathArgs := replacePathPrefix(src, dst)
+	fits, fitsArgs := pathKeyFits(utf8.RuneCountInString(dst) - utf8.RuneCountInString(src))
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		dstUnder, dstUnderArgs := pathUnder(dst)
+		if err := tx.Where(dstUnder, dstUnderArgs...).Delete(&model.WebdavProps{}).Error; err != nil {
+			return err
+		}
+		if !move {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(&model.WebdavProps{}); err != nil {
+				return err
+			}
+			table := columnName(stmt.Table)
+			sql := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) SELECT %s, %s, ? FROM %s WHERE %s AND %s",
+				table, columnName("path"), columnName("props_raw"), columnName("updated_at"),
+				path, columnName("props_raw"), table, under, fits)
+			args := append(append(append(pathArgs, time.Now()), underArgs...), fitsArgs...)
+			return tx.Exec(sql, args...).Error
+		}
+		if err := tx.Where(under, underArgs...).Not(fits, fitsArgs...).Delete(&model.WebdavProps{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.WebdavProps{}).Where(under, underArgs...).
+			Update("path", gorm.Expr(path, pathArgs...)).Error
+	}))
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func webdavPropsPaths(t *testing.T) string {
+	var props []model.WebdavProps
+	if err := db.Order("path").Find(&props).Error; err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range props {
+		paths = append(paths, p.Path+"="+p.PropsRaw)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestTransferWebdavProps(t *testing.T) {
+	long := "/" + strings.Repeat("l", model.MaxPathKeyLength-10)
+	for _, p := range []string{"/a", "/a/a", "/a/b/a", "/ab", "/c", "/c/x", long} {
+		if err := SaveWebdavProps(&model.WebdavProps{Path: p, PropsRaw: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Where("1 = 1").Delete(&model.WebdavProps{}) })
+	if err := CopyWebdavProps("/a", "/c"); err != nil {
+		t.Fatal(err)
+	}
+	want := "/a=/a,/a/a=/a/a,/a/b/a=/a/b/a,/ab=/ab,/c=/a,/c/a=/a/a,/c/b/a=/a/b/a," + long + "=" + long
+	if got := webdavPropsPaths(t); got != want {
+		t.Fatalf("after copy: %s", got)
+	}
+	// only the prefix is replaced
+	if err := MoveWebdavProps("/a/", "/d/a"); err != nil {
+		t.Fatal(err)
+	}
+	want = "/ab=/ab,/c=/a,/c/a=/a/a,/c/b/a=/a/b/a,/d/a=/a,/d/a/a=/a/a,/d/a/b/a=/a/b/a," + long + "=" + long
+	if got := webdavPropsPaths(t); got != want {
+		t.Fatalf("after move: %s", got)
+	}
+	// the props whose path would no longer fit in the key are dropped
+	if err := MoveWebdavProps(long, "/"+strings.Repeat("m", model.MaxPathKeyLength)); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveWebdavProps("/c", "/c"+strings.Repeat("n", model.MaxPathKeyLength-5)); err != nil {
+		t.Fatal(err)
+	}
+	n := "/c" + strings.Repeat("n", model.MaxPathKeyLength-5)
+	want = "/ab=/ab," + n + "=/a," + n + "/a=/a/a,/d/a=/a,/d/a/a=/a/a,/d/a/b/a=/a/b/a"
+	if got := webdavPropsPaths(t); got != want {
+		t.Fatalf("after moving to long paths: %s", got)
+	}
+	if SaveWebdavProps(&model.WebdavProps{Path: "/" + strings.Repeat("a", model.MaxPathKeyLength)}) == nil {
+		t.Error("saved a path longer than the key")
+	}
+}
@@ -0,0 +1,333 @@
+// Package diskcache caches the blocks read from slow storages on the local disk.
+//
+// The blocks of an object are stored as files in a directory named by the hash of its key,
+// next to a meta file recording the size, modified time and hash of the object they belong to.
+// Once any of them changes, the blocks are dropped. The least recently used blocks are evicted
+// when the total size exceeds the limit.
+package diskcache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const metaFile = "meta.json"
+
+type entryMeta struct {
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified"`
+	Hash     string `json:"hash"`
+}
+
+type block struct {
+	name string // <entry>/<index>
+	size int64
+}
+
+type Stats struct {
+	Dir       string  `json:"dir"`
+	Size      int64   `json:"size"`
+	MaxSize   int64   `json:"max_size"`
+	BlockSize int64   `json:"block_size"`
+	Blocks    int     `json:"blocks"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+	// bytes served from the cache and fetched from the storages
+	HitBytes  int64 `json:"hit_bytes"`
+	MissBytes int64 `json:"miss_bytes"`
+}
+
+type Cache struct {
+	dir       string
+	maxSize   int64
+	blockSize int64
+
+	mu      sync.Mutex
+	lru     *list.List // of *block, the front is the most recently used
+	blocks  map[string]*list.Element
+	entries map[string]entryMeta
+	size    int64
+
+	hits, misses, hitBytes, missBytes atomic.Int64
+}
+
+// New opens the cache in dir, the blocks left by the last run are indexed by their modified time
+func New(dir string, maxSize, blockSize int64) (*Cache, error) {
+	if maxSize <= 0 || blockSize <= 0 {
+		return nil, errors.New("size of the disk cache must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &Cache{
+		dir:       dir,
+		maxSize:   maxSize,
+		blockSize: blockSize,
+		lru:       list.New(),
+		blocks:    make(map[string]*list.Element),
+		entries:   make(map[string]entryMeta),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+func (c *Cache) load() error {
+	type found struct {
+		block
+		modified time.Time
+	}
+	var blocks []found
+	err := filepath.WalkDir(c.dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if strings.Contains(name, ".tmp") {
+			return os.Remove(p)
+		}
+		if _, err := strconv.ParseInt(name, 10, 64); err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(c.dir, p)
+		blocks = append(blocks, found{
+			block:    block{name: filepath.ToSlash(rel), size: info.Size()},
+			modified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].modified.After(blocks[j].modified)
+	})
+	for i := range blocks {
+		b := blocks[i].block
+		c.blocks[b.name] = c.lru.PushBack(&b)
+		c.size += b.size
+	}
+	log.Infof("disk cache: %d blocks, %dMB in %s", len(blocks), c.size/utils.MB, c.dir)
+	return nil
+}
+
+func entryName(key string) string {
+	sum := sha1.Sum([]byte(key))
+	h := hex.EncodeToString(sum[:])
+	return h[:2] + "/" + h
+}
+
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(name))
+}
+
+// validate drops the blocks of the entry if they belong to another version of the object
+func (c *Cache) validate(entry string, meta entryMeta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[entry]; ok && old == meta {
+		return
+	}
+	var old entryMeta
+	if err := readJSON(filepath.Join(c.path(entry), metaFile), &old); err == nil && old == meta {
+		c.entries[entry] = meta
+		return
+	}
+	c.dropLocked(entry)
+	if err := os.MkdirAll(c.path(entry), 0o777); err != nil {
+		log.Warnf("failed to create disk cache entry: %+v", err)
+		return
+	}
+	if err := writeJSON(filepath.Join(c.path(entry), metaFile), meta); err != nil {
+		log.Warnf("failed to write disk cache meta: %+v", err)
+		return
+	}
+	c.entries[entry] = meta
+}
+
+func (c *Cache) dropLocked(entry string) {
+	prefix := entry + "/"
+	for name, e := range c.blocks {
+		if strings.HasPrefix(name, prefix) {
+			c.removeLocked(name, e)
+		}
+	}
+	delete(c.entries, entry)
+	_ = os.RemoveAll(c.path(entry))
+}
+
+func (c *Cache) removeLocked(name string, e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.blocks, name)
+	c.size -= e.Value.(*block).size
+}
+
+// open returns the cached block if it is complete
+func (c *Cache) open(name string, size int64) (*os.File, bool) {
+	c.mu.Lock()
+	e, ok := c.blocks[name]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	f, err := os.Open(c.path(name))
+	if err == nil {
+		if info, err := f.Stat(); err == nil && info.Size() == size {
+			now := time.Now()
+			_ = os.Chtimes(f.Name(), now, now)
+			return f, true
+		}
+		_ = f.Close()
+	}
+	c.mu.Lock()
+	if e, ok := c.blocks[name]; ok {
+		c.removeLocked(name, e)
+	}
+	c.mu.Unlock()
+	_ = os.Remove(c.path(name))
+	return nil, false
+}
+
+// put stores a block, the file is renamed into place so that readers never see a partial block
+func (c *Cache) put(name string, data []byte) error {
+	p := c.path(name)
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".tmp*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithStack(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.blocks[name]; ok {
+		c.removeLocked(name, e)
+	}
+	c.blocks[name] = c.lru.PushFront(&block{name: name, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+func (c *Cache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		e := c.lru.Back()
+		name := e.Value.(*block).name
+		c.removeLocked(name, e)
+		if err := os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to evict disk cache block: %+v", err)
+		}
+	}
+}
+
+// RangeReader wraps the range reader of an object so that the blocks read through it are cached under key
+func (c *Cache) RangeReader(key string, size int64, modified time.Time, hash string, rr model.RangeReaderIF) model.RangeReaderIF {
+	entry := entryName(key)
+	c.validate(entry, entryMeta{Size: size, Modified: modified.UnixNano(), Hash: hash})
+	return &rangeReader{cache: c, entry: entry, size: size, rr: rr}
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	s := Stats{
+		Dir:       c.dir,
+		Size:      c.size,
+		MaxSize:   c.maxSize,
+		BlockSize: c.blockSize,
+		Blocks:    c.lru.Len(),
+	}
+	c.mu.Unlock()
+	s.Hits = c.hits.Load()
+	s.Misses = c.misses.Load()
+	s.HitBytes = c.hitBytes.Load()
+	s.MissBytes = c.missBytes.Load()
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+// Clear removes all the cached blocks and resets the counters
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.blocks = make(map[string]*list.Element)
+	c.entries = make(map[string]entryMeta)
+	c.size = 0
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.hitBytes.Store(0)
+	c.missBytes.Store(0)
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range files {
+		if err := os.RemoveAll(filepath.Join(c.dir, f.Name())); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func readJSON(p string, v any) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	return utils.Json.Unmarshal(data, v)
+}
+
+func writeJSON(p string, v any) error {
+	data, err := utils.Json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o666)
+}
+
+var instance atomic.Pointer[Cache]
+
+// Default is the cache configured at startup, nil if it is disabled
+func Default() *Cache {
+	return instance.Load()
+}
+
+func SetDefault(c *Cache) {
+	instance.Store(c)
+}
@@ -0,0 +1,97 @@
+package diskcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+type countingReader struct {
+	data     []byte
+	requests int
+}
+
+func (r *countingReader) RangeRead(_ context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	r.requests++
+	return io.NopCloser(bytes.NewReader(r.data[httpRange.Start : httpRange.Start+httpRange.Length])), nil
+}
+
+func readRange(t *testing.T, c *Cache, key string, modified time.Time, src *countingReader, start, length int64) []byte {
+	t.Helper()
+	rr := c.RangeReader(key, int64(len(src.data)), modified, "", src)
+	rc, err := rr.RangeRead(context.Background(), http_range.Range{Start: start, Length: length})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 64, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 45)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	src := &countingReader{data: data}
+	modified := time.Unix(1700000000, 0)
+
+	if got := readRange(t, c, "/a", modified, src, 5, 30); !bytes.Equal(got, data[5:35]) {
+		t.Fatalf("first read = %v", got)
+	}
+	if src.requests != 1 {
+		t.Errorf("missing blocks should be fetched with one request, got %d", src.requests)
+	}
+	if got := readRange(t, c, "/a", modified, src, 12, 20); !bytes.Equal(got, data[12:32]) {
+		t.Fatalf("cached read = %v", got)
+	}
+	if src.requests != 1 {
+		t.Errorf("cached blocks should not be fetched again, got %d requests", src.requests)
+	}
+	if got := readRange(t, c, "/a", modified, src, 30, -1); !bytes.Equal(got, data[30:]) {
+		t.Fatalf("read to the end = %v", got)
+	}
+	s := c.Stats()
+	if s.Hits != 4 || s.Misses != 5 || s.Size != 45 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// a modified object drops its blocks
+	readRange(t, c, "/a", modified.Add(time.Second), src, 0, 10)
+	if src.requests != 3 || c.Stats().Size != 10 {
+		t.Errorf("blocks of the old version are kept, requests %d, stats %+v", src.requests, c.Stats())
+	}
+
+	// the least recently used blocks are evicted
+	other := &countingReader{data: bytes.Repeat([]byte{1}, 60)}
+	readRange(t, c, "/b", modified, other, 0, -1)
+	if s := c.Stats(); s.Size > 64 {
+		t.Errorf("size %d exceeds the limit", s.Size)
+	}
+
+	// the blocks are indexed again after a restart
+	size := c.Stats().Size
+	c, err = New(dir, 64, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Stats().Size != size {
+		t.Errorf("reloaded size %d, want %d", c.Stats().Size, size)
+	}
+	other.requests = 0
+	if got := readRange(t, c, "/b", modified, other, 50, 10); !bytes.Equal(got, other.data[50:]) || other.requests != 0 {
+		t.Errorf("reloaded block read = %v with %d requests", got, other.requests)
+	}
+}
@@ -0,0 +1,135 @@
+package diskcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type rangeReader struct {
+	cache *Cache
+	entry string
+	size  int64
+	rr    model.RangeReaderIF
+}
+
+func (r *rangeReader) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	if httpRange.Start < 0 || httpRange.Start > r.size {
+		return nil, errors.Errorf("range start %d out of size %d", httpRange.Start, r.size)
+	}
+	if httpRange.Length < 0 || httpRange.Start+httpRange.Length > r.size {
+		httpRange.Length = r.size - httpRange.Start
+	}
+	return &blockReader{
+		rangeReader: r,
+		ctx:         ctx,
+		pos:         httpRange.Start,
+		end:         httpRange.Start + httpRange.Length,
+		index:       -1,
+	}, nil
+}
+
+// blockReader reads a range block by block, the missing blocks are fetched with one
+// request to the storage which is kept open as long as the following blocks are missing too
+type blockReader struct {
+	*rangeReader
+	ctx      context.Context
+	pos, end int64
+
+	index int64 // index of the current block
+	cur   io.ReaderAt
+	file  *os.File
+
+	upstream      io.ReadCloser
+	upstreamIndex int64 // index of the next block of upstream
+}
+
+func (b *blockReader) blockSize(index int64) int64 {
+	return min(b.cache.blockSize, b.size-index*b.cache.blockSize)
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if b.pos >= b.end {
+		return 0, io.EOF
+	}
+	bs := b.cache.blockSize
+	index := b.pos / bs
+	if index != b.index {
+		if err := b.load(index); err != nil {
+			return 0, err
+		}
+	}
+	off := b.pos - index*bs
+	n := min(int64(len(p)), b.end-b.pos, b.blockSize(index)-off)
+	read, err := b.cur.ReadAt(p[:n], off)
+	b.pos += int64(read)
+	if err == io.EOF && int64(read) == n {
+		err = nil
+	}
+	return read, err
+}
+
+func (b *blockReader) load(index int64) error {
+	b.closeFile()
+	size := b.blockSize(index)
+	name := b.entry + "/" + strconv.FormatInt(index, 10)
+	if f, ok := b.cache.open(name, size); ok {
+		b.cache.hits.Add(1)
+		b.cache.hitBytes.Add(size)
+		b.index, b.cur, b.file = index, f, f
+		return nil
+	}
+	b.cache.misses.Add(1)
+	if b.upstream == nil || b.upstreamIndex != index {
+		b.closeUpstream()
+		start := index * b.cache.blockSize
+		// stop at the end of the block holding the end of the range
+		end := min(((b.end-1)/b.cache.blockSize+1)*b.cache.blockSize, b.size)
+		rc, err := b.rr.RangeRead(b.ctx, http_range.Range{Start: start, Length: end - start})
+		if err != nil {
+			return err
+		}
+		b.upstream, b.upstreamIndex = rc, index
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(b.upstream, data); err != nil {
+		b.closeUpstream()
+		return errors.WithStack(err)
+	}
+	b.upstreamIndex++
+	b.cache.missBytes.Add(size)
+	if err := b.cache.put(name, data); err != nil {
+		log.Warnf("failed to cache block: %+v", err)
+	}
+	b.index, b.cur = index, bytes.NewReader(data)
+	return nil
+}
+
+func (b *blockReader) closeFile() {
+	if b.file != nil {
+		_ = b.file.Close()
+		b.file = nil
+	}
+	b.cur = nil
+	b.index = -1
+}
+
+func (b *blockReader) closeUpstream() {
+	if b.upstream != nil {
+		_ = b.upstream.Close()
+		b.upstream = nil
+	}
+}
+
+func (b *blockReader) Close() error {
+	b.closeFile()
+	b.closeUpstream()
+	return nil
+}
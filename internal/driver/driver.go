@@ -2,6 +2,7 @@ package driver
 
 import (
 	"context"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 )
@@ -76,6 +77,11 @@ type Remove interface {
 	Remove(ctx context.Context, obj model.Obj) error
 }
 
+// SetModTime is implemented by the drivers that can change the modification time of an object
+type SetModTime interface {
+	SetModTime(ctx context.Context, obj model.Obj, modTime time.Time) error
+}
+
 type Put interface {
 	// Put a file (provided as a FileStreamer) into the driver
 	// Besides the most basic upload functionality, the following features also need to be implemented:
@@ -0,0 +1,418 @@
+// Package drivertest is a conformance suite for storage drivers.
+//
+// A driver test starts a fake of the service the driver talks to, then hands
+// the driver constructor and its Addition to Run:
+//
+//	drivertest.Run(t, drivertest.Harness{
+//		New:      func() driver.Driver { return &Local{} },
+//		Addition: Addition{RootPath: driver.RootPath{RootFolderPath: t.TempDir()}},
+//	})
+//
+// Every check works in a directory of its own, the capabilities a driver
+// doesn't implement, or reports as not implemented or not supported, are skipped.
+package drivertest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	stdpath "path"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// Harness describes the driver under test
+type Harness struct {
+	// New returns a driver the way it is registered, before its Addition is set
+	New func() driver.Driver
+	// Addition is marshalled to JSON and unmarshalled into the Addition of the driver,
+	// the same way the addition of a storage is
+	Addition any
+	// LargeSize is the size of the upload that is expected to be split into parts,
+	// the large upload is skipped if it is 0
+	LargeSize int64
+	// Skip maps the names of the checks the driver can't pass to the reason
+	Skip map[string]string
+}
+
+// Run initializes the driver and runs every check against it
+func Run(t *testing.T, h Harness) {
+	t.Helper()
+	initEnv(t)
+	s := &suite{h: h, d: h.New()}
+	s.init(t)
+	s.work = s.mkdir(t, s.root, "drivertest-"+random(4))
+	t.Cleanup(func() {
+		if r, ok := s.d.(driver.Remove); ok {
+			_ = r.Remove(context.Background(), s.work.obj)
+		}
+	})
+
+	checks := []struct {
+		name string
+		fn   func(t *testing.T, s *suite)
+	}{
+		{"MakeDir", testMakeDir},
+		{"Put", testPut},
+		{"Progress", testProgress},
+		{"Get", testGet},
+		{"Link", testLink},
+		{"RangeRead", testRangeRead},
+		{"Rename", testRename},
+		{"Move", testMove},
+		{"Copy", testCopy},
+		{"Remove", testRemove},
+		{"SetModTime", testSetModTime},
+		{"Cancel", testCancel},
+		{"LargeUpload", testLargeUpload},
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			if reason, ok := h.Skip[c.name]; ok {
+				t.Skip(reason)
+			}
+			c.fn(t, s)
+		})
+	}
+}
+
+// initEnv sets up what drivers expect from the bootstrap
+func initEnv(t *testing.T) {
+	if conf.Conf == nil {
+		dir, err := os.MkdirTemp("", "drivertest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Conf = conf.DefaultConfig(dir)
+		if err = os.MkdirAll(conf.Conf.TempDir, 0o777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if base.RestyClient == nil {
+		base.InitClient()
+	}
+}
+
+// node is an object with the path of it in the storage, which drivers don't report
+type node struct {
+	path string
+	obj  model.Obj
+}
+
+type suite struct {
+	h    Harness
+	d    driver.Driver
+	root node
+	work node
+}
+
+func (s *suite) init(t *testing.T) {
+	t.Helper()
+	addition, err := utils.Json.MarshalToString(s.h.Addition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = utils.Json.UnmarshalFromString(addition, s.d.GetAddition()); err != nil {
+		t.Fatal(err)
+	}
+	s.d.SetStorage(model.Storage{
+		ID:        1,
+		MountPath: "/drivertest",
+		Driver:    s.d.Config().Name,
+		Addition:  addition,
+	})
+	ctx := context.Background()
+	if err = s.d.Init(ctx); err != nil {
+		t.Fatalf("failed init: %+v", err)
+	}
+	t.Cleanup(func() { _ = s.d.Drop(context.Background()) })
+	// some drivers call back into op, which checks the status
+	s.d.GetStorage().SetStatus(op.WORK)
+
+	// the same as op.Get for the root
+	var root model.Obj
+	if getRooter, ok := s.d.(driver.GetRooter); ok {
+		if root, err = getRooter.GetRoot(ctx); err != nil {
+			t.Fatalf("failed get root: %+v", err)
+		}
+	} else {
+		switch r := s.d.GetAddition().(type) {
+		case driver.IRootId:
+			root = &model.Object{ID: r.GetRootId(), Name: "root", Path: "/", IsFolder: true}
+		case driver.IRootPath:
+			root = &model.Object{Path: r.GetRootPath(), Name: "root", IsFolder: true}
+		default:
+			// drivers over other storages, e.g. union, get their root by path
+			getter, ok := s.d.(driver.Getter)
+			if !ok {
+				t.Fatal("the driver implements neither IRootPath, IRootId, GetRooter nor Getter")
+			}
+			if root, err = getter.Get(ctx, "/"); err != nil {
+				t.Fatalf("failed get root: %+v", err)
+			}
+		}
+	}
+	s.root = node{path: "/", obj: root}
+}
+
+func testMakeDir(t *testing.T, s *suite) {
+	dir := s.mkdir(t, s.work, "dir")
+	if !dir.obj.IsDir() {
+		t.Fatalf("%s is not a dir", dir.path)
+	}
+	sub := s.mkdir(t, dir, "sub")
+	if objs := s.list(t, dir); len(objs) != 1 || objs["sub"] == nil {
+		t.Fatalf("%s has %v, want only sub", dir.path, objs)
+	}
+	if objs := s.list(t, sub); len(objs) != 0 {
+		t.Fatalf("new dir %s has %v", sub.path, objs)
+	}
+}
+
+func testPut(t *testing.T, s *suite) {
+	data := []byte("hello, openlist")
+	f := s.put(t, s.work, "put.txt", data)
+	if f.obj.IsDir() || f.obj.GetSize() != int64(len(data)) {
+		t.Fatalf("%s is dir %t with size %d, want a file of %d bytes", f.path, f.obj.IsDir(), f.obj.GetSize(), len(data))
+	}
+	if got := s.read(t, f, 0, f.obj.GetSize()); !bytes.Equal(got, data) {
+		t.Fatalf("read %q from %s, want %q", got, f.path, data)
+	}
+	// putting again replaces the file
+	data = []byte("replaced")
+	f = s.put(t, s.work, "put.txt", data)
+	if got := s.read(t, f, 0, f.obj.GetSize()); !bytes.Equal(got, data) {
+		t.Fatalf("read %q from %s after replacing it, want %q", got, f.path, data)
+	}
+}
+
+func testProgress(t *testing.T, s *suite) {
+	var progress []float64
+	s.putWith(t, context.Background(), s.work, "progress.bin", bytes.NewReader(randomBytes(t, 1<<20)), 1<<20, time.Time{}, func(p float64) {
+		progress = append(progress, p)
+	})
+	last := 0.0
+	for _, p := range progress {
+		if p < last || p > 100 {
+			t.Fatalf("progress went %v", progress)
+		}
+		last = p
+	}
+	if len(progress) == 0 {
+		t.Log("the driver reports no progress")
+	}
+}
+
+func testGet(t *testing.T, s *suite) {
+	g, ok := s.d.(driver.Getter)
+	if !ok {
+		t.Skip("driver.Getter is not implemented")
+	}
+	dir := s.mkdir(t, s.work, "get")
+	f := s.put(t, dir, "file.txt", []byte("get"))
+	ctx := context.Background()
+	obj, err := g.Get(ctx, f.path)
+	if err != nil {
+		t.Fatalf("failed get %s: %+v", f.path, err)
+	}
+	if obj.GetName() != "file.txt" || obj.IsDir() || obj.GetSize() != 3 {
+		t.Fatalf("got %s: %s, dir %t, size %d", f.path, obj.GetName(), obj.IsDir(), obj.GetSize())
+	}
+	if obj, err = g.Get(ctx, dir.path); err != nil || !obj.IsDir() {
+		t.Fatalf("got %s: %v, %+v", dir.path, obj, err)
+	}
+	if _, err = g.Get(ctx, stdpath.Join(dir.path, "missing")); !errs.IsObjectNotFound(err) {
+		t.Fatalf("got missing object with %+v, want object not found", err)
+	}
+}
+
+func testLink(t *testing.T, s *suite) {
+	data := randomBytes(t, 64<<10)
+	f := s.put(t, s.work, "link.bin", data)
+	if got := s.read(t, f, 0, f.obj.GetSize()); !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes from %s differing from the %d put", len(got), f.path, len(data))
+	}
+}
+
+func testRangeRead(t *testing.T, s *suite) {
+	data := randomBytes(t, 64<<10)
+	f := s.put(t, s.work, "range.bin", data)
+	size := int64(len(data))
+	for _, r := range [][2]int64{{0, 1}, {1000, 4096}, {size - 10, 10}, {size / 2, size - size/2}} {
+		if got := s.read(t, f, r[0], r[1]); !bytes.Equal(got, data[r[0]:r[0]+r[1]]) {
+			t.Fatalf("read %d bytes at %d of %s, want the %d there", len(got), r[0], f.path, r[1])
+		}
+	}
+}
+
+func testRename(t *testing.T, s *suite) {
+	dir := s.mkdir(t, s.work, "rename")
+	f := s.put(t, dir, "a.txt", []byte("rename"))
+	renamed := s.rename(t, f, "b.txt")
+	objs := s.list(t, dir)
+	if objs["a.txt"] != nil || objs["b.txt"] == nil {
+		t.Fatalf("%s has %v after renaming a.txt to b.txt", dir.path, objs)
+	}
+	if got := s.read(t, renamed, 0, 6); string(got) != "rename" {
+		t.Fatalf("read %q from renamed file", got)
+	}
+	sub := s.mkdir(t, dir, "c")
+	s.put(t, sub, "inner.txt", []byte("inner"))
+	sub = s.rename(t, sub, "d")
+	if objs := s.list(t, sub); objs["inner.txt"] == nil {
+		t.Fatalf("renamed dir %s has %v", sub.path, objs)
+	}
+}
+
+func testMove(t *testing.T, s *suite) {
+	dir := s.mkdir(t, s.work, "move")
+	dst := s.mkdir(t, dir, "dst")
+	f := s.put(t, dir, "file.txt", []byte("move"))
+	moved := s.move(t, f, dst)
+	if objs := s.list(t, dir); objs["file.txt"] != nil {
+		t.Fatalf("%s still has the moved file", dir.path)
+	}
+	if got := s.read(t, moved, 0, 4); string(got) != "move" {
+		t.Fatalf("read %q from moved file", got)
+	}
+	sub := s.mkdir(t, dir, "sub")
+	s.put(t, sub, "inner.txt", []byte("inner"))
+	sub = s.move(t, sub, dst)
+	if objs := s.list(t, sub); objs["inner.txt"] == nil {
+		t.Fatalf("moved dir %s has %v", sub.path, objs)
+	}
+}
+
+func testCopy(t *testing.T, s *suite) {
+	dir := s.mkdir(t, s.work, "copy")
+	dst := s.mkdir(t, dir, "dst")
+	f := s.put(t, dir, "file.txt", []byte("copy"))
+	copied := s.copy(t, f, dst)
+	if objs := s.list(t, dir); objs["file.txt"] == nil {
+		t.Fatalf("%s lost the copied file", dir.path)
+	}
+	if got := s.read(t, copied, 0, 4); string(got) != "copy" {
+		t.Fatalf("read %q from copied file", got)
+	}
+	sub := s.mkdir(t, dir, "sub")
+	s.put(t, sub, "inner.txt", []byte("inner"))
+	copied = s.copy(t, sub, dst)
+	if objs := s.list(t, copied); objs["inner.txt"] == nil {
+		t.Fatalf("copied dir %s has %v", copied.path, objs)
+	}
+}
+
+func testRemove(t *testing.T, s *suite) {
+	dir := s.mkdir(t, s.work, "remove")
+	f := s.put(t, dir, "file.txt", []byte("remove"))
+	sub := s.mkdir(t, dir, "sub")
+	s.put(t, sub, "inner.txt", []byte("inner"))
+	s.remove(t, f)
+	s.remove(t, sub)
+	if objs := s.list(t, dir); len(objs) != 0 {
+		t.Fatalf("%s has %v after removing everything", dir.path, objs)
+	}
+}
+
+func testSetModTime(t *testing.T, s *suite) {
+	m, ok := s.d.(driver.SetModTime)
+	if !ok {
+		t.Skip("driver.SetModTime is not implemented")
+	}
+	dir := s.mkdir(t, s.work, "modtime")
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	f := s.putWith(t, context.Background(), dir, "put.txt", bytes.NewReader([]byte("put")), 3, modTime, nil)
+	if !f.obj.ModTime().Equal(modTime) {
+		t.Errorf("%s put with modification time %s has %s", f.path, modTime, f.obj.ModTime())
+	}
+	f = s.put(t, dir, "set.txt", []byte("set"))
+	if err := m.SetModTime(context.Background(), f.obj, modTime); err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed set modification time of %s: %+v", f.path, err)
+	}
+	if got := s.find(t, dir, "set.txt").obj.ModTime(); !got.Equal(modTime) {
+		t.Fatalf("%s has modification time %s, want %s", f.path, got, modTime)
+	}
+}
+
+func testCancel(t *testing.T, s *suite) {
+	if _, ok := s.d.(driver.Put); !ok {
+		if _, ok = s.d.(driver.PutResult); !ok {
+			t.Skip("driver.Put is not implemented")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	size := int64(4 << 20)
+	r := &cancelReader{r: bytes.NewReader(randomBytes(t, int(size))), after: size / 4, ctx: ctx, cancel: cancel}
+	if err := s.rawPut(ctx, s.work, "cancel.bin", r, size, time.Time{}, nil); err == nil {
+		t.Fatal("the upload canceled halfway succeeded")
+	}
+}
+
+func testLargeUpload(t *testing.T, s *suite) {
+	if s.h.LargeSize == 0 {
+		t.Skip("no LargeSize in the harness")
+	}
+	data := randomBytes(t, int(s.h.LargeSize))
+	f := s.put(t, s.work, "large.bin", data)
+	if f.obj.GetSize() != s.h.LargeSize {
+		t.Fatalf("%s has size %d, want %d", f.path, f.obj.GetSize(), s.h.LargeSize)
+	}
+	if got := s.read(t, f, 0, s.h.LargeSize); !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes from %s differing from the %d put", len(got), f.path, len(data))
+	}
+}
+
+// cancelReader cancels the upload after some bytes have been read
+type cancelReader struct {
+	r      io.Reader
+	read   int64
+	after  int64
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	if r.read >= r.after {
+		r.cancel()
+	}
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+func skipUnsupported(t *testing.T, err error) {
+	t.Helper()
+	if errs.IsNotImplementError(err) || errs.IsNotSupportError(err) {
+		t.Skip(err)
+	}
+}
+
+func random(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,269 @@
+package drivertest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdpath "path"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+// the helpers below call the drivers the way the op package does,
+// preferring the *Result variants and checking what they return
+
+func (s *suite) list(t *testing.T, dir node) map[string]model.Obj {
+	t.Helper()
+	objs, err := s.d.List(context.Background(), dir.obj, model.ListArgs{ReqPath: dir.path, Refresh: true})
+	if err != nil {
+		t.Fatalf("failed list %s: %+v", dir.path, err)
+	}
+	res := make(map[string]model.Obj, len(objs))
+	for _, obj := range objs {
+		// the same as op.List for the drivers leaving the path to it
+		if p, ok := obj.(model.SetPath); ok && obj.GetPath() == "" && dir.obj.GetPath() != "" {
+			p.SetPath(stdpath.Join(dir.obj.GetPath(), obj.GetName()))
+		}
+		res[obj.GetName()] = model.UnwrapObj(obj)
+	}
+	return res
+}
+
+func (s *suite) find(t *testing.T, dir node, name string) node {
+	t.Helper()
+	obj := s.list(t, dir)[name]
+	if obj == nil {
+		t.Fatalf("%s not found in %s", name, dir.path)
+	}
+	return node{path: stdpath.Join(dir.path, name), obj: obj}
+}
+
+// checkResult checks the object returned by a *Result variant, nil is allowed like in op
+func checkResult(t *testing.T, obj model.Obj, name string, isDir bool) {
+	t.Helper()
+	if obj != nil && (obj.GetName() != name || obj.IsDir() != isDir) {
+		t.Fatalf("the driver returned %s, dir %t, want %s, dir %t", obj.GetName(), obj.IsDir(), name, isDir)
+	}
+}
+
+func (s *suite) mkdir(t *testing.T, parent node, name string) node {
+	t.Helper()
+	ctx := context.Background()
+	var (
+		obj model.Obj
+		err error
+	)
+	switch d := s.d.(type) {
+	case driver.MkdirResult:
+		obj, err = d.MakeDir(ctx, parent.obj, name)
+	case driver.Mkdir:
+		err = d.MakeDir(ctx, parent.obj, name)
+	default:
+		t.Skip("driver.Mkdir is not implemented")
+	}
+	if err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed make dir %s in %s: %+v", name, parent.path, err)
+	}
+	checkResult(t, obj, name, true)
+	return s.find(t, parent, name)
+}
+
+func (s *suite) put(t *testing.T, dir node, name string, data []byte) node {
+	t.Helper()
+	return s.putWith(t, context.Background(), dir, name, bytes.NewReader(data), int64(len(data)), time.Time{}, nil)
+}
+
+func (s *suite) putWith(t *testing.T, ctx context.Context, dir node, name string, r io.Reader, size int64, modTime time.Time, up driver.UpdateProgress) node {
+	t.Helper()
+	if err := s.rawPut(ctx, dir, name, r, size, modTime, up); err != nil {
+		if errs.IsNotImplementError(err) {
+			t.Skip("driver.Put is not implemented")
+		}
+		skipUnsupported(t, err)
+		t.Fatalf("failed put %s in %s: %+v", name, dir.path, err)
+	}
+	f := s.find(t, dir, name)
+	if f.obj.GetSize() != size {
+		t.Fatalf("%s has size %d after putting %d bytes", f.path, f.obj.GetSize(), size)
+	}
+	return f
+}
+
+func (s *suite) rawPut(ctx context.Context, dir node, name string, r io.Reader, size int64, modTime time.Time, up driver.UpdateProgress) error {
+	file := &stream.FileStream{
+		Ctx: ctx,
+		Obj: &model.Object{
+			Name:     name,
+			Size:     size,
+			Modified: modTime,
+		},
+		Reader:   r,
+		Mimetype: "application/octet-stream",
+	}
+	defer file.Close()
+	objs, err := s.d.List(ctx, dir.obj, model.ListArgs{ReqPath: dir.path, Refresh: true})
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if obj.GetName() != name {
+			continue
+		}
+		// the same as op.Put for an existing file
+		if exist := model.UnwrapObj(obj); s.d.Config().NoOverwriteUpload {
+			if err = s.d.(driver.Remove).Remove(ctx, exist); err != nil {
+				return err
+			}
+		} else {
+			file.SetExist(exist)
+		}
+	}
+	if up == nil {
+		up = func(float64) {}
+	}
+	switch d := s.d.(type) {
+	case driver.PutResult:
+		var obj model.Obj
+		obj, err = d.Put(ctx, dir.obj, file, up)
+		if err == nil && obj != nil && (obj.GetName() != name || obj.GetSize() != size) {
+			return errs.NewErr(errs.StreamIncomplete, "the driver returned %s of %d bytes", obj.GetName(), obj.GetSize())
+		}
+	case driver.Put:
+		err = d.Put(ctx, dir.obj, file, up)
+	default:
+		err = errs.NotImplement
+	}
+	return err
+}
+
+// read reads length bytes at off through the link of the file
+func (s *suite) read(t *testing.T, f node, off, length int64) []byte {
+	t.Helper()
+	ctx := context.Background()
+	link, err := s.d.Link(ctx, f.obj, model.LinkArgs{})
+	if err != nil {
+		t.Fatalf("failed link %s: %+v", f.path, err)
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(f.obj.GetSize(), link)
+	if err != nil {
+		t.Fatalf("failed get range reader of %s: %+v", f.path, err)
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Start: off, Length: length})
+	if err != nil {
+		t.Fatalf("failed read %d bytes at %d of %s: %+v", length, off, f.path, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, length))
+	if err != nil {
+		t.Fatalf("failed read %d bytes at %d of %s: %+v", length, off, f.path, err)
+	}
+	return data
+}
+
+func (s *suite) rename(t *testing.T, src node, name string) node {
+	t.Helper()
+	ctx := context.Background()
+	var (
+		obj model.Obj
+		err error
+	)
+	switch d := s.d.(type) {
+	case driver.RenameResult:
+		obj, err = d.Rename(ctx, src.obj, name)
+	case driver.Rename:
+		err = d.Rename(ctx, src.obj, name)
+	default:
+		t.Skip("driver.Rename is not implemented")
+	}
+	if err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed rename %s to %s: %+v", src.path, name, err)
+	}
+	checkResult(t, obj, name, src.obj.IsDir())
+	return s.find(t, node{path: stdpath.Dir(src.path), obj: s.parent(t, src)}, name)
+}
+
+func (s *suite) move(t *testing.T, src, dst node) node {
+	t.Helper()
+	ctx := context.Background()
+	var (
+		obj model.Obj
+		err error
+	)
+	switch d := s.d.(type) {
+	case driver.MoveResult:
+		obj, err = d.Move(ctx, src.obj, dst.obj)
+	case driver.Move:
+		err = d.Move(ctx, src.obj, dst.obj)
+	default:
+		t.Skip("driver.Move is not implemented")
+	}
+	if err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed move %s to %s: %+v", src.path, dst.path, err)
+	}
+	checkResult(t, obj, src.obj.GetName(), src.obj.IsDir())
+	return s.find(t, dst, src.obj.GetName())
+}
+
+func (s *suite) copy(t *testing.T, src, dst node) node {
+	t.Helper()
+	ctx := context.Background()
+	var (
+		obj model.Obj
+		err error
+	)
+	switch d := s.d.(type) {
+	case driver.CopyResult:
+		obj, err = d.Copy(ctx, src.obj, dst.obj)
+	case driver.Copy:
+		err = d.Copy(ctx, src.obj, dst.obj)
+	default:
+		t.Skip("driver.Copy is not implemented")
+	}
+	if err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed copy %s to %s: %+v", src.path, dst.path, err)
+	}
+	checkResult(t, obj, src.obj.GetName(), src.obj.IsDir())
+	return s.find(t, dst, src.obj.GetName())
+}
+
+func (s *suite) remove(t *testing.T, obj node) {
+	t.Helper()
+	d, ok := s.d.(driver.Remove)
+	if !ok {
+		t.Skip("driver.Remove is not implemented")
+	}
+	if err := d.Remove(context.Background(), obj.obj); err != nil {
+		skipUnsupported(t, err)
+		t.Fatalf("failed remove %s: %+v", obj.path, err)
+	}
+}
+
+// parent finds the dir object of a node again, as drivers can't go up from an object
+func (s *suite) parent(t *testing.T, n node) model.Obj {
+	t.Helper()
+	dir := s.root
+	for _, name := range splitPath(stdpath.Dir(n.path)) {
+		dir = s.find(t, dir, name)
+	}
+	return dir.obj
+}
+
+func splitPath(p string) []string {
+	var names []string
+	for p != "/" && p != "." && p != "" {
+		names = append([]string{stdpath.Base(p)}, names...)
+		p = stdpath.Dir(p)
+	}
+	return names
+}
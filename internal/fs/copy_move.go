@@ -254,7 +254,12 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 	t.SetTotalBytes(ss.GetSize())
 	t.Status = "uploading"
-	return op.Put(t.Ctx(), t.DstStorage, t.DstActualPath, ss, t.SetProgress, true)
+	if err = op.Put(t.Ctx(), t.DstStorage, t.DstActualPath, ss, t.SetProgress, true); err != nil {
+		return err
+	}
+	// not every driver takes the time of the stream on put, the dst dir is refreshed after the tasks
+	op.PreserveModTime(t.Ctx(), t.DstStorage, srcObj, t.DstActualPath, true)
+	return nil
 }
 
 var (
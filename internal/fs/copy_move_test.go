@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	op.RegisterDriver(func() driver.Driver {
+		return &timelessLocal{Local: local.Local{}}
+	})
+}
+
+// timelessLocal is a local storage whose put ignores the time of the stream, like many remote drivers
+type timelessLocal struct {
+	local.Local
+}
+
+func (d *timelessLocal) Config() driver.Config {
+	c := d.Local.Config()
+	c.Name = "TimelessLocal"
+	return c
+}
+
+func (d *timelessLocal) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	if err := d.Local.Put(ctx, dstDir, stream, up); err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(filepath.Join(dstDir.GetPath(), stream.GetName()), now, now)
+}
+
+func TestCopyPreservesModTime(t *testing.T) {
+	ctx := context.Background()
+	src, dst := t.TempDir(), t.TempDir()
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "a.txt"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []model.Storage{
+		{Driver: "Local", MountPath: "/src", Addition: `{"root_folder_path":"` + filepath.ToSlash(src) + `"}`},
+		{Driver: "TimelessLocal", MountPath: "/dst", Addition: `{"root_folder_path":"` + filepath.ToSlash(dst) + `"}`},
+	} {
+		id, err := op.CreateStorage(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	}
+	// list the dst first so that the put lands in a cached dir
+	if _, err := List(ctx, "/dst", &ListArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Copy(context.WithValue(ctx, conf.NoTaskKey, struct{}{}), "/src/a.txt", "/dst"); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("modification time of the copy is %s, want %s", fi.ModTime(), modTime)
+	}
+}
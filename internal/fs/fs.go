@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"io"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -108,6 +109,14 @@ func Remove(ctx context.Context, path string) error {
 	return err
 }
 
+func SetModTime(ctx context.Context, path string, modTime time.Time) error {
+	err := setModTime(ctx, path, modTime)
+	if err != nil && !errs.IsNotImplementError(err) {
+		log.Errorf("failed set mod time of %s: %+v", path, err)
+	}
+	return err
+}
+
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
 	err := putDirectly(ctx, dstDirPath, file, lazyCache...)
 	if err != nil {
@@ -0,0 +1,71 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+// Versions lists the previous revisions of the file, newest first,
+// the file may have been deleted
+func Versions(ctx context.Context, path string) ([]model.FileVersion, error) {
+	dir, name := stdpath.Split(path)
+	storage, actualDir, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	res, err := op.Other(ctx, storage, model.FsOtherArgs{
+		Path:   actualDir,
+		Method: model.OtherVersions,
+		Data:   model.FileVersionArgs{Name: name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	versions, ok := res.([]model.FileVersion)
+	if !ok {
+		return nil, errs.NotSupport
+	}
+	return versions, nil
+}
+
+// VersionLink links a version of the file, the object is named after the file
+func VersionLink(ctx context.Context, path, version string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	dir, name := stdpath.Split(path)
+	storage, actualDir, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed get storage")
+	}
+	res, err := op.Other(ctx, storage, model.FsOtherArgs{
+		Path:   actualDir,
+		Method: model.OtherVersionLink,
+		Data:   model.FileVersionArgs{Name: name, Version: version, Link: args},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	vl, ok := res.(*model.VersionLink)
+	if !ok {
+		return nil, nil, errs.NotSupport
+	}
+	return vl.Link, vl.Obj, nil
+}
+
+// RestoreVersion replaces the file by one of its versions, the current content becomes a new version
+func RestoreVersion(ctx context.Context, path, version string) error {
+	dir, name := stdpath.Split(path)
+	storage, actualDir, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	_, err = op.Other(ctx, storage, model.FsOtherArgs{
+		Path:   actualDir,
+		Method: model.OtherRestoreVersion,
+		Data:   model.FileVersionArgs{Name: name, Version: version},
+	})
+	return err
+}
@@ -0,0 +1,268 @@
+// Package health probes the mounted storages in the background.
+//
+// Each storage is probed every interval by getting its root, listing it and getting its details, the
+// interval doubles on failures up to the max backoff. Storages failing on their login or left
+// uninitialized are dropped and initialized again, a failed init leaves its error as the status
+// of the storage like on startup. After enough failures in a row a storage is recorded down,
+// the transitions are kept in the status history table and optionally posted to a webhook.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	tick        = 10 * time.Second
+	purgePeriod = time.Hour
+)
+
+type state struct {
+	running     bool
+	loaded      bool // the last record in the history has been read
+	up          *bool
+	upSince     time.Time
+	lastCheck   time.Time
+	nextCheck   time.Time
+	failures    int
+	lastError   string
+	lastErrorAt time.Time
+}
+
+type monitor struct {
+	c      conf.StorageHealth
+	sem    *semaphore.Weighted
+	mu     sync.Mutex
+	states map[uint]*state
+}
+
+var m *monitor
+
+// Init starts the monitor once the storages have been loaded
+func Init(c conf.StorageHealth) {
+	m = newMonitor(c)
+	op.RegisterStorageHook(m.onStorageChange)
+	go func() {
+		<-conf.StoragesLoadSignal()
+		m.run()
+	}()
+}
+
+func newMonitor(c conf.StorageHealth) *monitor {
+	if c.Interval <= 0 {
+		c.Interval = 300
+	}
+	if c.MaxBackoff < c.Interval {
+		c.MaxBackoff = c.Interval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 1
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	return &monitor{
+		c:      c,
+		sem:    semaphore.NewWeighted(int64(c.Concurrency)),
+		states: make(map[uint]*state),
+	}
+}
+
+// Get returns the health of the storage, nil if it has not been probed yet
+func Get(storageId uint) *model.StorageHealth {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[storageId]
+	if !ok || s.lastCheck.IsZero() {
+		return nil
+	}
+	h := &model.StorageHealth{
+		LastCheck: timePtr(s.lastCheck),
+		NextCheck: timePtr(s.nextCheck),
+		Failures:  s.failures,
+		LastError: s.lastError,
+	}
+	if !s.lastErrorAt.IsZero() {
+		h.LastErrorAt = timePtr(s.lastErrorAt)
+	}
+	if s.up != nil && *s.up {
+		h.UpSince = timePtr(s.upSince)
+		h.Uptime = int64(time.Since(s.upSince).Seconds())
+	}
+	return h
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// onStorageChange probes an updated storage again right away and forgets the removed ones
+func (m *monitor) onStorageChange(typ string, d driver.Driver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[d.GetStorage().ID]
+	if !ok {
+		return
+	}
+	switch typ {
+	case "del":
+		delete(m.states, d.GetStorage().ID)
+	case "add", "update":
+		s.failures = 0
+		s.nextCheck = time.Time{}
+	}
+}
+
+func (m *monitor) run() {
+	var lastPurge time.Time
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if m.c.HistoryDays > 0 && now.Sub(lastPurge) >= purgePeriod {
+			lastPurge = now
+			if err := db.DeleteStorageStatusHistoryBefore(now.AddDate(0, 0, -m.c.HistoryDays)); err != nil {
+				log.Errorf("failed purge storage status history: %+v", err)
+			}
+		}
+		for _, d := range op.GetAllStorages() {
+			if s := m.due(d, now); s != nil {
+				go m.check(d, s)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// due marks the state of the storage running and returns it when the storage should be probed
+func (m *monitor) due(d driver.Driver, now time.Time) *state {
+	storage := d.GetStorage()
+	if storage.Disabled {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[storage.ID]
+	if !ok {
+		s = &state{}
+		m.states[storage.ID] = s
+	}
+	if s.running || now.Before(s.nextCheck) {
+		return nil
+	}
+	s.running = true
+	return s
+}
+
+func (m *monitor) check(d driver.Driver, s *state) {
+	_ = m.sem.Acquire(context.Background(), 1)
+	defer m.sem.Release(1)
+	storage := d.GetStorage()
+	if !s.loaded {
+		m.loadLast(storage.ID, s)
+	}
+	err := m.probe(d)
+	if err != nil && needsReinit(err) {
+		log.Infof("reinit storage %s after the health check failed: %s", storage.MountPath, err)
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout())
+		err = op.ReinitStorage(ctx, d)
+		cancel()
+		if err == nil {
+			err = m.probe(d)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.running = false
+	now := time.Now()
+	s.lastCheck = now
+	if err == nil {
+		s.failures = 0
+		s.nextCheck = now.Add(time.Duration(m.c.Interval) * time.Second)
+		m.transit(d, s, true, "")
+		return
+	}
+	s.failures++
+	s.lastError = err.Error()
+	s.lastErrorAt = now
+	s.nextCheck = now.Add(backoff(m.c.Interval, m.c.MaxBackoff, s.failures))
+	log.Warnf("health check of storage %s failed %d times: %s", storage.MountPath, s.failures, s.lastError)
+	if s.failures >= m.c.FailureThreshold {
+		m.transit(d, s, false, s.lastError)
+	}
+}
+
+func (m *monitor) loadLast(storageId uint, s *state) {
+	last, err := db.GetLastStorageStatus(storageId)
+	if err != nil {
+		log.Errorf("%+v", err)
+		return
+	}
+	s.loaded = true
+	if last != nil {
+		s.up = &last.Up
+		if last.Up {
+			s.upSince = last.Time
+		}
+	}
+}
+
+func (m *monitor) timeout() time.Duration {
+	return time.Duration(m.c.Timeout) * time.Second
+}
+
+// transit records the storage going up or down, the caller holds the lock.
+// The status of the storage is left to its init, a probe failing once may not be
+// worth showing the storage as broken
+func (m *monitor) transit(d driver.Driver, s *state, up bool, errMsg string) {
+	storage := d.GetStorage()
+	if s.up != nil && *s.up == up {
+		return
+	}
+	now := time.Now()
+	s.up = &up
+	if up {
+		s.upSince = now
+	}
+	h := &model.StorageStatusHistory{
+		StorageID: storage.ID,
+		MountPath: storage.MountPath,
+		Up:        up,
+		Error:     errMsg,
+		Time:      now,
+	}
+	if err := db.CreateStorageStatusHistory(h); err != nil {
+		log.Errorf("failed save storage status history: %+v", err)
+	}
+	if up {
+		log.Infof("storage %s is up", storage.MountPath)
+	} else {
+		log.Errorf("storage %s is down: %s", storage.MountPath, errMsg)
+	}
+	if m.c.NotifyURL != "" {
+		go notify(m.c.NotifyURL, m.timeout(), h)
+	}
+}
+
+func backoff(interval, maxBackoff, failures int) time.Duration {
+	d := interval
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return time.Duration(min(d, maxBackoff)) * time.Second
+}
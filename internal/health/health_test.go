@@ -0,0 +1,232 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	op.RegisterDriver(func() driver.Driver { return &fake{} })
+}
+
+type fakeAddition struct {
+	Name string `json:"name"`
+}
+
+// fake is a driver whose init and list fail with the errors of the test,
+// listing fails with listErr until the next init
+type fake struct {
+	model.Storage
+	fakeAddition
+	listErr error
+}
+
+var (
+	initErr error
+	listErr error
+	inits   int
+)
+
+func (d *fake) Config() driver.Config {
+	return driver.Config{Name: "HealthFake", NoCache: true, CheckStatus: true}
+}
+
+func (d *fake) GetAddition() driver.Additional {
+	return &d.fakeAddition
+}
+
+func (d *fake) Init(ctx context.Context) error {
+	inits++
+	d.listErr = nil
+	return initErr
+}
+
+func (d *fake) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *fake) GetRoot(ctx context.Context) (model.Obj, error) {
+	return &model.Object{Path: "/", IsFolder: true}, nil
+}
+
+func (d *fake) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	if d.listErr != nil {
+		return nil, d.listErr
+	}
+	return nil, listErr
+}
+
+func (d *fake) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	return nil, errs.NotImplement
+}
+
+func mountFake(t *testing.T) *fake {
+	initErr, listErr, inits = nil, nil, 0
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{Driver: "HealthFake", MountPath: "/health", Addition: `{"name":"a"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(context.Background(), id) })
+	d, err := op.GetStorageByMountPath("/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(*fake)
+}
+
+// checkNow probes the storage right away and waits for it
+func checkNow(t *testing.T, m *monitor, d driver.Driver) *state {
+	s := m.due(d, time.Now().Add(24*time.Hour))
+	if s == nil {
+		t.Fatal("the storage is not due")
+	}
+	m.check(d, s)
+	return s
+}
+
+func history(t *testing.T, d driver.Driver) []model.StorageStatusHistory {
+	hs, _, err := db.GetStorageStatusHistory(d.GetStorage().ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hs
+}
+
+func TestCheckRecordsFailures(t *testing.T) {
+	d := mountFake(t)
+	m := newMonitor(conf.StorageHealth{FailureThreshold: 2})
+	listErr = errors.New("connection refused")
+	s := checkNow(t, m, d)
+	if s.failures != 1 || len(history(t, d)) != 0 {
+		t.Fatalf("%d failures and %d records after a failure", s.failures, len(history(t, d)))
+	}
+	s = checkNow(t, m, d)
+	hs := history(t, d)
+	if s.failures != 2 || len(hs) != 1 || hs[0].Up || hs[0].Error != "failed list root: connection refused" {
+		t.Fatalf("%d failures and records %+v after reaching the threshold", s.failures, hs)
+	}
+	if d.GetStorage().Status != op.WORK {
+		t.Errorf("the probe failures are set as the status: %s", d.GetStorage().Status)
+	}
+	if inits != 1 {
+		t.Errorf("the storage is reinit on a network failure")
+	}
+	listErr = nil
+	checkNow(t, m, d)
+	if hs = history(t, d); len(hs) != 2 || !hs[0].Up {
+		t.Fatalf("records %+v after coming back", hs)
+	}
+	if h := Get(d.GetStorage().ID); h != nil {
+		t.Errorf("the health of a storage is got without the monitor running: %+v", h)
+	}
+}
+
+func TestCheckReinit(t *testing.T) {
+	d := mountFake(t)
+	m := newMonitor(conf.StorageHealth{})
+	d.listErr = fmt.Errorf("failed list: %w", net.HttpStatusCodeError(401))
+	s := checkNow(t, m, d)
+	if inits != 2 || s.failures != 0 || d.GetStorage().Status != op.WORK {
+		t.Fatalf("%d inits, %d failures, status %s after a reinit", inits, s.failures, d.GetStorage().Status)
+	}
+	if hs := history(t, d); len(hs) != 1 || !hs[0].Up {
+		t.Fatalf("records %+v", hs)
+	}
+
+	// the status is only changed by a failed init
+	d.listErr = net.HttpStatusCodeError(401)
+	initErr = errors.New("wrong password")
+	checkNow(t, m, d)
+	if status := d.GetStorage().Status; status != "wrong password" {
+		t.Errorf("status %s after the reinit failed", status)
+	}
+	saved, err := db.GetStorageById(d.GetStorage().ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != "wrong password" {
+		t.Errorf("saved status %s after the reinit failed", saved.Status)
+	}
+	if hs := history(t, d); len(hs) != 2 || hs[0].Up {
+		t.Fatalf("records %+v", hs)
+	}
+}
+
+func TestReinitUpdated(t *testing.T) {
+	d := mountFake(t)
+	// the storage is updated in the database by another instance
+	updated := *d.GetStorage()
+	updated.Addition = `{"name":"b"}`
+	updated.Modified = updated.Modified.Add(time.Minute)
+	if err := db.UpdateStorage(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.ReinitStorage(context.Background(), d); err == nil {
+		t.Fatal("the storage is reinit over an update")
+	}
+	saved, err := db.GetStorageById(d.GetStorage().ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inits != 1 || saved.Addition != `{"name":"b"}` {
+		t.Errorf("%d inits, saved addition %s", inits, saved.Addition)
+	}
+	if err = op.UpdateStorage(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.ReinitStorage(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	if inits != 3 || d.Name != "b" {
+		t.Errorf("%d inits, name %s", inits, d.Name)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for failures, want := range []int{300, 300, 600, 1200, 2400, 3600, 3600} {
+		if got := backoff(300, 3600, failures); got != time.Duration(want)*time.Second {
+			t.Errorf("backoff after %d failures is %s, want %ds", failures, got, want)
+		}
+	}
+}
+
+func TestNeedsReinit(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want bool
+	}{
+		{errors.WithMessage(errs.StorageNotInit, "storage status: failed"), true},
+		{fmt.Errorf("failed list root: %w", net.HttpStatusCodeError(401)), true},
+		{errors.New("access token expired"), true},
+		{errors.New("refresh token has expired"), true},
+		{errors.New("Unauthorized"), true},
+		{net.HttpStatusCodeError(502), false},
+		{errors.New("connection refused"), false},
+		{errors.New("failed get token of the share: 404 not found"), false},
+		{errors.New("permission denied"), false},
+	} {
+		if got := needsReinit(c.err); got != c.want {
+			t.Errorf("needsReinit(%q) = %t, want %t", c.err, got, c.want)
+		}
+	}
+}
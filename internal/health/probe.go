@@ -0,0 +1,112 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// probe gets the root of the storage, lists it without the cache and gets its details
+func (m *monitor) probe(d driver.Driver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout())
+	defer cancel()
+	storage := d.GetStorage()
+	if d.Config().CheckStatus && storage.Status != op.WORK {
+		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.Status)
+	}
+	root, err := op.Get(ctx, d, "/")
+	if err != nil {
+		return err
+	}
+	if _, err = d.List(ctx, model.UnwrapObj(root), model.ListArgs{ReqPath: storage.MountPath, Refresh: true}); err != nil {
+		return errors.WithMessage(err, "failed list root")
+	}
+	if _, ok := d.(driver.WithDetails); ok {
+		if _, err = op.GetStorageDetails(ctx, d, true); err != nil && !errs.IsNotImplementError(err) {
+			return errors.WithMessage(err, "failed get details")
+		}
+	}
+	return nil
+}
+
+// authKeywords are what the drivers put in the errors of an expired or rejected login,
+// the words alone like "token" or "login" show up in unrelated errors too
+var authKeywords = []string{
+	"unauthorized",
+	"token expired", "token has expired", "token is expired", "expired token",
+	"invalid token", "token invalid", "invalid access token", "invalid refresh token",
+	"session expired", "cookie expired", "cookie is invalid", "invalid cookie",
+	"not logged in", "login required", "please login", "please log in",
+}
+
+// needsReinit reports whether the error looks like an expired login or a storage left uninitialized
+func needsReinit(err error) bool {
+	if errors.Is(err, errs.StorageNotInit) {
+		return true
+	}
+	var code net.HttpStatusCodeError
+	if errors.As(err, &code) && (code == http.StatusUnauthorized || code == http.StatusForbidden) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, k := range authKeywords {
+		if strings.Contains(msg, k) {
+			return true
+		}
+	}
+	return false
+}
+
+type notification struct {
+	StorageID uint      `json:"storage_id"`
+	MountPath string    `json:"mount_path"`
+	Status    string    `json:"status"` // up or down
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+func notify(url string, timeout time.Duration, h *model.StorageStatusHistory) {
+	n := notification{
+		StorageID: h.StorageID,
+		MountPath: h.MountPath,
+		Status:    "down",
+		Error:     h.Error,
+		Time:      h.Time,
+	}
+	if h.Up {
+		n.Status = "up"
+	}
+	body, err := utils.Json.Marshal(n)
+	if err != nil {
+		log.Errorf("failed marshal storage notification: %+v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		log.Errorf("failed create storage notification: %+v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := net.HttpClient().Do(req)
+	if err != nil {
+		log.Errorf("failed send storage notification: %+v", err)
+		return
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Errorf("failed send storage notification: %s", res.Status)
+	}
+}
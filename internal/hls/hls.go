@@ -0,0 +1,316 @@
+// Package hls streams the videos of any storage over HLS, remuxing or transcoding them with ffmpeg.
+//
+// The playlists are made up front from the duration of the video, and every segment is produced
+// on its own by seeking to its start, so that players can seek to anywhere without waiting for
+// the segments before it. The segments are kept in the temp dir until they are not used for a while.
+package hls
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"golang.org/x/sync/semaphore"
+)
+
+// RemuxProfile is the profile of the original streams put into segments as they are
+const RemuxProfile = "original"
+
+const (
+	ffmpegTimeout = 2 * time.Minute
+	probeExpire   = time.Hour
+)
+
+var (
+	config  conf.HLS
+	dir     string
+	sem     *semaphore.Weighted
+	probes  = cache.NewMemCache[*Probe]()
+	probeG  singleflight.Group[*Probe]
+	segment singleflight.Group[string]
+)
+
+var errBusy = errors.New("too many segments are being transcoded")
+
+// Init checks for ffmpeg and prepares the segment dir, HLS is not offered when it fails
+func Init(c conf.HLS) error {
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return errors.Errorf("%s is not found", bin)
+		}
+	}
+	if c.SegmentTime <= 0 {
+		c.SegmentTime = 6
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 2
+	}
+	if c.CacheExpire <= 0 {
+		c.CacheExpire = 30
+	}
+	d := filepath.Join(conf.Conf.TempDir, "hls")
+	// the segments of the last run are not indexed, start over
+	_ = os.RemoveAll(d)
+	if err := os.MkdirAll(d, 0o777); err != nil {
+		return errors.WithStack(err)
+	}
+	config, dir = c, d
+	sem = semaphore.NewWeighted(int64(c.Concurrency))
+	go clean()
+	return nil
+}
+
+// Enabled reports whether the service has been initialized
+func Enabled() bool {
+	return sem != nil
+}
+
+// Supported reports whether the object can be streamed
+func Supported(obj model.Obj) bool {
+	return Enabled() && !obj.IsDir() && utils.GetFileType(obj.GetName()) == conf.VIDEO
+}
+
+// clean removes the segments of the videos that have not been played for CacheExpire
+func clean() {
+	expire := time.Duration(config.CacheExpire) * time.Minute
+	for range time.Tick(expire / 2) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Warnf("failed to read hls dir: %+v", err)
+			continue
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || time.Since(info.ModTime()) < expire {
+				continue
+			}
+			if err = os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				log.Warnf("failed to remove hls segments: %+v", err)
+			}
+		}
+	}
+}
+
+// Probe is what the playlists are made from
+type Probe struct {
+	Duration   float64
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+}
+
+// Variant is an entry of the master playlist
+type Variant struct {
+	Profile   string
+	Width     int
+	Height    int
+	Bandwidth int // bits per second
+}
+
+func objKey(path string, obj model.Obj) string {
+	return fmt.Sprintf("%s\n%d\n%d", path, obj.GetSize(), obj.ModTime().UnixNano())
+}
+
+// source exposes the video to ffmpeg, it is read through the link of the storage
+func source(ctx context.Context, path string) (url string, stop func(), err error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return "", nil, err
+	}
+	link, file, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return "", nil, err
+	}
+	rr, err := stream.GetRangeReaderFromLink(file.GetSize(), link)
+	if err != nil {
+		_ = link.Close()
+		return "", nil, err
+	}
+	url, stopServe, err := net.ServeLoopback(ctx, file.GetName(), file.ModTime(), file.GetSize(), rr)
+	if err != nil {
+		_ = link.Close()
+		return "", nil, err
+	}
+	return url, func() {
+		stopServe()
+		_ = link.Close()
+	}, nil
+}
+
+func probe(ctx context.Context, path string, obj model.Obj) (*Probe, error) {
+	key := objKey(path, obj)
+	if p, ok := probes.Get(key); ok {
+		return p, nil
+	}
+	p, err, _ := probeG.Do(key, func() (*Probe, error) {
+		url, stop, err := source(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		defer stop()
+		out, err := ffmpeg.ProbeWithTimeout(url, ffmpegTimeout, nil)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parseProbe(out)
+		if err != nil {
+			return nil, err
+		}
+		probes.Set(key, p, cache.WithEx[*Probe](probeExpire))
+		return p, nil
+	})
+	return p, err
+}
+
+func parseProbe(out string) (*Probe, error) {
+	var res struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p := &Probe{}
+	p.Duration, _ = strconv.ParseFloat(res.Format.Duration, 64)
+	for _, s := range res.Streams {
+		switch {
+		case s.CodecType == "video" && p.VideoCodec == "":
+			p.VideoCodec, p.Width, p.Height = s.CodecName, s.Width, s.Height
+		case s.CodecType == "audio" && p.AudioCodec == "":
+			p.AudioCodec = s.CodecName
+		}
+	}
+	if p.Duration <= 0 || p.VideoCodec == "" {
+		return nil, errors.New("no video stream found")
+	}
+	return p, nil
+}
+
+// canRemux reports whether browsers can play the video stream as it is
+func (p *Probe) canRemux() bool {
+	return config.Remux && p.VideoCodec == "h264"
+}
+
+// Variants lists the profiles offered for the video, the ones larger than it are left out
+func (p *Probe) Variants() []Variant {
+	var variants []Variant
+	if p.canRemux() {
+		variants = append(variants, Variant{Profile: RemuxProfile, Width: p.Width, Height: p.Height})
+	}
+	for i, prof := range config.Profiles {
+		// the smallest profile is always offered
+		if prof.Height > p.Height && i != len(config.Profiles)-1 {
+			continue
+		}
+		variants = append(variants, Variant{
+			Profile:   prof.Name,
+			Width:     p.scaledWidth(prof.Height),
+			Height:    prof.Height,
+			Bandwidth: (prof.VideoBitrate + prof.AudioBitrate) * 1000,
+		})
+	}
+	return variants
+}
+
+func (p *Probe) scaledWidth(height int) int {
+	if p.Height == 0 {
+		return 0
+	}
+	// libx264 needs even dimensions, the same as scale=-2
+	return int(math.Round(float64(p.Width*height)/float64(p.Height)/2)) * 2
+}
+
+func (p *Probe) profile(name string) (conf.HLSProfile, bool) {
+	if name == RemuxProfile {
+		return conf.HLSProfile{Name: RemuxProfile}, p.canRemux()
+	}
+	for _, prof := range config.Profiles {
+		if prof.Name == name {
+			return prof, true
+		}
+	}
+	return conf.HLSProfile{}, false
+}
+
+func (p *Probe) segments() int {
+	return int(math.Ceil(p.Duration / float64(config.SegmentTime)))
+}
+
+// MasterPlaylist lists the variants of the video, uri gives the location of the playlist of a profile
+func MasterPlaylist(ctx context.Context, path string, obj model.Obj, uri func(profile string) string) (string, error) {
+	p, err := probe(ctx, path, obj)
+	if err != nil {
+		return "", err
+	}
+	return masterPlaylist(p.Variants(), uri), nil
+}
+
+func masterPlaylist(variants []Variant, uri func(profile string) string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, v := range variants {
+		bandwidth := v.Bandwidth
+		if bandwidth == 0 {
+			// the bitrate of the original is unknown, but the attribute is required
+			bandwidth = 10_000_000
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,NAME=\"%s\"\n%s\n", bandwidth, v.Width, v.Height, v.Profile, uri(v.Profile))
+	}
+	return b.String()
+}
+
+// MediaPlaylist lists the segments of the video in the profile, uri gives the location of a segment
+func MediaPlaylist(ctx context.Context, path string, obj model.Obj, profile string, uri func(index int) string) (string, error) {
+	p, err := probe(ctx, path, obj)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := p.profile(profile); !ok {
+		return "", errors.Errorf("unknown profile: %s", profile)
+	}
+	return mediaPlaylist(p.Duration, float64(config.SegmentTime), uri), nil
+}
+
+func mediaPlaylist(duration, segmentTime float64, uri func(index int) string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-MEDIA-SEQUENCE:0\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(segmentTime)))
+	for i := 0; float64(i)*segmentTime < duration; i++ {
+		fmt.Fprintf(&b, "#EXTINF:%.6f,\n%s\n", min(segmentTime, duration-float64(i)*segmentTime), uri(i))
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+func segmentDir(path string, obj model.Obj, profile string) string {
+	sum := sha1.Sum([]byte(objKey(path, obj)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:]), profile)
+}
@@ -0,0 +1,59 @@
+package hls
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+func TestMediaPlaylist(t *testing.T) {
+	pl := mediaPlaylist(13, 6, func(index int) string {
+		return "v.mkv?seg=" + strconv.Itoa(index)
+	})
+	for _, want := range []string{
+		"#EXT-X-TARGETDURATION:6\n",
+		"#EXTINF:6.000000,\nv.mkv?seg=0\n",
+		"#EXTINF:6.000000,\nv.mkv?seg=1\n",
+		"#EXTINF:1.000000,\nv.mkv?seg=2\n",
+	} {
+		if !strings.Contains(pl, want) {
+			t.Errorf("playlist misses %q:\n%s", want, pl)
+		}
+	}
+	if strings.Contains(pl, "seg=3") || !strings.HasSuffix(pl, "#EXT-X-ENDLIST\n") {
+		t.Errorf("unexpected playlist:\n%s", pl)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	config = conf.DefaultConfig(t.TempDir()).HLS
+	p, err := parseProbe(`{"format":{"duration":"60.5"},"streams":[
+		{"codec_type":"video","codec_name":"h264","width":1280,"height":720},
+		{"codec_type":"audio","codec_name":"ac3"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.segments() != 11 {
+		t.Fatalf("expected 11 segments, got %d", p.segments())
+	}
+	var names []string
+	for _, v := range p.Variants() {
+		names = append(names, v.Profile)
+	}
+	if got := strings.Join(names, ","); got != "original,720p,480p,360p" {
+		t.Fatalf("unexpected variants %s", got)
+	}
+	if w := p.scaledWidth(480); w != 854 {
+		t.Fatalf("expected 854 wide 480p, got %d", w)
+	}
+	if _, ok := p.profile("1080p"); !ok {
+		t.Fatal("listed profiles are accepted even if not offered")
+	}
+
+	p.VideoCodec = "hevc"
+	if _, ok := p.profile(RemuxProfile); ok {
+		t.Fatal("hevc should not be remuxed")
+	}
+}
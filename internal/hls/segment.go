@@ -0,0 +1,132 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// Segment returns the file of the segment, it is transcoded if it is not ready.
+// The next one is prepared in the background if ffmpeg has capacity left.
+func Segment(ctx context.Context, path string, obj model.Obj, profile string, index int) (string, error) {
+	p, err := probe(ctx, path, obj)
+	if err != nil {
+		return "", err
+	}
+	prof, ok := p.profile(profile)
+	if !ok {
+		return "", errors.Errorf("unknown profile: %s", profile)
+	}
+	if index < 0 || index >= p.segments() {
+		return "", errors.New("segment out of range")
+	}
+	d := segmentDir(path, obj, profile)
+	// keep the segments of the video from being cleaned while it is played
+	now := time.Now()
+	_ = os.Chtimes(filepath.Dir(d), now, now)
+	file, err := makeSegment(ctx, path, p, prof, d, index, true)
+	if err == nil && index+1 < p.segments() {
+		go func() {
+			_, _ = makeSegment(context.Background(), path, p, prof, d, index+1, false)
+		}()
+	}
+	return file, err
+}
+
+func makeSegment(ctx context.Context, path string, p *Probe, prof conf.HLSProfile, d string, index int, wait bool) (string, error) {
+	file := filepath.Join(d, strconv.Itoa(index)+".ts")
+	for {
+		if utils.Exists(file) {
+			return file, nil
+		}
+		_, err, _ := segment.Do(file, func() (string, error) {
+			if utils.Exists(file) {
+				return file, nil
+			}
+			// the other callers wait for the same segment, so finish it even if this one leaves
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*ffmpegTimeout)
+			defer cancel()
+			if !wait {
+				if !sem.TryAcquire(1) {
+					return "", errBusy
+				}
+			} else if err := sem.Acquire(ctx, 1); err != nil {
+				return "", err
+			}
+			defer sem.Release(1)
+			return file, transcode(ctx, path, p, prof, index, file)
+		})
+		// joined a prefetch that gave up, try on our own
+		if wait && errors.Is(err, errBusy) {
+			continue
+		}
+		return file, err
+	}
+}
+
+func transcode(ctx context.Context, path string, p *Probe, prof conf.HLSProfile, index int, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+		return errors.WithStack(err)
+	}
+	url, stop, err := source(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	start := fmt.Sprintf("%f", float64(index*config.SegmentTime))
+	args := ffmpeg.KwArgs{
+		"t":                strconv.Itoa(config.SegmentTime),
+		"map":              []string{"0:v:0", "0:a:0?"},
+		"output_ts_offset": start,
+		"muxdelay":         "0",
+		"format":           "mpegts",
+	}
+	if prof.Name == RemuxProfile {
+		// copied streams can only be cut at key frames, so the segments may overlap a little
+		args["c:v"] = "copy"
+	} else {
+		args["c:v"] = "libx264"
+		args["preset"] = "veryfast"
+		args["pix_fmt"] = "yuv420p"
+		args["vf"] = fmt.Sprintf("scale=-2:%d", min(prof.Height, p.Height))
+		args["b:v"] = fmt.Sprintf("%dk", prof.VideoBitrate)
+		args["maxrate"] = fmt.Sprintf("%dk", prof.VideoBitrate)
+		args["bufsize"] = fmt.Sprintf("%dk", 2*prof.VideoBitrate)
+	}
+	if prof.Name == RemuxProfile && p.AudioCodec == "aac" {
+		args["c:a"] = "copy"
+	} else {
+		audioBitrate := prof.AudioBitrate
+		if audioBitrate <= 0 {
+			audioBitrate = 128
+		}
+		args["c:a"] = "aac"
+		args["ac"] = "2"
+		args["b:a"] = fmt.Sprintf("%dk", audioBitrate)
+	}
+	// ffmpeg writes next to the segment, which is renamed into place once complete
+	tmp := file + ".tmp"
+	err = ffmpeg.Input(url, ffmpeg.KwArgs{"ss": start}).
+		Output(tmp, args).
+		OverWriteOutput().
+		GlobalArgs("-loglevel", "error").Silent(true).
+		WithTimeout(ffmpegTimeout).
+		Run()
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+	return nil
+}
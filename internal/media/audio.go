@@ -0,0 +1,26 @@
+package media
+
+import (
+	"io"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/dhowden/tag"
+)
+
+// parseAudio reads the ID3, Vorbis, FLAC and MP4 tags of music
+func parseAudio(r io.ReaderAt, size int64, info *model.MediaInfo) {
+	m, err := tag.ReadFrom(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return
+	}
+	info.Title = strings.TrimSpace(m.Title())
+	info.Artist = strings.TrimSpace(m.Artist())
+	if info.Artist == "" {
+		info.Artist = strings.TrimSpace(m.AlbumArtist())
+	}
+	info.Album = strings.TrimSpace(m.Album())
+	info.Genre = strings.TrimSpace(m.Genre())
+	info.Year = m.Year()
+	info.Track, _ = m.Track()
+}
@@ -0,0 +1,157 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/rwcarlsen/goexif/exif"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// headSize is how much of an image is read, EXIF and XMP are expected in it
+const headSize = 1 << 20
+
+func readHead(r io.ReaderAt, off, size int64) []byte {
+	if off < 0 || off >= size {
+		return nil
+	}
+	buf := make([]byte, min(size-off, headSize))
+	n, _ := r.ReadAt(buf, off)
+	return buf[:n]
+}
+
+func parseImage(r io.ReaderAt, size int64, info *model.MediaInfo) {
+	head := readHead(r, 0, size)
+	if bytes.HasPrefix(head, []byte("FUJIFILMCCD-RAW")) && len(head) >= 92 {
+		// the metadata of Fujifilm raw files is in the jpeg preview
+		head = readHead(r, int64(binary.BigEndian.Uint32(head[84:])), size)
+	} else if !isTiff(head) {
+		// the dimensions of raw files are of their thumbnails, leave them to EXIF
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	}
+	if x := decodeExif(head); x != nil {
+		fillExif(x, info)
+	}
+	parseXMP(head, info)
+}
+
+func isTiff(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*"))
+}
+
+// decodeExif finds EXIF in jpeg, tiff and the containers that keep it in an Exif box or chunk
+func decodeExif(head []byte) *exif.Exif {
+	data := head
+	if !bytes.HasPrefix(head, []byte{0xff, 0xd8}) && !isTiff(head) {
+		if i := bytes.Index(head, []byte("Exif\x00\x00")); i >= 0 {
+			data = head[i:]
+		} else if i = bytes.Index(head, []byte("eXIf")); i >= 0 {
+			data = head[i+4:]
+		} else {
+			return nil
+		}
+	}
+	x, err := exif.Decode(bytes.NewReader(data))
+	if x == nil || err != nil && exif.IsCriticalError(err) {
+		return nil
+	}
+	return x
+}
+
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	s, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+func exifInt(x *exif.Exif, name exif.FieldName) int {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	v, _ := tag.Int(0)
+	return v
+}
+
+func fillExif(x *exif.Exif, info *model.MediaInfo) {
+	info.Make = exifString(x, exif.Make)
+	info.Model = exifString(x, exif.Model)
+	info.Lens = exifString(x, exif.LensModel)
+	if t, err := x.DateTime(); err == nil && !t.IsZero() {
+		info.TakenAt = &t
+	}
+	if lat, long, err := x.LatLong(); err == nil && (lat != 0 || long != 0) {
+		info.Latitude, info.Longitude = &lat, &long
+	}
+	if info.Width == 0 {
+		info.Width = exifInt(x, exif.PixelXDimension)
+		info.Height = exifInt(x, exif.PixelYDimension)
+	}
+}
+
+var xmpLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseXMP fills in what EXIF misses, the properties may be attributes or elements
+func parseXMP(head []byte, info *model.MediaInfo) {
+	start := bytes.Index(head, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return
+	}
+	xmp := head[start:]
+	if end := bytes.Index(xmp, []byte("</x:xmpmeta>")); end >= 0 {
+		xmp = xmp[:end]
+	}
+	if info.TakenAt == nil {
+		for _, name := range []string{"exif:DateTimeOriginal", "photoshop:DateCreated", "xmp:CreateDate"} {
+			v := xmpValue(xmp, name)
+			for _, layout := range xmpLayouts {
+				if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+					info.TakenAt = &t
+					break
+				}
+			}
+			if info.TakenAt != nil {
+				break
+			}
+		}
+	}
+	if info.Make == "" {
+		info.Make = xmpValue(xmp, "tiff:Make")
+	}
+	if info.Model == "" {
+		info.Model = xmpValue(xmp, "tiff:Model")
+	}
+	if info.Lens == "" {
+		info.Lens = xmpValue(xmp, "exifEX:LensModel", "aux:Lens")
+	}
+}
+
+func xmpValue(xmp []byte, names ...string) string {
+	for _, name := range names {
+		q := regexp.QuoteMeta(name)
+		re := regexp.MustCompile(q + `="([^"]*)"|<` + q + `>([^<]*)</` + q + `>`)
+		if m := re.FindSubmatch(xmp); m != nil {
+			return strings.TrimSpace(string(m[1]) + string(m[2]))
+		}
+	}
+	return ""
+}
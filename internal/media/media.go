@@ -0,0 +1,157 @@
+// Package media extracts the metadata of images, audio and video files in background and keeps
+// it in the database, keyed by the path and modified time of the files.
+//
+// Only the headers are read from the storages through their links. EXIF and XMP are read from
+// images, tags from music and the duration and resolution from MP4 containers, ffprobe fills in
+// the rest of the containers when it is installed.
+package media
+
+import (
+	"context"
+	"os/exec"
+	stdpath "path"
+	"sync"
+	"sync/atomic"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/mq"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	imageExts = []string{"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp", "heic", "heif", "avif",
+		"dng", "cr2", "nef", "nrw", "arw", "srf", "sr2", "orf", "pef", "srw", "rw2", "raf"}
+
+	mediaMQ = mq.NewInMemoryMQ[string]()
+	running atomic.Bool
+)
+
+// Kind returns the kind of media of the file, empty for the other files
+func Kind(name string) string {
+	if utils.SliceContains(imageExts, utils.Ext(name)) {
+		return model.MediaImage
+	}
+	switch utils.GetFileType(name) {
+	case conf.AUDIO:
+		return model.MediaAudio
+	case conf.VIDEO:
+		return model.MediaVideo
+	}
+	return ""
+}
+
+// Enqueue queues files to extract metadata from, the work is done in background
+// because every file has to be read from its storage.
+func Enqueue(paths ...string) {
+	if !setting.GetBool(conf.MediaIndex) {
+		return
+	}
+	for _, p := range paths {
+		if Kind(p) != "" {
+			mediaMQ.Publish(mq.Message[string]{Content: p})
+		}
+	}
+	if mediaMQ.Len() > 0 && running.CompareAndSwap(false, true) {
+		go consume()
+	}
+}
+
+func consume() {
+	for {
+		mediaMQ.ConsumeAll(func(messages []mq.Message[string]) {
+			for _, msg := range messages {
+				if err := extract(context.Background(), msg.Content); err != nil {
+					log.Warnf("extract media info of %s error: %+v", msg.Content, err)
+				}
+			}
+		})
+		running.Store(false)
+		// a publish may have happened between the last consume and the store
+		if mediaMQ.Len() == 0 || !running.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+// Get returns the metadata of the object at path, nil if it is not extracted for this version
+func Get(path string, obj model.Obj) *model.MediaInfo {
+	info, err := db.GetMediaInfo(path)
+	if err != nil || info.Size != obj.GetSize() || !info.Modified.Equal(obj.ModTime()) {
+		return nil
+	}
+	return info
+}
+
+// Delete drops the metadata of the file or everything under the dir
+func Delete(path string) {
+	if err := db.DeleteMediaInfos(path); err != nil {
+		log.Warnf("failed to delete media info of %s: %+v", path, err)
+	}
+}
+
+func extract(ctx context.Context, path string) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return err
+	}
+	if storage.GetStorage().DisableIndex {
+		return nil
+	}
+	obj, err := op.Get(ctx, storage, actualPath)
+	if err != nil {
+		return err
+	}
+	if Get(path, obj) != nil {
+		return nil
+	}
+	link, file, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Ctx: ctx, Obj: file}, link)
+	if err != nil {
+		_ = link.Close()
+		return err
+	}
+	defer ss.Close()
+	r, err := stream.NewReadAtSeeker(ss, 0)
+	if err != nil {
+		return err
+	}
+	parent, name := stdpath.Split(path)
+	info := &model.MediaInfo{
+		Parent:   utils.FixAndCleanPath(parent),
+		Name:     name,
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Kind:     Kind(name),
+	}
+	switch info.Kind {
+	case model.MediaImage:
+		parseImage(r, obj.GetSize(), info)
+	case model.MediaAudio:
+		parseAudio(r, obj.GetSize(), info)
+		parseMP4(r, obj.GetSize(), info)
+	case model.MediaVideo:
+		parseMP4(r, obj.GetSize(), info)
+	}
+	// the containers other than MP4 are left to ffprobe
+	if info.Kind != model.MediaImage && info.Duration == 0 && hasFFprobe() {
+		if err = probe(ctx, link, file, info); err != nil {
+			log.Debugf("failed to probe %s: %+v", path, err)
+		}
+	}
+	log.Debugf("extract media info of %s: %+v", path, info)
+	return db.SaveMediaInfo(info)
+}
+
+var hasFFprobe = sync.OnceValue(func() bool {
+	_, err := exec.LookPath("ffprobe")
+	return err == nil
+})
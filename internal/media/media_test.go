@@ -0,0 +1,72 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func box(typ string, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(data))), append([]byte(typ), data...)...)
+}
+
+func trak(handler, format string, width, height uint32) []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:], width<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], height<<16)
+	hdlr := append(make([]byte, 8), []byte(handler)...)
+	stsd := append(make([]byte, 12), []byte(format)...)
+	return box("trak", box("tkhd", tkhd), box("mdia", box("hdlr", hdlr),
+		box("minf", box("stbl", box("stsd", stsd)))))
+}
+
+func TestParseMP4(t *testing.T) {
+	mvhd := make([]byte, 20)
+	taken := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	binary.BigEndian.PutUint32(mvhd[4:], uint32(taken.Sub(mp4Epoch)/time.Second))
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 90500)
+	// the moov after the media data, as most cameras write it
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom")),
+		box("mdat", make([]byte, 4096)),
+		box("moov", box("mvhd", mvhd), trak("vide", "avc1", 1920, 1080), trak("soun", "mp4a", 0, 0)),
+	}, nil)
+	info := &model.MediaInfo{Kind: model.MediaVideo}
+	parseMP4(bytes.NewReader(file), int64(len(file)), info)
+	if info.Duration != 90.5 || info.Width != 1920 || info.Height != 1080 {
+		t.Errorf("unexpected duration or resolution: %+v", info)
+	}
+	if info.VideoCodec != "h264" || info.AudioCodec != "aac" {
+		t.Errorf("unexpected codecs: %+v", info)
+	}
+	if info.TakenAt == nil || !info.TakenAt.Equal(taken) {
+		t.Errorf("unexpected taken at: %v", info.TakenAt)
+	}
+
+	info = &model.MediaInfo{Kind: model.MediaAudio}
+	mp3 := append([]byte("ID3\x04\x00\x00"), make([]byte, 64)...)
+	parseMP4(bytes.NewReader(mp3), int64(len(mp3)), info)
+	if info.Duration != 0 {
+		t.Errorf("mp3 parsed as mp4: %+v", info)
+	}
+}
+
+func TestParseXMP(t *testing.T) {
+	head := []byte(`<?xpacket begin=""?><x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:Description
+		tiff:Make="Canon" exif:DateTimeOriginal="2023-08-14T09:30:00">
+		<tiff:Model>EOS R5</tiff:Model></rdf:Description></x:xmpmeta>`)
+	info := &model.MediaInfo{}
+	parseXMP(head, info)
+	if info.Make != "Canon" || info.Model != "EOS R5" {
+		t.Errorf("unexpected camera: %+v", info)
+	}
+	want := time.Date(2023, 8, 14, 9, 30, 0, 0, time.Local)
+	if info.TakenAt == nil || !info.TakenAt.Equal(want) {
+		t.Errorf("unexpected taken at: %v", info.TakenAt)
+	}
+}
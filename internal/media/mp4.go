@@ -0,0 +1,174 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+const (
+	maxMoovSize = 16 << 20
+	maxTopBoxes = 64
+)
+
+// mp4Epoch is where the times of the movie header count from
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// the sample entries are mapped to the codec names of ffprobe
+var mp4Codecs = map[string]string{
+	"avc1": "h264", "avc3": "h264", "hvc1": "hevc", "hev1": "hevc", "av01": "av1", "vp09": "vp9",
+	"mp4v": "mpeg4", "mp4a": "aac", "ac-3": "ac3", "ec-3": "eac3", "Opus": "opus", "fLaC": "flac", "alac": "alac",
+}
+
+// parseMP4 reads the duration, resolution and codecs from the moov box of MP4 and QuickTime files,
+// the top level boxes are walked with small reads so a moov at the end costs no more than one at the start
+func parseMP4(r io.ReaderAt, size int64, info *model.MediaInfo) {
+	var hdr [16]byte
+	for off, i := int64(0), 0; off+8 <= size && i < maxTopBoxes; i++ {
+		if _, err := r.ReadAt(hdr[:8], off); err != nil {
+			return
+		}
+		boxSize, typ, hdrSize := int64(binary.BigEndian.Uint32(hdr[:])), string(hdr[4:8]), int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - off
+		case 1:
+			if _, err := r.ReadAt(hdr[8:], off+8); err != nil {
+				return
+			}
+			boxSize, hdrSize = int64(binary.BigEndian.Uint64(hdr[8:])), 16
+		}
+		if boxSize < hdrSize || off+boxSize > size {
+			return
+		}
+		// the files not starting with a known box are not MP4
+		if i == 0 && typ != "ftyp" && typ != "moov" && typ != "mdat" && typ != "wide" && typ != "free" {
+			return
+		}
+		if typ == "moov" {
+			if boxSize-hdrSize > maxMoovSize {
+				return
+			}
+			moov := make([]byte, boxSize-hdrSize)
+			if _, err := r.ReadAt(moov, off+hdrSize); err != nil {
+				return
+			}
+			parseMoov(moov, info)
+			return
+		}
+		off += boxSize
+	}
+}
+
+// walkBoxes calls fn for the boxes in data, the ones of 64 bit sizes are not expected in moov
+func walkBoxes(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		n := int(binary.BigEndian.Uint32(data))
+		if n < 8 || n > len(data) {
+			return
+		}
+		fn(string(data[4:8]), data[8:n])
+		data = data[n:]
+	}
+}
+
+func parseMoov(moov []byte, info *model.MediaInfo) {
+	walkBoxes(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			parseMvhd(body, info)
+		case "trak":
+			parseTrak(body, info)
+		}
+	})
+}
+
+func parseMvhd(body []byte, info *model.MediaInfo) {
+	var created, timescale, duration uint64
+	switch {
+	case len(body) >= 20 && body[0] == 0:
+		created = uint64(binary.BigEndian.Uint32(body[4:]))
+		timescale = uint64(binary.BigEndian.Uint32(body[12:]))
+		duration = uint64(binary.BigEndian.Uint32(body[16:]))
+	case len(body) >= 32 && body[0] == 1:
+		created = binary.BigEndian.Uint64(body[4:])
+		timescale = uint64(binary.BigEndian.Uint32(body[20:]))
+		duration = binary.BigEndian.Uint64(body[24:])
+	default:
+		return
+	}
+	if timescale > 0 {
+		info.Duration = float64(duration) / float64(timescale)
+	}
+	// cameras record when the video is taken, the tools that write 0 or the epoch itself are ignored
+	if info.Kind == model.MediaVideo && info.TakenAt == nil && created > 0 {
+		t := mp4Epoch.Add(time.Duration(created) * time.Second)
+		if t.After(time.Unix(0, 0)) {
+			info.TakenAt = &t
+		}
+	}
+}
+
+func parseTrak(trak []byte, info *model.MediaInfo) {
+	var (
+		width, height int
+		handler       string
+		codec         string
+	)
+	walkBoxes(trak, func(typ string, body []byte) {
+		switch typ {
+		case "tkhd":
+			// the dimensions are 16.16 fixed point numbers after the matrix
+			at := 76
+			if len(body) > 0 && body[0] == 1 {
+				at = 88
+			}
+			if len(body) >= at+8 {
+				width = int(binary.BigEndian.Uint32(body[at:]) >> 16)
+				height = int(binary.BigEndian.Uint32(body[at+4:]) >> 16)
+			}
+		case "mdia":
+			walkBoxes(body, func(typ string, body []byte) {
+				switch typ {
+				case "hdlr":
+					if len(body) >= 12 {
+						handler = string(body[8:12])
+					}
+				case "minf":
+					walkBoxes(body, func(typ string, body []byte) {
+						if typ == "stbl" {
+							walkBoxes(body, func(typ string, body []byte) {
+								// the format of the first sample entry
+								if typ == "stsd" && len(body) >= 16 {
+									codec = string(body[12:16])
+								}
+							})
+						}
+					})
+				}
+			})
+		}
+	})
+	switch handler {
+	case "vide":
+		if width*height > info.Width*info.Height {
+			info.Width, info.Height = width, height
+		}
+		if info.VideoCodec == "" {
+			info.VideoCodec = mp4CodecName(codec)
+		}
+	case "soun":
+		if info.AudioCodec == "" {
+			info.AudioCodec = mp4CodecName(codec)
+		}
+	}
+}
+
+func mp4CodecName(format string) string {
+	if name, ok := mp4Codecs[format]; ok {
+		return name
+	}
+	return format
+}
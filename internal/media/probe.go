@@ -0,0 +1,63 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/pkg/errors"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+const probeTimeout = time.Minute
+
+// probe fills in what the parsers could not read with ffprobe, it only reads the parts of the file it needs
+func probe(ctx context.Context, link *model.Link, file model.Obj, info *model.MediaInfo) error {
+	rr, err := stream.GetRangeReaderFromLink(file.GetSize(), link)
+	if err != nil {
+		return err
+	}
+	url, stop, err := net.ServeLoopback(ctx, file.GetName(), file.ModTime(), file.GetSize(), rr)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	out, err := ffmpeg.ProbeWithTimeout(url, probeTimeout, nil)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err = json.Unmarshal([]byte(out), &res); err != nil {
+		return errors.WithStack(err)
+	}
+	if info.Duration == 0 {
+		info.Duration, _ = strconv.ParseFloat(res.Format.Duration, 64)
+	}
+	for _, s := range res.Streams {
+		switch {
+		// the cover art of music is a video stream as well
+		case s.CodecType == "video" && info.Kind == model.MediaVideo && info.VideoCodec == "":
+			info.VideoCodec = s.CodecName
+			if info.Width == 0 {
+				info.Width, info.Height = s.Width, s.Height
+			}
+		case s.CodecType == "audio" && info.AudioCodec == "":
+			info.AudioCodec = s.CodecName
+		}
+	}
+	return nil
+}
@@ -12,6 +12,10 @@ type Messenger interface {
 	WaitReceive(int) (string, error)
 }
 
+// GetMessenger prefers the websocket when an admin is connected, the polling http one otherwise
 func GetMessenger() Messenger {
+	if WsInstance.HasAdmin() {
+		return WsInstance
+	}
 	return HttpInstance
 }
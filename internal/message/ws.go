@@ -1,3 +1,291 @@
 package message
 
-// TODO websocket implementation
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsSendBuffer is how many messages may be queued for a client before it is dropped as too slow
+	wsSendBuffer = 256
+)
+
+// wsCheckPeriod is how often the user and the session of a client are checked,
+// the clients logged out, disabled or with a changed password are disconnected
+var wsCheckPeriod = time.Minute
+
+// WsProtocol is the subprotocol of the web socket, the client may offer its token as the next one
+// since browsers can't set the Authorization header of a web socket
+const WsProtocol = "openlist"
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin:  checkOrigin,
+	Subprotocols: []string{WsProtocol},
+}
+
+// checkOrigin allows the pages of the site itself, by the host of the request or the site url,
+// and the clients that are not browsers
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	site, err := url.Parse(conf.Conf.SiteURL)
+	return err == nil && site.Host != "" && strings.EqualFold(u.Host, site.Host)
+}
+
+type wsClient struct {
+	user    *model.User
+	session string
+	conn    *websocket.Conn
+	send    chan Message
+}
+
+// Ws pushes messages and live events to the connected web clients,
+// and implements Messenger with the admin clients
+type Ws struct {
+	mu       sync.RWMutex
+	clients  map[*wsClient]struct{}
+	Received chan string // received messages from admins
+}
+
+var WsInstance = &Ws{
+	clients:  make(map[*wsClient]struct{}),
+	Received: make(chan string),
+}
+
+// Handle upgrades the request of an authenticated user to a web socket
+func (w *Ws) Handle(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	session, _ := c.Request.Context().Value(conf.SessionKey).(string)
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Debugf("failed to upgrade websocket: %+v", err)
+		return
+	}
+	client := &wsClient{
+		user:    user,
+		session: session,
+		conn:    conn,
+		send:    make(chan Message, wsSendBuffer),
+	}
+	w.mu.Lock()
+	w.clients[client] = struct{}{}
+	w.mu.Unlock()
+	go w.writeLoop(client)
+	w.readLoop(client)
+}
+
+func (w *Ws) remove(client *wsClient) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.clients[client]; ok {
+		delete(w.clients, client)
+		close(client.send)
+	}
+}
+
+// userOf returns the user of the client, which is replaced when it is checked
+func (w *Ws) userOf(client *wsClient) *model.User {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return client.user
+}
+
+// check reloads the user of the client, false if the client is no longer logged in
+func (w *Ws) check(client *wsClient) bool {
+	old := w.userOf(client)
+	user, err := op.GetUserById(old.ID)
+	if err != nil || user.Disabled || user.PwdTS != old.PwdTS {
+		return false
+	}
+	if client.session != "" {
+		if s, err := op.GetSessionById(client.session); err != nil || s.Expired() {
+			return false
+		}
+	}
+	w.mu.Lock()
+	client.user = user
+	w.mu.Unlock()
+	return true
+}
+
+func (w *Ws) readLoop(client *wsClient) {
+	defer func() {
+		w.remove(client)
+		_ = client.conn.Close()
+	}()
+	client.conn.SetReadLimit(64 * 1024)
+	_ = client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		var msg Message
+		if err := client.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		// only admins answer the messages, e.g. the verification code of a storage
+		user := w.userOf(client)
+		if msg.Type != "message" || !user.IsAdmin() {
+			continue
+		}
+		content, _ := msg.Content.(string)
+		select {
+		case w.Received <- content:
+		default:
+			log.Debugf("websocket message from %s is not needed", user.Username)
+		}
+	}
+}
+
+func (w *Ws) writeLoop(client *wsClient) {
+	ticker := time.NewTicker(wsPingPeriod)
+	checker := time.NewTicker(wsCheckPeriod)
+	defer func() {
+		ticker.Stop()
+		checker.Stop()
+		_ = client.conn.Close()
+	}()
+	for {
+		select {
+		case msg, ok := <-client.send:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				_ = client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			data, err := utils.Json.Marshal(msg)
+			if err != nil {
+				log.Errorf("failed to marshal websocket message: %+v", err)
+				continue
+			}
+			if err = client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-checker.C:
+			if !w.check(client) {
+				_ = client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				_ = client.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "login please"))
+				return
+			}
+		}
+	}
+}
+
+// Publish sends the message built for each connected user, a false return skips the user.
+// Clients that can not keep up are disconnected rather than blocking the publisher.
+func (w *Ws) Publish(build func(user *model.User) (Message, bool)) int {
+	w.mu.RLock()
+	var slow []*wsClient
+	sent := 0
+	for client := range w.clients {
+		msg, ok := build(client.user)
+		if !ok {
+			continue
+		}
+		select {
+		case client.send <- msg:
+			sent++
+		default:
+			slow = append(slow, client)
+		}
+	}
+	w.mu.RUnlock()
+	for _, client := range slow {
+		log.Warnf("websocket client of %s is too slow, disconnect it", w.userOf(client).Username)
+		w.remove(client)
+	}
+	return sent
+}
+
+// Broadcast sends the message to the users it is visible to, nil visible means everyone
+func (w *Ws) Broadcast(msg Message, visible func(user *model.User) bool) int {
+	return w.Publish(func(user *model.User) (Message, bool) {
+		return msg, visible == nil || visible(user)
+	})
+}
+
+func (w *Ws) HasClients() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.clients) > 0
+}
+
+func (w *Ws) HasAdmin() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for client := range w.clients {
+		if client.user.IsAdmin() {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Ws) Send(message Message) error {
+	if w.Broadcast(message, (*model.User).IsAdmin) == 0 {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (w *Ws) Receive() (string, error) {
+	select {
+	case message := <-w.Received:
+		return message, nil
+	default:
+		return "", errors.New("receive failed")
+	}
+}
+
+// WaitSend waits at most d seconds for an admin to connect
+func (w *Ws) WaitSend(message Message, d int) error {
+	deadline := time.Now().Add(time.Duration(d) * time.Second)
+	for {
+		if w.Send(message) == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("send timeout")
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (w *Ws) WaitReceive(d int) (string, error) {
+	select {
+	case message := <-w.Received:
+		return message, nil
+	case <-time.After(time.Duration(d) * time.Second):
+		return "", errors.New("receive timeout")
+	}
+}
@@ -0,0 +1,162 @@
+package message
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	conf.Conf = conf.DefaultConfig("data")
+	conf.Conf.SiteURL = "https://pan.example.com/openlist"
+	for origin, allowed := range map[string]bool{
+		"":                              true,
+		"http://127.0.0.1:5244":         true,
+		"https://pan.example.com":       true,
+		"https://evil.example.com":      false,
+		"http://127.0.0.1:5244.evil.io": false,
+		"null":                          false,
+	} {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:5244/api/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if checkOrigin(r) != allowed {
+			t.Errorf("origin %q allowed: %v, want %v", origin, !allowed, allowed)
+		}
+	}
+}
+
+func TestWsPublish(t *testing.T) {
+	conf.Conf = conf.DefaultConfig("data")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) {
+		user := &model.User{Username: c.Query("user"), Role: model.GENERAL}
+		if user.Username == "admin" {
+			user.Role = model.ADMIN
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), conf.UserKey, user))
+		WsInstance.Handle(c)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	dial := func(user string) *websocket.Conn {
+		dialer := websocket.Dialer{Subprotocols: []string{WsProtocol}}
+		conn, res, err := dialer.Dial(strings.Replace(srv.URL, "http", "ws", 1)+"/ws?user="+user, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := res.Header.Get("Sec-WebSocket-Protocol"); p != WsProtocol {
+			t.Errorf("negotiated protocol %q", p)
+		}
+		return conn
+	}
+	admin, user := dial("admin"), dial("user")
+	defer admin.Close()
+	defer user.Close()
+	deadline := time.Now().Add(time.Second)
+	for !WsInstance.HasAdmin() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := WsInstance.Send(Message{Type: "message", Content: "code"}); err != nil {
+		t.Fatal(err)
+	}
+	var msg Message
+	_ = admin.SetReadDeadline(time.Now().Add(time.Second))
+	if err := admin.ReadJSON(&msg); err != nil || msg.Content != "code" {
+		t.Errorf("admin received %+v, %v", msg, err)
+	}
+	_ = user.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if err := user.ReadJSON(&msg); err == nil {
+		t.Errorf("non admin received %+v", msg)
+	}
+
+	header := http.Header{"Origin": {"https://evil.example.com"}}
+	if _, _, err := websocket.DefaultDialer.Dial(strings.Replace(srv.URL, "http", "ws", 1)+"/ws", header); err == nil {
+		t.Error("cross origin web socket is accepted")
+	}
+}
+
+func TestWsCheck(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	period := wsCheckPeriod
+	wsCheckPeriod = 50 * time.Millisecond
+	defer func() { wsCheckPeriod = period }()
+
+	user := &model.User{Username: "ws", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteUserById(user.ID) })
+	sessions := make(map[string]string)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), conf.UserKey, user)
+		ctx = context.WithValue(ctx, conf.SessionKey, sessions[c.Query("session")])
+		c.Request = c.Request.WithContext(ctx)
+		WsInstance.Handle(c)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	dial := func() (*websocket.Conn, string) {
+		s := &model.Session{UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+		if _, err := op.CreateSession(s); err != nil {
+			t.Fatal(err)
+		}
+		sessions[s.ID] = s.ID
+		conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(srv.URL, "http", "ws", 1)+"/ws?session="+s.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, s.ID
+	}
+	closed := func(conn *websocket.Conn) bool {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err := conn.ReadMessage()
+		return websocket.IsCloseError(err, websocket.ClosePolicyViolation)
+	}
+
+	loggedIn, _ := dial()
+	defer loggedIn.Close()
+	loggedOut, id := dial()
+	defer loggedOut.Close()
+	if err := op.DeleteSessionById(id); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(loggedOut) {
+		t.Error("web socket of a revoked session is kept")
+	}
+	_ = loggedIn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := loggedIn.ReadMessage(); websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Error("web socket of a valid session is closed")
+	}
+
+	disabled, _ := dial()
+	defer disabled.Close()
+	user.Disabled = true
+	if err := op.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(disabled) {
+		t.Error("web socket of a disabled user is kept")
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/diskcache"
+	"github.com/OpenListTeam/tache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var stateNames = map[tache.State]string{
+	tache.StatePending:      "pending",
+	tache.StateRunning:      "running",
+	tache.StateSucceeded:    "succeeded",
+	tache.StateCanceling:    "canceling",
+	tache.StateCanceled:     "canceled",
+	tache.StateErrored:      "errored",
+	tache.StateFailing:      "failing",
+	tache.StateFailed:       "failed",
+	tache.StateWaitingRetry: "waiting_retry",
+	tache.StateBeforeRetry:  "before_retry",
+}
+
+var (
+	taskManagersMu sync.RWMutex
+	taskManagers   = make(map[string]func() []tache.State)
+)
+
+// RegisterTaskManager reports the tasks of the manager under name
+func RegisterTaskManager[T tache.Task](name string, m *tache.Manager[T]) {
+	taskManagersMu.Lock()
+	defer taskManagersMu.Unlock()
+	taskManagers[name] = func() []tache.State {
+		tasks := m.GetAll()
+		states := make([]tache.State, len(tasks))
+		for i, t := range tasks {
+			states[i] = t.GetState()
+		}
+		return states
+	}
+}
+
+var tasksDesc = prometheus.NewDesc(namespace+"_tasks", "Tasks of the task managers by state.", []string{"manager", "state"}, nil)
+
+type taskCollector struct{}
+
+func (taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tasksDesc
+}
+
+func (taskCollector) Collect(ch chan<- prometheus.Metric) {
+	taskManagersMu.RLock()
+	defer taskManagersMu.RUnlock()
+	for name, states := range taskManagers {
+		counts := make(map[tache.State]int)
+		for _, s := range states() {
+			counts[s]++
+		}
+		for s, stateName := range stateNames {
+			ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(counts[s]), name, stateName)
+		}
+	}
+}
+
+var (
+	diskCacheSize      = prometheus.NewDesc(namespace+"_disk_cache_size_bytes", "Size of the blocks in the disk cache.", nil, nil)
+	diskCacheMaxSize   = prometheus.NewDesc(namespace+"_disk_cache_max_size_bytes", "Size limit of the disk cache.", nil, nil)
+	diskCacheBlocks    = prometheus.NewDesc(namespace+"_disk_cache_blocks", "Blocks in the disk cache.", nil, nil)
+	diskCacheRequests  = prometheus.NewDesc(namespace+"_disk_cache_requests_total", "Block reads of the disk cache by result.", []string{"result"}, nil)
+	diskCacheReadBytes = prometheus.NewDesc(namespace+"_disk_cache_read_bytes_total", "Bytes read from the disk cache or fetched from the storages.", []string{"result"}, nil)
+)
+
+type diskCacheCollector struct{}
+
+func (diskCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- diskCacheSize
+	ch <- diskCacheMaxSize
+	ch <- diskCacheBlocks
+	ch <- diskCacheRequests
+	ch <- diskCacheReadBytes
+}
+
+func (diskCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c := diskcache.Default()
+	if c == nil {
+		return
+	}
+	s := c.Stats()
+	ch <- prometheus.MustNewConstMetric(diskCacheSize, prometheus.GaugeValue, float64(s.Size))
+	ch <- prometheus.MustNewConstMetric(diskCacheMaxSize, prometheus.GaugeValue, float64(s.MaxSize))
+	ch <- prometheus.MustNewConstMetric(diskCacheBlocks, prometheus.GaugeValue, float64(s.Blocks))
+	ch <- prometheus.MustNewConstMetric(diskCacheRequests, prometheus.CounterValue, float64(s.Hits), "hit")
+	ch <- prometheus.MustNewConstMetric(diskCacheRequests, prometheus.CounterValue, float64(s.Misses), "miss")
+	ch <- prometheus.MustNewConstMetric(diskCacheReadBytes, prometheus.CounterValue, float64(s.HitBytes), "hit")
+	ch <- prometheus.MustNewConstMetric(diskCacheReadBytes, prometheus.CounterValue, float64(s.MissBytes), "miss")
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "openlist"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	driverCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "driver_calls_total",
+		Help:      "Calls of the storage drivers by method and result.",
+	}, []string{"storage", "driver", "method", "result"})
+	driverCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "driver_call_duration_seconds",
+		Help:      "Latency of the calls of the storage drivers.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"storage", "driver", "method"})
+	servedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "served_bytes_total",
+		Help:      "Bytes sent to the clients by protocol.",
+	}, []string{"protocol"})
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Lookups of the in-memory caches by result.",
+	}, []string{"cache", "result"})
+	limiterWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "limiter_wait_seconds",
+		Help:      "Time spent waiting for the stream rate limiters.",
+		Buckets:   []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"limiter"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		driverCalls,
+		driverCallDuration,
+		servedBytes,
+		cacheRequests,
+		limiterWait,
+		taskCollector{},
+		diskCacheCollector{},
+	)
+}
+
+// DriverCall records a call of method of the driver of storage started at start
+func DriverCall(storage *model.Storage, method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	driverCalls.WithLabelValues(storage.MountPath, storage.Driver, method, result).Inc()
+	driverCallDuration.WithLabelValues(storage.MountPath, storage.Driver, method).Observe(time.Since(start).Seconds())
+}
+
+// ServedBytes records n bytes sent to a client by protocol
+func ServedBytes(protocol string, n int64) {
+	if n > 0 {
+		servedBytes.WithLabelValues(protocol).Add(float64(n))
+	}
+}
+
+// CacheLookup records a hit or miss of the cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// LimiterWait records the time spent waiting for the limiter since start
+func LimiterWait(limiter string, start time.Time) {
+	limiterWait.WithLabelValues(limiter).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus text format,
+// a non-empty token is required as a bearer token or the token query
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.URL.Query().Get("token")
+		if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			got = auth
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
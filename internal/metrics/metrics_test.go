@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestHandler(t *testing.T) {
+	storage := &model.Storage{MountPath: "/local", Driver: "Local"}
+	DriverCall(storage, "list", time.Now(), nil)
+	DriverCall(storage, "list", time.Now(), errors.New("failed"))
+	h := Handler("secret")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("request without token got %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("request with token got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`openlist_driver_calls_total{driver="Local",method="list",result="error",storage="/local"} 1`,
+		`openlist_driver_calls_total{driver="Local",method="list",result="ok",storage="/local"} 1`,
+		`openlist_driver_call_duration_seconds_count{driver="Local",method="list",storage="/local"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DirectLink is a revocable download link of a single path,
+// it is passed to /d and /p by the `link` query instead of `sign`
+type DirectLink struct {
+	ID           string     `json:"id" gorm:"type:char(16);primaryKey"`
+	Path         string     `json:"path" gorm:"type:text"`
+	CreatorId    uint       `json:"-" gorm:"index"`
+	Expires      *time.Time `json:"expires"`
+	MaxDownloads int        `json:"max_downloads"`
+	Downloads    int        `json:"downloads"`
+	// comma separated ip or cidr, empty means any
+	AllowIPs string `json:"allow_ips"`
+	// the user agent of the client must contain it, empty means any
+	UserAgent string `json:"user_agent"`
+	// comma separated hosts allowed in referer, empty means any
+	Referers     string     `json:"referers"`
+	Disabled     bool       `json:"disabled"`
+	Remark       string     `json:"remark"`
+	LastAccessed *time.Time `json:"last_accessed"`
+	LastIP       string     `json:"last_ip"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// DirectLinkEditableColumns are the columns set by the creator of a link
+var DirectLinkEditableColumns = []string{"path", "expires", "max_downloads", "allow_ips", "user_agent", "referers", "disabled", "remark"}
+
+// Valid checks the link is usable, the downloads limit is enforced again when a download is counted
+func (l *DirectLink) Valid() bool {
+	if l.MaxDownloads > 0 && l.Downloads >= l.MaxDownloads {
+		return false
+	}
+	return l.Active()
+}
+
+// Active checks the link is neither disabled nor expired, whatever its downloads
+func (l *DirectLink) Active() bool {
+	if l.Disabled {
+		return false
+	}
+	return l.Expires == nil || l.Expires.IsZero() || !l.Expires.Before(time.Now())
+}
+
+// Validate checks the ip binding is well-formed
+func (l *DirectLink) Validate() error {
+	for _, s := range strings.Split(l.AllowIPs, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return errors.Errorf("invalid cidr: %s", s)
+			}
+		} else if net.ParseIP(s) == nil {
+			return errors.Errorf("invalid ip: %s", s)
+		}
+	}
+	return nil
+}
+
+// Allow checks the binding of the link against the client
+func (l *DirectLink) Allow(ip, userAgent, referer string) error {
+	if l.AllowIPs != "" && !matchIP(l.AllowIPs, ip) {
+		return errors.Errorf("ip %s is not allowed", ip)
+	}
+	if l.UserAgent != "" && !strings.Contains(userAgent, l.UserAgent) {
+		return errors.New("user agent is not allowed")
+	}
+	if l.Referers != "" {
+		host := ""
+		if u, err := url.Parse(referer); err == nil {
+			host = u.Hostname()
+		}
+		if !matchHost(l.Referers, host) {
+			return errors.New("referer is not allowed")
+		}
+	}
+	return nil
+}
+
+func matchIP(allowed, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, s := range strings.Split(allowed, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			if _, ipNet, err := net.ParseCIDR(s); err == nil && ipNet.Contains(addr) {
+				return true
+			}
+		} else if a := net.ParseIP(s); a != nil && a.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost supports exact hosts and `*.example.com` wildcards
+func matchHost(allowed, host string) bool {
+	if host == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	for _, s := range strings.Split(allowed, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		if strings.HasPrefix(s, "*.") {
+			if strings.HasSuffix(host, s[1:]) {
+				return true
+			}
+		} else if s == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package model
+
+const (
+	FsChangePut        = "put"
+	FsChangeMkdir      = "mkdir"
+	FsChangeRemove     = "remove"
+	FsChangeRename     = "rename"
+	FsChangeMove       = "move"
+	FsChangeCopy       = "copy"
+	FsChangeDecompress = "decompress"
+)
+
+// FsChange describes a successful write made through the op layer, all paths are full paths
+type FsChange struct {
+	Op string `json:"op"`
+	// Path of the changed object, or the source of rename, move and copy
+	Path string `json:"path"`
+	// DstPath of the resulting object of rename, move, copy and decompress
+	DstPath string `json:"dst_path,omitempty"`
+	IsDir   bool   `json:"is_dir"`
+}
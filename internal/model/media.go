@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+const (
+	MediaImage = "image"
+	MediaAudio = "audio"
+	MediaVideo = "video"
+)
+
+// MediaInfo is the metadata extracted from an image, audio or video file,
+// it belongs to the version of the file with the same size and modified time
+type MediaInfo struct {
+	ID       uint      `json:"-" gorm:"primaryKey"`
+	Parent   string    `json:"-" gorm:"index"`
+	Name     string    `json:"-"`
+	Size     int64     `json:"-"`
+	Modified time.Time `json:"-"`
+	Kind     string    `json:"kind" gorm:"index"`
+	Width    int       `json:"width,omitempty"`
+	Height   int       `json:"height,omitempty"`
+	Duration float64   `json:"duration,omitempty"` // seconds
+	// photos and the videos taken by cameras
+	Make      string     `json:"make,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	Lens      string     `json:"lens,omitempty"`
+	TakenAt   *time.Time `json:"taken_at,omitempty" gorm:"index"`
+	Latitude  *float64   `json:"latitude,omitempty"`
+	Longitude *float64   `json:"longitude,omitempty"`
+	// music
+	Title  string `json:"title,omitempty"`
+	Artist string `json:"artist,omitempty" gorm:"index"`
+	Album  string `json:"album,omitempty" gorm:"index"`
+	Genre  string `json:"genre,omitempty"`
+	Year   int    `json:"year,omitempty"`
+	Track  int    `json:"track,omitempty"`
+	// streams of audio and video files
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+}
+
+// MediaFilter filters on the extracted metadata, the files without it are left out
+type MediaFilter struct {
+	Kind        string     `json:"kind"`   // image, audio or video
+	Camera      string     `json:"camera"` // matches the make or model
+	Artist      string     `json:"artist"`
+	Album       string     `json:"album"`
+	Genre       string     `json:"genre"`
+	TakenAfter  *time.Time `json:"taken_after"`
+	TakenBefore *time.Time `json:"taken_before"`
+	DurationMin float64    `json:"duration_min"`
+	DurationMax float64    `json:"duration_max"`
+	HasLocation bool       `json:"has_location"`
+}
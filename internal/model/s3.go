@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// S3Upload is an in-progress multipart upload of the s3 gateway,
+// its parts are spooled to the temp dir
+type S3Upload struct {
+	ID        string    `json:"id" gorm:"type:char(32);primaryKey"`
+	Bucket    string    `json:"bucket" gorm:"index"`
+	Key       string    `json:"key" gorm:"type:text"`
+	UserId    uint      `json:"user_id"`
+	MetaRaw   string    `json:"-" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type S3UploadPart struct {
+	UploadID   string    `json:"upload_id" gorm:"type:char(32);primaryKey"`
+	PartNumber int       `json:"part_number" gorm:"primaryKey;autoIncrement:false"`
+	Size       int64     `json:"size"`
+	ETag       string    `json:"etag"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// MaxPathKeyLength is the longest path a path keyed table can hold,
+// 768 utf8mb4 characters take the 3072 bytes MySQL allows for an index
+const MaxPathKeyLength = 768
+
+// S3ObjectMeta keeps the Content-Type and user metadata of objects put by the s3 gateway
+type S3ObjectMeta struct {
+	Path      string    `json:"path" gorm:"type:varchar(768);primaryKey"`
+	MetaRaw   string    `json:"-" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// S3AccessKey is a credential of the s3 gateway owned by a user
+type S3AccessKey struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserId          uint      `json:"-" gorm:"index"`
+	Title           string    `json:"title"`
+	AccessKeyId     string    `json:"access_key_id" gorm:"unique"`
+	SecretAccessKey string    `json:"-"`
+	AddedTime       time.Time `json:"added_time"`
+	LastUsedTime    time.Time `json:"last_used_time"`
+}
@@ -23,6 +23,10 @@ type SearchReq struct {
     OrderBy string `json:"order_by"`
     OrderDirection string `json:"order_direction"`
     Distinct bool `json:"distinct"`
+    // match keywords against extracted file contents instead of names
+    Content bool `json:"content"`
+    // filter on the metadata of media files, order_by may be one of its fields as well
+    Media *MediaFilter `json:"media"`
     PageReq
 }
 
@@ -31,6 +35,8 @@ type SearchNode struct {
 	Name   string `json:"name"`
 	IsDir  bool   `json:"is_dir"`
 	Size   int64  `json:"size"`
+	// Snippet of the matched content, only set by content search
+	Snippet string `json:"snippet,omitempty" gorm:"-"`
 }
 
 func (p *SearchReq) Validate() error {
@@ -46,3 +52,20 @@ func (p *SearchReq) Validate() error {
 func (s *SearchNode) Type() string {
 	return "SearchNode"
 }
+
+const (
+	IndexEventUpsert = "upsert"
+	IndexEventDelete = "delete"
+)
+
+// IndexEvent is a pending change of the search index, persisted so it survives restarts
+type IndexEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Path      string    `json:"path" gorm:"index"`
+	Type      string    `json:"type"`
+	Recursive bool      `json:"recursive"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Session is a login of a user, the access tokens issued for it are valid until it is revoked or expired
+type Session struct {
+	ID     string `json:"id" gorm:"type:char(32);primaryKey"`
+	UserId uint   `json:"-" gorm:"index"`
+	// RefreshToken is the sha256 of the current refresh token, it changes every refresh
+	RefreshToken string `json:"-"`
+	// PrevRefreshToken is the sha256 of the refresh token replaced at RotatedAt,
+	// it is still accepted for a short while for the clients refreshing concurrently
+	PrevRefreshToken string    `json:"-"`
+	RotatedAt        time.Time `json:"-"`
+	Device           string    `json:"device"`
+	IP               string    `json:"ip"`
+	UserAgent        string    `json:"user_agent" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastSeen         time.Time `json:"last_seen"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func (s *Session) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// StorageStatusHistory records a storage going down or coming back
+type StorageStatusHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	StorageID uint      `json:"storage_id" gorm:"index"`
+	MountPath string    `json:"mount_path"`
+	Up        bool      `json:"up"`
+	Error     string    `json:"error" gorm:"type:text"`
+	Time      time.Time `json:"time" gorm:"index"`
+}
+
+// StorageHealth is what the health monitor knows of a storage since the start
+type StorageHealth struct {
+	UpSince     *time.Time `json:"up_since"`
+	Uptime      int64      `json:"uptime"` // seconds
+	LastCheck   *time.Time `json:"last_check"`
+	NextCheck   *time.Time `json:"next_check"`
+	Failures    int        `json:"failures"` // failed probes in a row
+	LastError   string     `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+}
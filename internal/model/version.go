@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// methods of driver.Other implemented by the storages keeping file versions,
+// the object is the dir of the file and the data is FileVersionArgs
+const (
+	OtherVersions       = "versions"
+	OtherRestoreVersion = "restore_version"
+	OtherVersionLink    = "version_link"
+)
+
+type FileVersionArgs struct {
+	Name    string   // name of the file in the dir
+	Version string   // name of the version to restore or link
+	Link    LinkArgs // args of the link of the version
+}
+
+// FileVersion is a previous revision of a file
+type FileVersion struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"` // when the revision was replaced
+}
+
+// VersionLink is the result of OtherVersionLink, the versions are only reachable through their file
+// so that the metas of the file apply to them
+type VersionLink struct {
+	Link *Link
+	Obj  Obj
+}
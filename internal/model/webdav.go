@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// WebdavProps keeps the dead properties set by PROPPATCH on a resource of the webdav server
+type WebdavProps struct {
+	Path      string    `json:"path" gorm:"type:varchar(768);primaryKey"`
+	PropsRaw  string    `json:"-" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,43 @@
+package net
+
+import (
+	"context"
+	stdnet "net"
+	"net/http"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+)
+
+// ServeLoopback exposes the content on a loopback listener until stop is called or ctx is done,
+// so that external programs like ffmpeg can seek in it with range requests.
+// The random path keeps other local processes from reading it.
+func ServeLoopback(ctx context.Context, name string, modTime time.Time, size int64, rr model.RangeReaderIF) (url string, stop func(), err error) {
+	l, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	token := random.String(16)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+token {
+			http.NotFound(w, r)
+			return
+		}
+		_ = ServeHTTP(w, r.WithContext(ctx), name, modTime, size, &model.RangeReadCloser{RangeReader: rr})
+	})}
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	done := make(chan struct{})
+	go func() {
+		// the programs reading it fail fast once it is gone
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = srv.Close()
+	}()
+	return "http://" + l.Addr().String() + "/" + token, func() { close(done) }, nil
+}
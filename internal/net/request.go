@@ -14,12 +14,15 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/rclone/rclone/lib/mmap"
 
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/aws/aws-sdk-go/aws/awsutil"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DefaultDownloadPartSize is the default range of bytes to get at a time when
@@ -93,7 +96,24 @@ func (d Downloader) Download(ctx context.Context, p *HttpRequestParams) (readClo
 		impl.cfg.HttpClient = DefaultHttpRequestFunc
 	}
 
-	return impl.download()
+	ctx, span := tracing.Start(ctx, "net.Download",
+		attribute.Int64("range.start", finalP.Range.Start),
+		attribute.Int64("range.length", finalP.Range.Length),
+		attribute.Int("concurrency", impl.cfg.Concurrency),
+		attribute.Int("part_size", impl.cfg.PartSize),
+	)
+	impl.ctx = ctx
+	rc, err := impl.download()
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	// the chunks are still downloaded after returning, the span lasts until the reader is closed
+	return utils.NewReadCloser(rc, func() error {
+		err := rc.Close()
+		span.End()
+		return err
+	}), nil
 }
 
 // downloader is the implementation structure used internally by Downloader.
@@ -350,16 +370,28 @@ func (d *downloader) downloadPart() {
 }
 
 // downloadChunk downloads the chunk
-func (d *downloader) downloadChunk(ch *chunk) error {
+func (d *downloader) downloadChunk(ch *chunk) (err error) {
 	log.Debugf("start chunk_%d, %+v", ch.id, ch)
+	ctx, span := tracing.Start(d.ctx, "net.DownloadChunk",
+		attribute.Int("chunk.id", ch.id),
+		attribute.Int64("chunk.start", ch.start),
+		attribute.Int64("chunk.size", ch.size),
+	)
+	defer func() {
+		if err == errCancelConcurrency {
+			// the chunk is handed over to the remaining workers
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
 	params := d.getParamsFromChunk(ch)
 	var n int64
-	var err error
 	for retry := 0; retry <= d.cfg.PartBodyMaxRetries; retry++ {
 		if d.getErr() != nil {
 			return nil
 		}
-		n, err = d.tryDownloadChunk(params, ch)
+		n, err = d.tryDownloadChunk(ctx, params, ch)
 		if err == nil {
 			d.incrWritten(n)
 			log.Debugf("chunk_%d downloaded", ch.id)
@@ -387,6 +419,7 @@ func (d *downloader) downloadChunk(ch *chunk) error {
 			}
 			log.Warnf("err chunk_%d, object part download error %s, retrying attempt %d. %v",
 				ch.id, params.URL, retry, err)
+			span.AddEvent("retry", trace.WithAttributes(attribute.String("error", err.Error())))
 		} else if err == errInfiniteRetry {
 			retry--
 			continue
@@ -401,8 +434,8 @@ func (d *downloader) downloadChunk(ch *chunk) error {
 var errCancelConcurrency = errors.New("cancel concurrency")
 var errInfiniteRetry = errors.New("infinite retry")
 
-func (d *downloader) tryDownloadChunk(params *HttpRequestParams, ch *chunk) (int64, error) {
-	resp, err := d.cfg.HttpClient(d.ctx, params)
+func (d *downloader) tryDownloadChunk(ctx context.Context, params *HttpRequestParams, ch *chunk) (int64, error) {
+	resp, err := d.cfg.HttpClient(ctx, params)
 	if err != nil {
 		statusCode, ok := errs.UnwrapOrSelf(err).(HttpStatusCodeError)
 		if !ok {
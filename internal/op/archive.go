@@ -16,6 +16,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	gocache "github.com/OpenListTeam/go-cache"
@@ -499,6 +500,111 @@ func InternalExtract(ctx context.Context, storage driver.Driver, path string, ar
 	return &streamWithParent{rc: rc, parents: ss}, size, nil
 }
 
+type archiveExtent struct {
+	// located is false if the archive tool can't locate the inner files
+	located      bool
+	offset, size int64
+	// the archive when it's located, the extent is stale once the archive changes
+	archiveSize int64
+	modified    time.Time
+}
+
+var (
+	archiveExtentCache = gocache.NewMemCache(gocache.WithShards[*archiveExtent](64))
+	archiveExtentG     singleflight.Group[*archiveExtent]
+)
+
+// locateExtent finds where the inner file is stored in the archive, the archive is parsed once
+// and the extent is cached until the archive changes
+func locateExtent(ctx context.Context, storage driver.Driver, path string, args model.ArchiveInnerArgs, archive model.Obj) (*archiveExtent, error) {
+	key := stdpath.Join(Key(storage, path), args.InnerPath)
+	if e, ok := archiveExtentCache.Get(key); ok && e.archiveSize == archive.GetSize() && e.modified.Equal(archive.ModTime()) {
+		return e, nil
+	}
+	e, err, _ := archiveExtentG.Do(key, func() (*archiveExtent, error) {
+		obj, t, ss, err := GetArchiveToolAndStream(ctx, storage, path, args.LinkArgs)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			for _, s := range ss {
+				_ = s.Close()
+			}
+		}()
+		e := &archiveExtent{archiveSize: obj.GetSize(), modified: obj.ModTime()}
+		if locator, ok := t.(tool.ExtentLocator); ok {
+			if e.offset, e.size, err = locator.LocateExtent(ss, args); err != nil {
+				return nil, err
+			}
+			e.located = true
+		}
+		archiveExtentCache.Set(key, e, gocache.WithEx[*archiveExtent](time.Hour))
+		return e, nil
+	})
+	return e, err
+}
+
+// InternalExtractRange reads a range of an inner file, the range is read from the archive directly
+// if the archive tool can locate the inner file, otherwise the data before it is decompressed and discarded
+func InternalExtractRange(ctx context.Context, storage driver.Driver, path string, args model.ArchiveInnerArgs, httpRange http_range.Range) (io.ReadCloser, error) {
+	path = utils.FixAndCleanPath(path)
+	link, archive, err := Link(ctx, storage, path, args.LinkArgs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get [%s] link", path)
+	}
+	e, err := locateExtent(ctx, storage, path, args, archive)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	if e.located {
+		if httpRange.Length < 0 || httpRange.Start+httpRange.Length > e.size {
+			httpRange.Length = e.size - httpRange.Start
+		}
+		rr, err := stream.GetRangeReaderFromLink(archive.GetSize(), link)
+		if err != nil {
+			_ = link.Close()
+			return nil, err
+		}
+		rc, err := rr.RangeRead(ctx, http_range.Range{Start: e.offset + httpRange.Start, Length: httpRange.Length})
+		if err != nil {
+			_ = link.Close()
+			return nil, err
+		}
+		return utils.NewReadCloser(rc, func() error {
+			return stderrors.Join(rc.Close(), link.Close())
+		}), nil
+	}
+	_ = link.Close()
+	_, t, ss, err := GetArchiveToolAndStream(ctx, storage, path, args.LinkArgs)
+	if err != nil {
+		return nil, err
+	}
+	closeAll := func(err error) error {
+		for _, s := range ss {
+			err = stderrors.Join(err, s.Close())
+		}
+		return err
+	}
+	rc, size, err := t.Extract(ss, args)
+	if err != nil {
+		return nil, closeAll(err)
+	}
+	if httpRange.Length < 0 || httpRange.Start+httpRange.Length > size {
+		httpRange.Length = size - httpRange.Start
+	}
+	if _, err = utils.CopyWithBufferN(io.Discard, rc, httpRange.Start); err != nil {
+		return nil, closeAll(stderrors.Join(err, rc.Close()))
+	}
+	return &streamWithParent{
+		rc: utils.ReadCloser{
+			Reader: io.LimitReader(rc, httpRange.Length),
+			Closer: rc,
+		},
+		parents: ss,
+	}, nil
+}
+
 func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string, args model.ArchiveDecompressArgs, lazyCache ...bool) error {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
@@ -535,6 +641,15 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		if len(newObjs) > 0 {
+			for _, newObj := range newObjs {
+				handleFsChange(ctx, storage, model.FsChangeDecompress, srcPath, stdpath.Join(dstDirPath, newObj.GetName()), newObj.IsDir())
+			}
+		} else {
+			handleFsChange(ctx, storage, model.FsChangeDecompress, srcPath, dstDirPath, true)
+		}
+	}
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		onlyList := false
 		targetPath := dstDirPath
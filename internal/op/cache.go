@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/cache"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 )
 
@@ -119,7 +120,9 @@ func (cm *CacheManager) SetUser(username string, user *model.User) {
 
 // cached user data
 func (cm *CacheManager) GetUser(username string) (*model.User, bool) {
-	return cm.userCache.Get(username)
+	user, exists := cm.userCache.Get(username)
+	metrics.CacheLookup("user", exists)
+	return user, exists
 }
 
 // remove user data from cache
@@ -136,9 +139,11 @@ func (cm *CacheManager) SetSetting(key string, setting *model.SettingItem) {
 func (cm *CacheManager) GetSetting(key string) (*model.SettingItem, bool) {
 	if data, exists := cm.settingCache.Get(key); exists {
 		if setting, ok := data.(*model.SettingItem); ok {
+			metrics.CacheLookup("setting", true)
 			return setting, true
 		}
 	}
+	metrics.CacheLookup("setting", false)
 	return nil, false
 }
 
@@ -151,9 +156,11 @@ func (cm *CacheManager) SetSettingGroup(key string, settings []model.SettingItem
 func (cm *CacheManager) GetSettingGroup(key string) ([]model.SettingItem, bool) {
 	if data, exists := cm.settingCache.Get(key); exists {
 		if settings, ok := data.([]model.SettingItem); ok {
+			metrics.CacheLookup("setting", true)
 			return settings, true
 		}
 	}
+	metrics.CacheLookup("setting", false)
 	return nil, false
 }
 
@@ -166,7 +173,9 @@ func (cm *CacheManager) SetStorageDetails(storage driver.Driver, details *model.
 }
 
 func (cm *CacheManager) GetStorageDetails(storage driver.Driver) (*model.StorageDetails, bool) {
-	return cm.detailCache.Get(storage.GetStorage().MountPath)
+	details, exists := cm.detailCache.Get(storage.GetStorage().MountPath)
+	metrics.CacheLookup("details", exists)
+	return details, exists
 }
 
 func (cm *CacheManager) InvalidateStorageDetails(storage driver.Driver) {
@@ -0,0 +1,92 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var directLinkCache = cache.NewMemCache(cache.WithShards[*model.DirectLink](8))
+var directLinkG singleflight.Group[*model.DirectLink]
+
+func GetDirectLinkById(id string, refresh ...bool) (*model.DirectLink, error) {
+	if !utils.IsBool(refresh...) {
+		if l, ok := directLinkCache.Get(id); ok {
+			log.Debugf("use cache when get direct link %s", id)
+			return l, nil
+		}
+	}
+	l, err, _ := directLinkG.Do(id, func() (*model.DirectLink, error) {
+		l, err := db.GetDirectLinkById(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get direct link [%s]", id)
+		}
+		directLinkCache.Set(id, l)
+		return l, nil
+	})
+	return l, err
+}
+
+func GetDirectLinks(pageIndex, pageSize int) ([]model.DirectLink, int64, error) {
+	return db.GetDirectLinks(pageIndex, pageSize)
+}
+
+func GetDirectLinksByCreatorId(userId uint, pageIndex, pageSize int) ([]model.DirectLink, int64, error) {
+	return db.GetDirectLinksByCreatorId(userId, pageIndex, pageSize)
+}
+
+func CreateDirectLink(l *model.DirectLink) error {
+	l.Path = utils.FixAndCleanPath(l.Path)
+	return db.CreateDirectLink(l)
+}
+
+func UpdateDirectLink(l *model.DirectLink, columns ...string) error {
+	l.Path = utils.FixAndCleanPath(l.Path)
+	directLinkCache.Del(l.ID)
+	return db.UpdateDirectLink(l, columns...)
+}
+
+// directLinkCountWindow is how long the requests of a client through a link are taken as
+// the same download, whatever their ranges, once one of them has been counted
+const directLinkCountWindow = time.Hour
+
+var directLinkCounted = cache.NewMemCache(cache.WithShards[struct{}](8))
+var directLinkCountG singleflight.Group[bool]
+
+// CountDirectLinkDownload counts a download of the client once in the window, and returns false
+// if the link has reached its downloads limit before the client was counted
+func CountDirectLinkDownload(id, ip string) (bool, error) {
+	key := id + "/" + ip
+	if _, ok := directLinkCounted.Get(key); ok {
+		return true, nil
+	}
+	counted, err, _ := directLinkCountG.Do(key, func() (bool, error) {
+		if _, ok := directLinkCounted.Get(key); ok {
+			return true, nil
+		}
+		directLinkCache.Del(id)
+		counted, err := db.CountDirectLinkDownload(id, ip)
+		if counted {
+			directLinkCounted.Set(key, struct{}{}, cache.WithEx[struct{}](directLinkCountWindow))
+		}
+		return counted, err
+	})
+	return counted, err
+}
+
+func DeleteDirectLink(id string) error {
+	directLinkCache.Del(id)
+	return db.DeleteDirectLinkById(id)
+}
+
+func DeleteDirectLinksByCreatorId(creatorId uint) error {
+	// links are not cached by creator, drop them all
+	directLinkCache.Clear()
+	return db.DeleteDirectLinksByCreatorId(creatorId)
+}
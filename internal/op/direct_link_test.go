@@ -0,0 +1,79 @@
+package op_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestDirectLinkDownloads(t *testing.T) {
+	l := &model.DirectLink{Path: "/a/b.txt", MaxDownloads: 5}
+	if err := op.CreateDirectLink(l); err != nil {
+		t.Fatal(err)
+	}
+	var counted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := op.CountDirectLinkDownload(l.ID, fmt.Sprintf("10.0.0.%d", i))
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				counted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if counted.Load() != 5 {
+		t.Fatalf("%d downloads are counted, want 5", counted.Load())
+	}
+	// editing the link with a stale copy keeps the counter
+	l.Remark = "edited"
+	if err := op.UpdateDirectLink(l); err != nil {
+		t.Fatal(err)
+	}
+	got, err := op.GetDirectLinkById(l.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Downloads != 5 || got.Remark != "edited" || got.Valid() {
+		t.Fatalf("unexpected link after editing: %+v", got)
+	}
+}
+
+func TestDirectLinkDownloadsOfClient(t *testing.T) {
+	l := &model.DirectLink{Path: "/a/b.txt", MaxDownloads: 2}
+	if err := op.CreateDirectLink(l); err != nil {
+		t.Fatal(err)
+	}
+	// the range requests of a download are counted once
+	for i := 0; i < 3; i++ {
+		if ok, err := op.CountDirectLinkDownload(l.ID, "10.0.1.1"); !ok || err != nil {
+			t.Fatalf("request %d of the first client is refused: %v", i, err)
+		}
+	}
+	if ok, err := op.CountDirectLinkDownload(l.ID, "10.0.1.2"); !ok || err != nil {
+		t.Fatalf("the second client is refused: %v", err)
+	}
+	if ok, _ := op.CountDirectLinkDownload(l.ID, "10.0.1.3"); ok {
+		t.Fatal("the third client is counted beyond the limit")
+	}
+	// the clients counted before the limit was reached finish their downloads
+	if ok, _ := op.CountDirectLinkDownload(l.ID, "10.0.1.1"); !ok {
+		t.Fatal("the first client is cut off")
+	}
+	got, err := op.GetDirectLinkById(l.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Downloads != 2 {
+		t.Errorf("%d downloads are counted, want 2", got.Downloads)
+	}
+}
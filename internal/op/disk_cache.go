@@ -0,0 +1,30 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/diskcache"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+// useDiskCache makes the link read through the disk cache, the blocks are kept for the key
+// until the size, modified time or hash of the file changes
+func useDiskCache(key string, file model.Obj, link *model.Link) {
+	c := diskcache.Default()
+	if c == nil {
+		return
+	}
+	size := link.ContentLength
+	if size <= 0 {
+		size = file.GetSize()
+	}
+	if size <= 0 {
+		return
+	}
+	rr, err := stream.GetRangeReaderFromLink(size, link)
+	if err != nil {
+		return
+	}
+	link.RangeReader = c.RangeReader(key, size, file.ModTime(), file.GetHash().String(), rr)
+	// the blocks are fetched one request at a time
+	link.Concurrency, link.PartSize = 0, 0
+}
@@ -9,19 +9,24 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/time/rate"
 )
 
 var listG singleflight.Group[[]model.Obj]
 
 // List files in storage, not contains virtual file
-func List(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) ([]model.Obj, error) {
+func List(ctx context.Context, storage driver.Driver, path string, args model.ListArgs) (_ []model.Obj, err error) {
+	ctx, span := tracing.Start(ctx, "op.List", traceAttrs(storage, path)...)
+	defer func() { tracing.End(span, err) }()
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
 	}
@@ -29,7 +34,10 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	log.Debugf("op.List %s", path)
 	key := Key(storage, path)
 	if !args.Refresh {
-		if dirCache, exists := Cache.dirCache.Get(key); exists {
+		dirCache, exists := Cache.dirCache.Get(key)
+		metrics.CacheLookup("dir", exists)
+		span.SetAttributes(attribute.Bool("cache.hit", exists))
+		if exists {
 			log.Debugf("use cache when list %s", path)
 			return dirCache.GetSortedObjects(storage), nil
 		}
@@ -45,7 +53,9 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	}
 
 	objs, err, _ := listG.Do(key, func() ([]model.Obj, error) {
+		start := time.Now()
 		files, err := storage.List(ctx, dir, args)
+		metrics.DriverCall(storage.GetStorage(), "list", start, err)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to list objs")
 		}
@@ -84,7 +94,9 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 }
 
 // Get object from list of files
-func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, error) {
+func Get(ctx context.Context, storage driver.Driver, path string) (_ model.Obj, err error) {
+	ctx, span := tracing.Start(ctx, "op.Get", traceAttrs(storage, path)...)
+	defer func() { tracing.End(span, err) }()
 	path = utils.FixAndCleanPath(path)
 	log.Debugf("op.Get %s", path)
 
@@ -165,7 +177,9 @@ func GetUnwrap(ctx context.Context, storage driver.Driver, path string) (model.O
 var linkG = singleflight.Group[*objWithLink]{}
 
 // Link get link, if is an url. should have an expiry time
-func Link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+func Link(ctx context.Context, storage driver.Driver, path string, args model.LinkArgs) (_ *model.Link, _ model.Obj, err error) {
+	ctx, span := tracing.Start(ctx, "op.Link", traceAttrs(storage, path)...)
+	defer func() { tracing.End(span, err) }()
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return nil, nil, errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
 	}
@@ -185,9 +199,13 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 	if ol, exists := Cache.linkCache.GetType(key, typeKey); exists {
 		if ol.link.Expiration != nil ||
 			ol.link.SyncClosers.AcquireReference() || !ol.link.RequireReference {
+			metrics.CacheLookup("link", true)
+			span.SetAttributes(attribute.Bool("cache.hit", true))
 			return ol.link, ol.obj, nil
 		}
 	}
+	metrics.CacheLookup("link", false)
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
 	fn := func() (*objWithLink, error) {
 		file, err := GetUnwrap(ctx, storage, path)
@@ -198,10 +216,15 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errors.WithStack(errs.NotFile)
 		}
 
+		start := time.Now()
 		link, err := storage.Link(ctx, file, args)
+		metrics.DriverCall(storage.GetStorage(), "link", start, err)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed get link")
 		}
+		if storage.GetStorage().EnableDiskCache {
+			useDiskCache(key+"/"+args.Type, file, link)
+		}
 		ol := &objWithLink{link: link, obj: file}
 		if link.Expiration != nil {
 			Cache.linkCache.SetTypeWithTTL(key, typeKey, ol, *link.Expiration)
@@ -293,6 +316,9 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 				default:
 					return nil, errs.NotImplement
 				}
+				if err == nil {
+					handleFsChange(ctx, storage, model.FsChangeMkdir, path, "", true)
+				}
 				return nil, errors.WithStack(err)
 			}
 			return nil, errors.WithMessage(err, "failed to check if dir exists")
@@ -353,6 +379,9 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		err = errs.NotImplement
 	}
+	if err == nil {
+		handleFsChange(ctx, storage, model.FsChangeMove, srcPath, stdpath.Join(dstDirPath, srcObj.GetName()), srcObj.IsDir())
+	}
 
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		if !srcObj.IsDir() {
@@ -413,6 +442,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		handleFsChange(ctx, storage, model.FsChangeRename, srcPath, stdpath.Join(stdpath.Dir(srcPath), dstName), srcObj.IsDir())
+	}
 	return errors.WithStack(err)
 }
 
@@ -457,6 +489,12 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		err = errs.NotImplement
 	}
+	if err == nil && !srcObj.IsDir() {
+		PreserveModTime(ctx, storage, srcObj, dstDirPath, lazyCache...)
+	}
+	if err == nil {
+		handleFsChange(ctx, storage, model.FsChangeCopy, srcPath, stdpath.Join(dstDirPath, srcObj.GetName()), srcObj.IsDir())
+	}
 
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		if !srcObj.IsDir() {
@@ -476,6 +514,39 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	return errors.WithStack(err)
 }
 
+// PreserveModTime gives a copied file the modification time of its source,
+// failures are only logged since the copy itself has succeeded
+func PreserveModTime(ctx context.Context, storage driver.Driver, srcObj model.Obj, dstDirPath string, lazyCache ...bool) {
+	s, ok := storage.(driver.SetModTime)
+	modTime := srcObj.ModTime()
+	if !ok || modTime.IsZero() {
+		return
+	}
+	dstPath := stdpath.Join(dstDirPath, srcObj.GetName())
+	dstObj, err := GetUnwrap(ctx, storage, dstPath)
+	if errs.IsObjectNotFound(err) {
+		// the lazily cached dir may not list the new file yet
+		Cache.DeleteDirectory(storage, dstDirPath)
+		dstObj, err = GetUnwrap(ctx, storage, dstPath)
+	}
+	if err != nil {
+		log.Warnf("failed to get copied file %s to preserve its modification time: %+v", dstPath, err)
+		return
+	}
+	if dstObj.ModTime().Truncate(time.Second).Equal(modTime.Truncate(time.Second)) {
+		return
+	}
+	if err = s.SetModTime(ctx, dstObj, modTime); err != nil {
+		if !errs.IsNotImplementError(err) {
+			log.Warnf("failed to preserve modification time of %s: %+v", dstPath, err)
+		}
+		return
+	}
+	if !utils.IsBool(lazyCache...) {
+		Cache.DeleteDirectory(storage, dstDirPath)
+	}
+}
+
 func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
@@ -500,6 +571,7 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		err = s.Remove(ctx, model.UnwrapObj(rawObj))
 		if err == nil {
 			Cache.removeDirectoryObject(storage, dirPath, rawObj)
+			handleFsChange(ctx, storage, model.FsChangeRemove, path, "", rawObj.IsDir())
 		}
 	default:
 		return errs.NotImplement
@@ -507,7 +579,31 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	return errors.WithStack(err)
 }
 
-func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
+// SetModTime changes the modification time of the object, errs.NotImplement if the driver can't
+func SetModTime(ctx context.Context, storage driver.Driver, path string, modTime time.Time) error {
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
+	}
+	s, ok := storage.(driver.SetModTime)
+	if !ok {
+		return errs.NotImplement
+	}
+	path = utils.FixAndCleanPath(path)
+	rawObj, err := Get(ctx, storage, path)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get object")
+	}
+	if err = s.SetModTime(ctx, model.UnwrapObj(rawObj), modTime); err != nil {
+		return errors.WithStack(err)
+	}
+	// the cached object still has the old time
+	Cache.DeleteDirectory(storage, stdpath.Dir(path))
+	return nil
+}
+
+func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) (err error) {
+	ctx, span := tracing.Start(ctx, "op.Put", traceAttrs(storage, stdpath.Join(dstDirPath, file.GetName()))...)
+	defer func() { tracing.End(span, err) }()
 	close := file.Close
 	defer func() {
 		if err := close(); err != nil {
@@ -564,10 +660,12 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		log.Warnf("file size < 0, try to get full size from cache")
 		file.CacheFullAndWriter(nil, nil)
 	}
+	start := time.Now()
 	switch s := storage.(type) {
 	case driver.PutResult:
 		var newObj model.Obj
 		newObj, err = s.Put(ctx, parentDir, file, up)
+		metrics.DriverCall(storage.GetStorage(), "put", start, err)
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if newObj != nil {
@@ -578,6 +676,7 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		}
 	case driver.Put:
 		err = s.Put(ctx, parentDir, file, up)
+		metrics.DriverCall(storage.GetStorage(), "put", start, err)
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if !utils.IsBool(lazyCache...) {
@@ -587,6 +686,9 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		handleFsChange(ctx, storage, model.FsChangePut, dstPath, "", false)
+	}
 	log.Debugf("put file [%s] done", file.GetName())
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
@@ -647,6 +749,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	default:
 		return errors.WithStack(errs.NotImplement)
 	}
+	if err == nil {
+		handleFsChange(ctx, storage, model.FsChangePut, dstPath, "", false)
+	}
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		go List(context.Background(), storage, dstDirPath, model.ListArgs{Refresh: true})
 	}
@@ -698,3 +803,11 @@ func needHandleObjsUpdateHook() bool {
 	needHandle, _ := GetSettingItemByKey(conf.HandleHookAfterWriting)
 	return needHandle != nil && (needHandle.Value == "true" || needHandle.Value == "1")
 }
+
+func traceAttrs(storage driver.Driver, path string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("storage.mount_path", storage.GetStorage().MountPath),
+		attribute.String("storage.driver", storage.GetStorage().Driver),
+		attribute.String("path", path),
+	}
+}
@@ -30,6 +30,35 @@ func HandleObjsUpdateHook(ctx context.Context, parent string, objs []model.Obj)
 	}
 }
 
+// FsChange
+type FsChangeHook = func(ctx context.Context, change model.FsChange)
+
+var fsChangeHooks = make([]FsChangeHook, 0)
+
+// RegisterFsChangeHook register a hook called after every successful write through op,
+// hooks are called synchronously so they should return quickly
+func RegisterFsChangeHook(hook FsChangeHook) {
+	fsChangeHooks = append(fsChangeHooks, hook)
+}
+
+func handleFsChange(ctx context.Context, storage driver.Driver, op, path, dstPath string, isDir bool) {
+	if len(fsChangeHooks) == 0 {
+		return
+	}
+	change := model.FsChange{
+		Op:    op,
+		Path:  utils.GetFullPath(storage.GetStorage().MountPath, path),
+		IsDir: isDir,
+	}
+	if dstPath != "" {
+		change.DstPath = utils.GetFullPath(storage.GetStorage().MountPath, dstPath)
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range fsChangeHooks {
+		hook(ctx, change)
+	}
+}
+
 // Setting
 type SettingItemHook func(item *model.SettingItem) error
 
@@ -0,0 +1,74 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+var s3KeyCache = cache.NewMemCache(cache.WithShards[*model.S3AccessKey](4))
+
+// CreateS3AccessKey generates the key pair, the secret is only readable from the returned key
+func CreateS3AccessKey(k *model.S3AccessKey) error {
+	k.AccessKeyId = "OL" + strings.ToUpper(random.String(18))
+	k.SecretAccessKey = random.String(40)
+	k.AddedTime = time.Now()
+	k.LastUsedTime = k.AddedTime
+	return db.CreateS3AccessKey(k)
+}
+
+func GetS3AccessKeysByUserId(userId uint, pageIndex, pageSize int) ([]model.S3AccessKey, int64, error) {
+	return db.GetS3AccessKeysByUserId(userId, pageIndex, pageSize)
+}
+
+func GetS3AccessKeyByIdAndUserId(id uint, userId uint) (*model.S3AccessKey, error) {
+	key, err := db.GetS3AccessKeyById(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserId != userId {
+		return nil, errors.New("failed get s3 key")
+	}
+	return key, nil
+}
+
+func GetS3AccessKeyByAccessKeyId(accessKeyId string) (*model.S3AccessKey, error) {
+	if k, ok := s3KeyCache.Get(accessKeyId); ok {
+		return k, nil
+	}
+	k, err := db.GetS3AccessKeyByAccessKeyId(accessKeyId)
+	if err != nil {
+		return nil, err
+	}
+	s3KeyCache.Set(accessKeyId, k, cache.WithEx[*model.S3AccessKey](time.Minute*10))
+	return k, nil
+}
+
+// UpdateS3AccessKeyLastUsedTime records the usage at most once a minute
+func UpdateS3AccessKeyLastUsedTime(k *model.S3AccessKey) error {
+	now := time.Now()
+	if now.Sub(k.LastUsedTime) < time.Minute {
+		return nil
+	}
+	k.LastUsedTime = now
+	return db.UpdateS3AccessKeyLastUsedTime(k.ID, now)
+}
+
+func DeleteS3AccessKeyById(id uint) error {
+	k, err := db.GetS3AccessKeyById(id)
+	if err != nil {
+		return err
+	}
+	s3KeyCache.Del(k.AccessKeyId)
+	return db.DeleteS3AccessKeyById(id)
+}
+
+func DeleteS3AccessKeysByUserId(userId uint) error {
+	s3KeyCache.Clear()
+	return db.DeleteS3AccessKeysByUserId(userId)
+}
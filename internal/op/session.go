@@ -0,0 +1,158 @@
+package op
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var sessionCache = cache.NewMemCache(cache.WithShards[*model.Session](16))
+
+// CreateSession fills the id and the times of the session and saves it, and returns its refresh token.
+// The expired sessions of all users are removed on the way.
+func CreateSession(s *model.Session) (string, error) {
+	now := time.Now()
+	if err := db.DeleteExpiredSessions(now); err != nil {
+		log.Warnf("failed to delete expired sessions: %+v", err)
+	}
+	s.ID = random.String(32)
+	s.CreatedAt = now
+	s.LastSeen = now
+	refreshToken := newRefreshToken(s)
+	return refreshToken, db.CreateSession(s)
+}
+
+// newRefreshToken replaces the refresh token of the session, only its hash is kept
+func newRefreshToken(s *model.Session) string {
+	refreshToken := s.ID + "." + random.String(48)
+	s.RefreshToken = hashRefreshToken(refreshToken)
+	return refreshToken
+}
+
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshGrace is how long the replaced refresh token still works,
+// so that two tabs refreshing at once don't look like a stolen token
+const refreshGrace = 30 * time.Second
+
+var (
+	refreshMu sync.Mutex
+	// rotatedTokens maps the hash of a replaced refresh token to its successor during the grace,
+	// every client refreshing with the replaced token gets the same successor
+	rotatedTokens = cache.NewMemCache[string]()
+)
+
+// RefreshSession rotates the refresh token of the session and extends its life.
+// A refresh token can only be used once, reusing an old one after the grace revokes the session
+// since either the token or its successor has been stolen.
+func RefreshSession(refreshToken, ip string, expiresIn time.Duration) (*model.Session, string, error) {
+	id, secret, _ := strings.Cut(refreshToken, ".")
+	if len(id) != 32 || len(secret) != 48 {
+		return nil, "", errors.New("invalid refresh token")
+	}
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	s, err := GetSessionById(id)
+	if err != nil {
+		return nil, "", errors.New("session not found")
+	}
+	now := time.Now()
+	hash := hashRefreshToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(s.RefreshToken)) != 1 {
+		if s.PrevRefreshToken != "" && now.Sub(s.RotatedAt) < refreshGrace &&
+			subtle.ConstantTimeCompare([]byte(hash), []byte(s.PrevRefreshToken)) == 1 {
+			if successor, ok := rotatedTokens.Get(hash); ok && !s.Expired() {
+				return s, successor, nil
+			}
+			return nil, "", errors.New("refresh token has been rotated")
+		}
+		log.Warnf("reused refresh token of session %s from %s, revoke it", s.ID, ip)
+		_ = DeleteSessionById(s.ID)
+		return nil, "", errors.New("refresh token is invalidated")
+	}
+	if s.Expired() {
+		_ = DeleteSessionById(s.ID)
+		return nil, "", errors.New("session is expired")
+	}
+	// work on a copy, the cached session may be read concurrently
+	cp := *s
+	cp.PrevRefreshToken = cp.RefreshToken
+	cp.RotatedAt = now
+	newRefresh := newRefreshToken(&cp)
+	cp.LastSeen = now
+	cp.ExpiresAt = now.Add(expiresIn)
+	cp.IP = ip
+	sessionCache.Del(cp.ID)
+	ok, err := db.RotateSession(&cp, s.RefreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", errors.New("session has been refreshed, try again")
+	}
+	rotatedTokens.Set(hash, newRefresh, cache.WithEx[string](refreshGrace))
+	return &cp, newRefresh, nil
+}
+
+func GetSessionById(id string) (*model.Session, error) {
+	if s, ok := sessionCache.Get(id); ok {
+		return s, nil
+	}
+	s, err := db.GetSessionById(id)
+	if err != nil {
+		return nil, err
+	}
+	sessionCache.Set(id, s, cache.WithEx[*model.Session](time.Minute*10))
+	return s, nil
+}
+
+func GetSessionsByUserId(userId uint, pageIndex, pageSize int) ([]model.Session, int64, error) {
+	return db.GetSessionsByUserId(userId, pageIndex, pageSize)
+}
+
+// TouchSession records the activity of the session at most once a minute, unless the ip changes
+func TouchSession(s *model.Session, ip string) error {
+	now := time.Now()
+	if now.Sub(s.LastSeen) < time.Minute && s.IP == ip {
+		return nil
+	}
+	// the session may be shared through the cache, reload it instead of writing to it
+	err := db.UpdateSessionLastSeen(s.ID, ip, now)
+	sessionCache.Del(s.ID)
+	return err
+}
+
+func UpdateSession(s *model.Session) error {
+	sessionCache.Del(s.ID)
+	return db.UpdateSession(s)
+}
+
+func DeleteSessionById(id string) error {
+	sessionCache.Del(id)
+	return db.DeleteSessionById(id)
+}
+
+// DeleteSessionsByUserId logs the user out everywhere
+func DeleteSessionsByUserId(userId uint) error {
+	ids, err := db.GetSessionIdsByUserId(userId)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		sessionCache.Del(id)
+	}
+	return db.DeleteSessionsByUserId(userId)
+}
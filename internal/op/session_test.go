@@ -0,0 +1,114 @@
+package op_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func newSession(t *testing.T) (*model.Session, string) {
+	s := &model.Session{UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	refresh, err := op.CreateSession(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, refresh
+}
+
+func TestRefreshSession(t *testing.T) {
+	s, r0 := newSession(t)
+	_, r1, err := op.RefreshSession(r0, "127.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another tab refreshing with the replaced token at the same time gets the same successor
+	_, again, err := op.RefreshSession(r0, "127.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatalf("replaced token in the grace window: %v", err)
+	}
+	if again != r1 {
+		t.Error("concurrent refreshes get different tokens")
+	}
+	_, r2, err := op.RefreshSession(r1, "127.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r0 has been replaced twice, using it again means it's stolen
+	if _, _, err = op.RefreshSession(r0, "127.0.0.1", time.Hour); err == nil {
+		t.Fatal("reused token is accepted")
+	}
+	if _, err = op.GetSessionById(s.ID); err == nil {
+		t.Error("session is not revoked on reuse")
+	}
+	if _, _, err = op.RefreshSession(r2, "127.0.0.1", time.Hour); err == nil {
+		t.Error("revoked session is refreshed")
+	}
+}
+
+func TestRefreshSessionMalformed(t *testing.T) {
+	s, r0 := newSession(t)
+	for _, token := range []string{s.ID, s.ID + ".", s.ID + ".short", r0[:len(r0)-1]} {
+		if _, _, err := op.RefreshSession(token, "127.0.0.1", time.Hour); err == nil {
+			t.Errorf("malformed token %q is accepted", token)
+		}
+	}
+	if _, _, err := op.RefreshSession(r0, "127.0.0.1", time.Hour); err != nil {
+		t.Errorf("session is revoked by a malformed token: %v", err)
+	}
+}
+
+func TestRefreshSessionConcurrent(t *testing.T) {
+	s, r0 := newSession(t)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var tokens []string
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, r, err := op.RefreshSession(r0, "127.0.0.1", time.Hour); err == nil {
+				mu.Lock()
+				tokens = append(tokens, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(tokens) != 10 {
+		t.Fatalf("%d of 10 refreshes succeeded", len(tokens))
+	}
+	for _, r := range tokens {
+		if r != tokens[0] {
+			t.Errorf("racing refreshes get different tokens %s and %s", tokens[0], r)
+		}
+	}
+	// racing refreshes are never taken for a stolen token
+	if _, err := op.GetSessionById(s.ID); err != nil {
+		t.Errorf("session is revoked by concurrent refreshes: %v", err)
+	}
+}
+
+func TestTouchSession(t *testing.T) {
+	s, _ := newSession(t)
+	cached, err := op.GetSessionById(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastSeen := cached.LastSeen
+	if err = op.TouchSession(cached, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if cached.IP == "10.0.0.1" || !cached.LastSeen.Equal(lastSeen) {
+		t.Error("cached session is written in place")
+	}
+	touched, err := op.GetSessionById(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if touched.IP != "10.0.0.1" {
+		t.Errorf("ip of touched session: %s", touched.IP)
+	}
+}
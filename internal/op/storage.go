@@ -26,6 +26,15 @@ import (
 // so it should actually be a storage, just wrapped by the driver
 var storagesMap generic_sync.MapOf[string, driver.Driver]
 
+// storageLocks serializes the drops and inits of a storage by its id
+var storageLocks generic_sync.MapOf[uint, *sync.Mutex]
+
+func lockStorage(id uint) func() {
+	mu, _ := storageLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.Lock()
+	return mu.Unlock
+}
+
 func GetAllStorages() []driver.Driver {
 	return storagesMap.Values()
 }
@@ -189,6 +198,7 @@ func EnableStorage(ctx context.Context, id uint) error {
 }
 
 func DisableStorage(ctx context.Context, id uint) error {
+	defer lockStorage(id)()
 	storage, err := db.GetStorageById(id)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -220,6 +230,7 @@ func DisableStorage(ctx context.Context, id uint) error {
 // get old storage first
 // drop the storage then reinitialize
 func UpdateStorage(ctx context.Context, storage model.Storage) error {
+	defer lockStorage(storage.ID)()
 	oldStorage, err := db.GetStorageById(storage.ID)
 	if err != nil {
 		return errors.WithMessage(err, "failed get old storage")
@@ -257,7 +268,39 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 	return err
 }
 
+// ReinitStorage drops the storage and initializes it again with its current settings,
+// which is how the drivers renew an expired login. It gives up if the storage has been
+// removed, disabled or updated in the database since it was loaded
+func ReinitStorage(ctx context.Context, storageDriver driver.Driver) error {
+	defer lockStorage(storageDriver.GetStorage().ID)()
+	storage := *storageDriver.GetStorage()
+	if d, ok := storagesMap.Load(storage.MountPath); !ok || d != storageDriver {
+		return errors.Errorf("storage %s has been removed or updated", storage.MountPath)
+	}
+	saved, err := db.GetStorageById(storage.ID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	// the databases keep the time with different precisions
+	if saved.Disabled || saved.Modified.Sub(storage.Modified).Abs() >= time.Second {
+		return errors.Errorf("storage %s has been removed or updated", storage.MountPath)
+	}
+	// the driver may have refreshed its tokens since the storage was loaded
+	str, err := utils.Json.MarshalToString(storageDriver.GetAddition())
+	if err != nil {
+		return errors.Wrap(err, "error while marshal addition")
+	}
+	storage.Addition = str
+	if err = storageDriver.Drop(ctx); err != nil {
+		return errors.Wrap(err, "failed drop storage")
+	}
+	Cache.DeleteDirectoryTree(storageDriver, "/")
+	Cache.InvalidateStorageDetails(storageDriver)
+	return initStorage(ctx, storage, storageDriver)
+}
+
 func DeleteStorageById(ctx context.Context, id uint) error {
+	defer lockStorage(id)()
 	storage, err := db.GetStorageById(id)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
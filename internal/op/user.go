@@ -82,6 +82,15 @@ func DeleteUserById(id uint) error {
 	if err := DeleteSharingsByCreatorId(id); err != nil {
 		return errors.WithMessage(err, "failed to delete user's sharings")
 	}
+	if err := DeleteDirectLinksByCreatorId(id); err != nil {
+		return errors.WithMessage(err, "failed to delete user's direct links")
+	}
+	if err := DeleteS3AccessKeysByUserId(id); err != nil {
+		return errors.WithMessage(err, "failed to delete user's s3 keys")
+	}
+	if err := DeleteSessionsByUserId(id); err != nil {
+		return errors.WithMessage(err, "failed to delete user's sessions")
+	}
 	return db.DeleteUserById(id)
 }
 
@@ -98,7 +107,14 @@ func UpdateUser(u *model.User) error {
 	}
 	Cache.DeleteUser(old.Username)
 	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.UpdateUser(u)
+	if err := db.UpdateUser(u); err != nil {
+		return err
+	}
+	// a new password logs out every session
+	if old.PwdTS != u.PwdTS {
+		return DeleteSessionsByUserId(u.ID)
+	}
+	return nil
 }
 
 func Cancel2FAByUser(u *model.User) error {
@@ -7,6 +7,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// bleve keys the nodes by random ids, so they can't be found by path to be updated or deleted,
+// auto update is not supported and the index has to be rebuilt to pick up changes
 var config = searcher.Config{
 	Name: "bleve",
 }
@@ -26,6 +28,18 @@ func Init(indexPath *string) (bleve.Index, error) {
 		nameFieldMapping := bleve.NewKeywordFieldMapping()
 		searchNodeMapping.AddFieldMappingsAt("name", nameFieldMapping)
 		indexMapping.AddDocumentMapping("SearchNode", searchNodeMapping)
+		// content documents are only searched by content, the rest is stored for building the result
+		contentMapping := bleve.NewDocumentMapping()
+		contentMapping.AddFieldMappingsAt("content", bleve.NewTextFieldMapping())
+		storeOnlyMapping := bleve.NewTextFieldMapping()
+		storeOnlyMapping.Index = false
+		contentMapping.AddFieldMappingsAt("content_parent", storeOnlyMapping)
+		contentMapping.AddFieldMappingsAt("content_name", storeOnlyMapping)
+		sizeMapping := bleve.NewNumericFieldMapping()
+		sizeMapping.Index = false
+		contentMapping.AddFieldMappingsAt("content_size", sizeMapping)
+		contentMapping.AddFieldMappingsAt("content_parents", bleve.NewKeywordFieldMapping())
+		indexMapping.AddDocumentMapping(contentDocumentType, contentMapping)
 		fileIndex, err = bleve.New(*indexPath, indexMapping)
 		if err != nil {
 			return nil, err
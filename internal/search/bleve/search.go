@@ -3,6 +3,8 @@ package bleve
 import (
 	"context"
 	"os"
+	"path"
+	"strings"
 
 	query2 "github.com/blevesearch/bleve/v2/search/query"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/blevesearch/bleve/v2"
 	search2 "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -26,6 +29,9 @@ func (b *Bleve) Config() searcher.Config {
 }
 
 func (b *Bleve) Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
+	if req.Content {
+		return b.searchContent(ctx, req)
+	}
 	var queries []query2.Query
 	query := bleve.NewMatchQuery(req.Keywords)
 	query.SetField("name")
@@ -57,6 +63,100 @@ func (b *Bleve) Search(ctx context.Context, req model.SearchReq) ([]model.Search
 	return res, int64(searchResults.Total), nil
 }
 
+const contentDocumentType = "SearchContent"
+
+type contentDocument struct {
+	Parent string `json:"content_parent"`
+	Name   string `json:"content_name"`
+	Size   int64  `json:"content_size"`
+	// Parents are the parent and its ancestors, for scoping searches and deleting descendants
+	Parents []string `json:"content_parents"`
+	Content string   `json:"content"`
+}
+
+func (d *contentDocument) Type() string {
+	return contentDocumentType
+}
+
+func (b *Bleve) searchContent(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
+	if req.Scope == 1 {
+		// only files have content
+		return []model.SearchNode{}, 0, nil
+	}
+	query := bleve.NewMatchQuery(req.Keywords)
+	query.SetField("content")
+	queries := []query2.Query{query}
+	if req.Parent != "" && req.Parent != "/" {
+		parentQuery := bleve.NewTermQuery(utils.FixAndCleanPath(req.Parent))
+		parentQuery.SetField("content_parents")
+		queries = append(queries, parentQuery)
+	}
+	search := bleve.NewSearchRequest(bleve.NewConjunctionQuery(queries...))
+	search.From = (req.Page - 1) * req.PerPage
+	search.Size = req.PerPage
+	search.Fields = []string{"content_parent", "content_name", "content_size"}
+	// the html style escapes the content around the <mark> tags
+	search.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	search.Highlight.AddField("content")
+	searchResults, err := b.BIndex.SearchInContext(ctx, search)
+	if err != nil {
+		log.Errorf("search content error: %+v", err)
+		return nil, 0, err
+	}
+	res, err := utils.SliceConvert(searchResults.Hits, func(src *search2.DocumentMatch) (model.SearchNode, error) {
+		node := model.SearchNode{
+			Snippet: strings.Join(src.Fragments["content"], " … "),
+		}
+		node.Parent, _ = src.Fields["content_parent"].(string)
+		node.Name, _ = src.Fields["content_name"].(string)
+		if size, ok := src.Fields["content_size"].(float64); ok {
+			node.Size = int64(size)
+		}
+		return node, nil
+	})
+	return res, int64(searchResults.Total), err
+}
+
+// IndexContent index the text in a separate document, keyed by path so re-indexing replaces it
+func (b *Bleve) IndexContent(ctx context.Context, node model.SearchNode, content string) error {
+	return b.BIndex.Index(contentID(path.Join(node.Parent, node.Name)), &contentDocument{
+		Parent:  node.Parent,
+		Name:    node.Name,
+		Size:    node.Size,
+		Parents: utils.GetPathHierarchy(node.Parent),
+		Content: content,
+	})
+}
+
+func contentID(p string) string {
+	return "content:" + p
+}
+
+// delContent deletes the content of the file at prefix and of the files under it
+func (b *Bleve) delContent(ctx context.Context, prefix string) error {
+	batch := b.BIndex.NewBatch()
+	batch.Delete(contentID(prefix))
+	query := bleve.NewTermQuery(prefix)
+	query.SetField("content_parents")
+	for {
+		search := bleve.NewSearchRequestOptions(query, 1000, 0, false)
+		res, err := b.BIndex.SearchInContext(ctx, search)
+		if err != nil {
+			return err
+		}
+		for _, hit := range res.Hits {
+			batch.Delete(hit.ID)
+		}
+		if err = b.BIndex.Batch(batch); err != nil {
+			return err
+		}
+		if len(res.Hits) == 0 {
+			return nil
+		}
+		batch.Reset()
+	}
+}
+
 func (b *Bleve) Index(ctx context.Context, node model.SearchNode) error {
 	return b.BIndex.Index(uuid.NewString(), node)
 }
@@ -73,7 +173,11 @@ func (b *Bleve) Get(ctx context.Context, parent string) ([]model.SearchNode, err
 	return nil, errs.NotSupport
 }
 
+// Del only removes the content, the nodes are keyed by random ids and stay until the index is rebuilt
 func (b *Bleve) Del(ctx context.Context, prefix string) error {
+	if err := b.delContent(ctx, utils.FixAndCleanPath(prefix)); err != nil {
+		return err
+	}
 	return errs.NotSupport
 }
 
@@ -103,3 +207,4 @@ func (b *Bleve) Clear(ctx context.Context) error {
 }
 
 var _ searcher.Searcher = (*Bleve)(nil)
+var _ searcher.ContentSearcher = (*Bleve)(nil)
@@ -0,0 +1,81 @@
+package bleve
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestContent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bleve")
+	index, err := Init(&dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bleve{BIndex: index}
+	defer b.Release(context.Background())
+	ctx := context.Background()
+	for _, node := range []model.SearchNode{
+		{Parent: "/a", Name: "1.txt", Size: 1},
+		{Parent: "/a/b", Name: "2.txt", Size: 2},
+		{Parent: "/c", Name: "3.txt", Size: 3},
+	} {
+		if err = b.IndexContent(ctx, node, "hello world"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(parent string, scope int) int64 {
+		t.Helper()
+		_, total, err := b.Search(ctx, model.SearchReq{
+			Parent:   parent,
+			Keywords: "hello",
+			Scope:    scope,
+			Content:  true,
+			PageReq:  model.PageReq{Page: 1, PerPage: 10},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+	for _, c := range []struct {
+		parent string
+		scope  int
+		want   int64
+	}{{"/", 0, 3}, {"/a", 0, 2}, {"/a/b", 2, 1}, {"/a", 1, 0}} {
+		if got := count(c.parent, c.scope); got != c.want {
+			t.Errorf("search in %s with scope %d found %d, want %d", c.parent, c.scope, got, c.want)
+		}
+	}
+	_ = b.Del(ctx, "/a")
+	if got := count("/", 0); got != 1 {
+		t.Errorf("found %d after deleting /a, want 1", got)
+	}
+	_ = b.Del(ctx, "/c/3.txt")
+	if got := count("/", 0); got != 0 {
+		t.Errorf("found %d after deleting /c/3.txt, want 0", got)
+	}
+}
+
+func TestContentSnippetEscaped(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bleve")
+	index, err := Init(&dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bleve{BIndex: index}
+	defer b.Release(context.Background())
+	ctx := context.Background()
+	if err = b.IndexContent(ctx, model.SearchNode{Parent: "/", Name: "x.html"}, `<img src=x onerror=alert(1)> hello`); err != nil {
+		t.Fatal(err)
+	}
+	nodes, _, err := b.Search(ctx, model.SearchReq{Keywords: "hello", Content: true, PageReq: model.PageReq{Page: 1, PerPage: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Snippet != "&lt;img src=x onerror=alert(1)&gt; <mark>hello</mark>" {
+		t.Errorf("snippet %+v", nodes)
+	}
+}
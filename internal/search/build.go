@@ -12,6 +12,7 @@ import (
     "github.com/OpenListTeam/OpenList/v4/internal/conf"
     "github.com/OpenListTeam/OpenList/v4/internal/errs"
     "github.com/OpenListTeam/OpenList/v4/internal/fs"
+    "github.com/OpenListTeam/OpenList/v4/internal/media"
     "github.com/OpenListTeam/OpenList/v4/internal/model"
     "github.com/OpenListTeam/OpenList/v4/internal/op"
     "github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
@@ -222,6 +223,7 @@ func BuildIndex(ctx context.Context, indexPaths, ignorePaths []string, maxDepth
 }
 
 func Del(ctx context.Context, prefix string) error {
+	media.Delete(prefix)
 	return instance.Del(ctx, prefix)
 }
 
@@ -278,6 +280,7 @@ func Update(ctx context.Context, parent string, objs []model.Obj) {
 	for i := range nodes {
 		if toDelete.Contains(nodes[i].Name) && !op.HasStorage(path.Join(parent, nodes[i].Name)) {
 			log.Debugf("delete index: %s", path.Join(parent, nodes[i].Name))
+			media.Delete(path.Join(parent, nodes[i].Name))
 			err = instance.Del(ctx, path.Join(parent, nodes[i].Name))
 			if err != nil {
 				log.Errorf("update search index error while del old node: %+v", err)
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/doctext"
+	"github.com/OpenListTeam/OpenList/v4/pkg/mq"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxContentLen limits the text stored for a single file
+const maxContentLen = 1 << 20
+
+var (
+	contentMQ      = mq.NewInMemoryMQ[model.SearchNode]()
+	contentRunning atomic.Bool
+)
+
+// EnqueueContent queue files to extract text from, the work is done in background
+// because every file has to be read from its storage.
+func EnqueueContent(nodes []model.SearchNode) {
+	if _, ok := instance.(searcher.ContentSearcher); !ok || !setting.GetBool(conf.ContentIndex) {
+		return
+	}
+	maxSize := int64(setting.GetInt(conf.ContentIndexMaxSize, 10)) * utils.MB
+	for _, node := range nodes {
+		if node.IsDir || node.Size <= 0 || node.Size > maxSize || !isContentType(node.Name) {
+			continue
+		}
+		contentMQ.Publish(mq.Message[model.SearchNode]{Content: node})
+	}
+	if contentMQ.Len() > 0 && contentRunning.CompareAndSwap(false, true) {
+		go consumeContent()
+	}
+}
+
+func consumeContent() {
+	for {
+		contentMQ.ConsumeAll(func(messages []mq.Message[model.SearchNode]) {
+			for _, msg := range messages {
+				node := msg.Content
+				if err := indexContent(context.Background(), node); err != nil {
+					log.Warnf("index content of %s error: %+v", path.Join(node.Parent, node.Name), err)
+				}
+			}
+		})
+		contentRunning.Store(false)
+		// a publish may have happened between the last consume and the store
+		if contentMQ.Len() == 0 || !contentRunning.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+func isContentType(name string) bool {
+	ext := utils.Ext(name)
+	if ext == "" {
+		return false
+	}
+	if utils.SliceContains(conf.SlicesMap[conf.TextTypes], ext) {
+		return true
+	}
+	for _, t := range strings.Split(setting.GetStr(conf.ContentIndexTypes), ",") {
+		if strings.TrimSpace(t) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func indexContent(ctx context.Context, node model.SearchNode) error {
+	cs, ok := instance.(searcher.ContentSearcher)
+	if !ok {
+		return nil
+	}
+	fullPath := path.Join(node.Parent, node.Name)
+	storage, actualPath, err := op.GetStorageAndActualPath(fullPath)
+	if err != nil {
+		return err
+	}
+	if !storage.GetStorage().EnableContentIndex || storage.GetStorage().DisableIndex {
+		return nil
+	}
+	link, obj, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Ctx: ctx, Obj: obj}, link)
+	if err != nil {
+		_ = link.Close()
+		return err
+	}
+	defer ss.Close()
+	maxSize := int64(setting.GetInt(conf.ContentIndexMaxSize, 10)) * utils.MB
+	data, err := io.ReadAll(io.LimitReader(ss, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		return errors.Errorf("file is larger than %d bytes", maxSize)
+	}
+	text, err := doctext.Extract(utils.Ext(node.Name), data, maxContentLen)
+	if err != nil {
+		if errors.Is(err, doctext.ErrUnsupported) {
+			return nil
+		}
+		return err
+	}
+	if text == "" {
+		return nil
+	}
+	log.Debugf("index content of %s, %d bytes", fullPath, len(text))
+	return cs.IndexContent(ctx, node, text)
+}
@@ -31,7 +31,7 @@ func init() {
             IndexUid: indexUid,
             FilterableAttributes: []string{"parent", "is_dir", "name", "ext", "size",
                 "parent_hash", "parent_path_hashes"},
-            SearchableAttributes: []string{"name"},
+            SearchableAttributes: []string{"name", "content"},
         }
 
 		_, err := m.Client.GetIndex(m.IndexUid)
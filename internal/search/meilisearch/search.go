@@ -35,6 +35,7 @@ type Meilisearch struct {
 	FilterableAttributes []string
 	SearchableAttributes []string
 	taskQueue            *TaskQueueManager
+	addedHook            func(nodes []model.SearchNode)
 }
 
 func (m *Meilisearch) Config() searcher.Config {
@@ -43,10 +44,19 @@ func (m *Meilisearch) Config() searcher.Config {
 
 func (m *Meilisearch) Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
     mReq := &meilisearch.SearchRequest{
-        AttributesToSearchOn: m.SearchableAttributes,
+        AttributesToSearchOn: []string{"name"},
+        AttributesToRetrieve: []string{"parent", "name", "is_dir", "size"},
         Page:                 int64(req.Page),
         HitsPerPage:          int64(req.PerPage),
     }
+    if req.Content {
+        mReq.AttributesToSearchOn = []string{"content"}
+        mReq.AttributesToCrop = []string{"content"}
+        mReq.CropLength = 40
+        mReq.AttributesToHighlight = []string{"content"}
+        mReq.HighlightPreTag = highlightPreTag
+        mReq.HighlightPostTag = highlightPostTag
+    }
     var filters []string
     if req.Scope != 0 {
         filters = append(filters, fmt.Sprintf("is_dir = %v", req.Scope == 1))
@@ -87,21 +97,54 @@ func (m *Meilisearch) Search(ctx context.Context, req model.SearchReq) ([]model.
 	if err != nil {
 		return nil, 0, err
 	}
-	nodes, err := utils.SliceConvert(search.Hits, func(src any) (model.SearchNode, error) {
-		srcMap := src.(map[string]any)
-		return model.SearchNode{
-			Parent: srcMap["parent"].(string),
-			Name:   srcMap["name"].(string),
-			IsDir:  srcMap["is_dir"].(bool),
-			Size:   int64(srcMap["size"].(float64)),
-		}, nil
-	})
-	if err != nil {
-		return nil, 0, err
+	nodes := make([]model.SearchNode, 0, len(search.Hits))
+	for _, src := range search.Hits {
+		srcMap, ok := src.(map[string]any)
+		if !ok {
+			continue
+		}
+		var node model.SearchNode
+		// skip the documents without the fields of a node, such as the ones left by older versions
+		if node.Parent, ok = srcMap["parent"].(string); !ok {
+			continue
+		}
+		if node.Name, ok = srcMap["name"].(string); !ok {
+			continue
+		}
+		node.IsDir, _ = srcMap["is_dir"].(bool)
+		if size, ok := srcMap["size"].(float64); ok {
+			node.Size = int64(size)
+		}
+		if formatted, ok := srcMap["_formatted"].(map[string]any); ok {
+			snippet, _ := formatted["content"].(string)
+			node.Snippet = formatSnippet(snippet)
+		}
+		nodes = append(nodes, node)
 	}
 	return nodes, search.TotalHits, nil
 }
 
+// IndexContent add the text to the existing document of the node,
+// nodes not indexed yet are skipped so that no document is left without its fields
+func (m *Meilisearch) IndexContent(ctx context.Context, node model.SearchNode, content string) error {
+	document, err := m.getDocumentInPath(ctx, node.Parent, node.Name)
+	if err != nil || document == nil {
+		return err
+	}
+	documents := []map[string]any{{
+		"id":      document.ID,
+		"content": content,
+	}}
+	// documents are merged by id, task was enqueued (if succeed), no need to wait
+	_, err = m.Client.Index(m.IndexUid).UpdateDocumentsWithContext(ctx, documents)
+	return err
+}
+
+// SetAddedHook set the hook called with nodes added by the task queue
+func (m *Meilisearch) SetAddedHook(hook func(nodes []model.SearchNode)) {
+	m.addedHook = hook
+}
+
 func (m *Meilisearch) Index(ctx context.Context, node model.SearchNode) error {
 	return m.BatchIndex(ctx, []model.SearchNode{node})
 }
@@ -132,8 +175,9 @@ func (m *Meilisearch) BatchIndex(ctx context.Context, nodes []model.SearchNode)
 		return err
 	}
 
-	// max up to 10,000 documents per batch to reduce error rate while uploading over the Internet
-	_, err = m.Client.Index(m.IndexUid).AddDocumentsInBatchesWithContext(ctx, documents, 10000)
+	// max up to 10,000 documents per batch to reduce error rate while uploading over the Internet,
+	// documents are updated rather than replaced to keep the extracted content
+	_, err = m.Client.Index(m.IndexUid).UpdateDocumentsInBatchesWithContext(ctx, documents, 10000)
 	if err != nil {
 		return err
 	}
@@ -295,7 +339,7 @@ func (m *Meilisearch) batchIndexWithTaskUID(ctx context.Context, nodes []model.S
 	}
 
 	// max up to 10,000 documents per batch to reduce error rate while uploading over the Internet
-	tasks, err := m.Client.Index(m.IndexUid).AddDocumentsInBatchesWithContext(ctx, documents, 10000)
+	tasks, err := m.Client.Index(m.IndexUid).UpdateDocumentsInBatchesWithContext(ctx, documents, 10000)
 	if err != nil {
 		return nil, err
 	}
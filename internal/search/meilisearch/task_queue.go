@@ -252,6 +252,9 @@ func (tqm *TaskQueueManager) executeTask(ctx context.Context, task *QueuedTask)
 			log.Errorf("failed to batch index for parent %s: %v", parent, err)
 		} else {
 			allTaskUIDs = append(allTaskUIDs, taskUIDs...)
+			if tqm.m.addedHook != nil {
+				tqm.m.addedHook(nodesToAdd)
+			}
 		}
 	}
 
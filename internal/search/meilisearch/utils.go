@@ -1,9 +1,26 @@
 package meilisearch
 
 import (
+	"html"
+	"strings"
+
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 )
 
+// the highlight tags asked from meilisearch are private use characters,
+// which unlike <mark> can't be told apart from the same text in the content
+const (
+	highlightPreTag  = "\ue000"
+	highlightPostTag = "\ue001"
+)
+
+var highlightReplacer = strings.NewReplacer(highlightPreTag, "<mark>", highlightPostTag, "</mark>")
+
+// formatSnippet escapes the content in the snippet and marks the matches with <mark>
+func formatSnippet(snippet string) string {
+	return highlightReplacer.Replace(html.EscapeString(snippet))
+}
+
 // hashPath hashes a path with SHA-1.
 // Path-relative exact matching should use hash,
 // because filtering strings on meilisearch is case-insensitive.
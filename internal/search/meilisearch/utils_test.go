@@ -0,0 +1,10 @@
+package meilisearch
+
+import "testing"
+
+func TestFormatSnippet(t *testing.T) {
+	got := formatSnippet("<mark>a</mark> & <script>" + highlightPreTag + "b" + highlightPostTag)
+	if want := "&lt;mark&gt;a&lt;/mark&gt; &amp; &lt;script&gt;<mark>b</mark>"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
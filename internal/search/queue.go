@@ -0,0 +1,304 @@
+package search
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/media"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// indexEventDebounce is how long a path has to stay unchanged before its event is applied,
+	// it is also the base delay before retrying a failed event
+	indexEventDebounce    = 5 * time.Second
+	indexEventMaxAttempts = 5
+)
+
+// eventQueue holds the pending index events, at most one per path.
+// Every event is persisted so the queue can be recovered after a restart.
+type eventQueue struct {
+	events    map[string]*model.IndexEvent // path -> event
+	mu        sync.Mutex
+	started   atomic.Bool
+	consuming atomic.Bool
+}
+
+var indexQueue = &eventQueue{events: make(map[string]*model.IndexEvent)}
+
+// InitIndexQueue load the events left by last run and start the consumer
+func InitIndexQueue() {
+	events, err := db.GetIndexEvents()
+	if err != nil {
+		log.Errorf("load index events error: %+v", err)
+	}
+	indexQueue.mu.Lock()
+	for i := range events {
+		e := events[i]
+		indexQueue.events[e.Path] = &e
+	}
+	indexQueue.mu.Unlock()
+	if len(events) > 0 {
+		log.Infof("recovered %d pending index events", len(events))
+	}
+	indexQueue.start()
+}
+
+// IndexQueue returns a snapshot of the pending index events
+func IndexQueue() []model.IndexEvent {
+	indexQueue.mu.Lock()
+	defer indexQueue.mu.Unlock()
+	events := make([]model.IndexEvent, 0, len(indexQueue.events))
+	for _, e := range indexQueue.events {
+		events = append(events, *e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events
+}
+
+// ClearIndexQueue drops all pending index events
+func ClearIndexQueue() error {
+	indexQueue.mu.Lock()
+	defer indexQueue.mu.Unlock()
+	indexQueue.events = make(map[string]*model.IndexEvent)
+	return db.ClearIndexEvents()
+}
+
+func autoUpdateEnabled() bool {
+	return instance != nil && instance.Config().AutoUpdate && setting.GetBool(conf.AutoUpdateIndex)
+}
+
+// OnFsChange turns a write made through op into index events
+func OnFsChange(ctx context.Context, change model.FsChange) {
+	if !autoUpdateEnabled() {
+		return
+	}
+	switch change.Op {
+	case model.FsChangePut, model.FsChangeMkdir:
+		indexQueue.push(change.Path, model.IndexEventUpsert, false)
+	case model.FsChangeRemove:
+		indexQueue.push(change.Path, model.IndexEventDelete, false)
+	case model.FsChangeRename, model.FsChangeMove:
+		indexQueue.push(change.Path, model.IndexEventDelete, false)
+		indexQueue.push(change.DstPath, model.IndexEventUpsert, change.IsDir)
+	case model.FsChangeCopy, model.FsChangeDecompress:
+		indexQueue.push(change.DstPath, model.IndexEventUpsert, change.IsDir)
+	}
+}
+
+func (q *eventQueue) push(p, typ string, recursive bool) {
+	if p == "" || isIgnorePath(p) {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.events[p]
+	if !ok {
+		e = &model.IndexEvent{Path: p, CreatedAt: time.Now()}
+		q.events[p] = e
+	}
+	// an upsert following an upsert must not lose the recursive flag
+	if typ == model.IndexEventUpsert && e.Type == model.IndexEventUpsert {
+		e.Recursive = e.Recursive || recursive
+	} else {
+		e.Recursive = recursive
+	}
+	e.Type = typ
+	e.Attempts = 0
+	e.Error = ""
+	e.UpdatedAt = time.Now()
+	if err := db.SaveIndexEvent(e); err != nil {
+		log.Errorf("save index event of %s error: %+v", p, err)
+	}
+	log.Debugf("enqueued index event: %s %s, recursive: %t", typ, p, e.Recursive)
+	q.start()
+}
+
+func (q *eventQueue) start() {
+	if !q.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			q.consume()
+		}
+	}()
+}
+
+// due reports whether the event is quiet long enough, failed events back off exponentially
+func due(e *model.IndexEvent, now time.Time) bool {
+	return now.Sub(e.UpdatedAt) >= indexEventDebounce<<e.Attempts
+}
+
+func (q *eventQueue) consume() {
+	if !q.consuming.CompareAndSwap(false, true) {
+		return
+	}
+	defer q.consuming.Store(false)
+	// a full build is going to pick up everything anyway
+	if instance == nil || Running() {
+		return
+	}
+	progress, err := Progress()
+	if err != nil || !progress.IsDone {
+		return
+	}
+
+	now := time.Now()
+	q.mu.Lock()
+	var events []*model.IndexEvent
+	var snapshots []model.IndexEvent
+	for _, e := range q.events {
+		if due(e, now) {
+			events = append(events, e)
+		}
+	}
+	// shallow paths first, so a parent is indexed before its children
+	sort.Slice(events, func(i, j int) bool {
+		di, dj := strings.Count(events[i].Path, "/"), strings.Count(events[j].Path, "/")
+		if di != dj {
+			return di < dj
+		}
+		return events[i].UpdatedAt.Before(events[j].UpdatedAt)
+	})
+	for _, e := range events {
+		snapshots = append(snapshots, *e)
+	}
+	q.mu.Unlock()
+
+	ctx := context.Background()
+	for i, e := range events {
+		err := applyIndexEvent(ctx, snapshots[i])
+		q.mu.Lock()
+		// the event has been replaced by a newer one while applying
+		if q.events[e.Path] != e || !e.UpdatedAt.Equal(snapshots[i].UpdatedAt) {
+			q.mu.Unlock()
+			continue
+		}
+		if err == nil || e.Attempts+1 >= indexEventMaxAttempts {
+			if err != nil {
+				log.Errorf("drop index event %s %s after %d attempts: %+v", e.Type, e.Path, indexEventMaxAttempts, err)
+			}
+			delete(q.events, e.Path)
+			if err := db.DeleteIndexEventById(e.ID); err != nil {
+				log.Errorf("delete index event of %s error: %+v", e.Path, err)
+			}
+		} else {
+			log.Warnf("apply index event %s %s error: %+v", e.Type, e.Path, err)
+			e.Attempts++
+			e.Error = err.Error()
+			e.UpdatedAt = time.Now()
+			if err := db.SaveIndexEvent(e); err != nil {
+				log.Errorf("save index event of %s error: %+v", e.Path, err)
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+func applyIndexEvent(ctx context.Context, e model.IndexEvent) error {
+	if e.Type == model.IndexEventDelete {
+		media.Delete(e.Path)
+		return instance.Del(ctx, e.Path)
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(e.Path)
+	if err != nil {
+		// the storage may be still loading, retry later
+		return err
+	}
+	if storage.GetStorage().DisableIndex {
+		return nil
+	}
+	obj, err := op.Get(ctx, storage, actualPath)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			media.Delete(e.Path)
+			return instance.Del(ctx, e.Path)
+		}
+		return err
+	}
+	parent, name := path.Split(e.Path)
+	parent = utils.FixAndCleanPath(parent)
+	if obj.IsDir() && !e.Recursive {
+		// a new dir is empty, nothing to do if it is indexed already
+		nodes, err := instance.Get(ctx, parent)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if node.Name == name {
+				return nil
+			}
+		}
+	}
+	if err := instance.Del(ctx, e.Path); err != nil {
+		return err
+	}
+	if err := Index(ctx, parent, obj); err != nil {
+		return err
+	}
+	if obj.IsDir() && e.Recursive {
+		return indexTree(ctx, e.Path, setting.GetInt(conf.MaxIndexDepth, 20))
+	}
+	return nil
+}
+
+// indexTree index everything below dir, dir itself should be indexed by the caller
+func indexTree(ctx context.Context, dir string, maxDepth int) error {
+	type item struct {
+		path  string
+		depth int
+	}
+	ignoreSystemFiles := setting.GetBool(conf.IgnoreSystemFiles)
+	queue := []item{{dir, 1}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		storage, actualPath, err := op.GetStorageAndActualPath(cur.path)
+		if err != nil {
+			return err
+		}
+		objs, err := op.List(ctx, storage, actualPath, model.ListArgs{})
+		if err != nil {
+			return err
+		}
+		var toIndex []ObjWithParent
+		for _, obj := range objs {
+			if ignoreSystemFiles && utils.IsSystemFile(obj.GetName()) {
+				continue
+			}
+			p := path.Join(cur.path, obj.GetName())
+			if isIgnorePath(p) {
+				continue
+			}
+			toIndex = append(toIndex, ObjWithParent{Parent: cur.path, Obj: obj})
+			if obj.IsDir() && (maxDepth < 0 || cur.depth < maxDepth) {
+				queue = append(queue, item{p, cur.depth + 1})
+			}
+		}
+		if err := BatchIndex(ctx, toIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	op.RegisterFsChangeHook(OnFsChange)
+}
@@ -0,0 +1,178 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestEventQueuePush(t *testing.T) {
+	q := &eventQueue{events: make(map[string]*model.IndexEvent)}
+	q.push("/a", model.IndexEventUpsert, true)
+	q.push("/a", model.IndexEventUpsert, false)
+	q.push("/b", model.IndexEventUpsert, false)
+	q.push("/b", model.IndexEventDelete, false)
+	if e := q.events["/a"]; e.Type != model.IndexEventUpsert || !e.Recursive {
+		t.Errorf("upserts of /a are merged into %s, recursive %t", e.Type, e.Recursive)
+	}
+	if e := q.events["/b"]; e.Type != model.IndexEventDelete {
+		t.Errorf("the delete of /b is replaced by %s", e.Type)
+	}
+	// the queue is recovered from the database after a restart
+	events, err := db.GetIndexEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("%d events are persisted, want 2: %+v", len(events), events)
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		age      time.Duration
+		attempts int
+		want     bool
+	}{
+		{time.Second, 0, false},
+		{indexEventDebounce, 0, true},
+		{indexEventDebounce, 1, false},
+		{indexEventDebounce * 4, 2, true},
+	} {
+		e := &model.IndexEvent{UpdatedAt: now.Add(-c.age), Attempts: c.attempts}
+		if got := due(e, now); got != c.want {
+			t.Errorf("event of %s after %d attempts is due: %t, want %t", c.age, c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestAutoUpdateHook(t *testing.T) {
+	conf.Conf.BleveDir = filepath.Join(t.TempDir(), "bleve")
+	if err := Init("bleve"); err != nil {
+		t.Fatal(err)
+	}
+	defer Init("none")
+	if _, err := op.HandleSettingItemHook(&model.SettingItem{Key: conf.AutoUpdateIndex, Value: "true"}); err == nil {
+		t.Error("auto update is enabled with bleve")
+	}
+	if _, err := op.HandleSettingItemHook(&model.SettingItem{Key: conf.AutoUpdateIndex, Value: "false"}); err != nil {
+		t.Error(err)
+	}
+}
+
+// newTestQueue returns a queue whose consumer is run by the test
+func newTestQueue() *eventQueue {
+	q := &eventQueue{events: make(map[string]*model.IndexEvent)}
+	q.started.Store(true)
+	return q
+}
+
+func TestDebounce(t *testing.T) {
+	if err := Init("database_non_full_text"); err != nil {
+		t.Fatal(err)
+	}
+	defer Init("none")
+	WriteProgress(&model.IndexProgress{IsDone: true})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/queue",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, dir),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer op.DeleteStorageById(ctx, id)
+	if err = ClearIndexQueue(); err != nil {
+		t.Fatal(err)
+	}
+	defer ClearIndexQueue()
+
+	q := newTestQueue()
+	for i := 0; i < 3; i++ {
+		q.push("/queue/a.txt", model.IndexEventUpsert, false)
+	}
+	e := q.events["/queue/a.txt"]
+	if len(q.events) != 1 {
+		t.Fatalf("%d events for the writes to a path", len(q.events))
+	}
+	// a write within the debounce delays the event again
+	e.UpdatedAt = time.Now().Add(-indexEventDebounce)
+	q.push("/queue/a.txt", model.IndexEventUpsert, false)
+	q.consume()
+	if len(q.events) != 1 {
+		t.Fatal("the event is applied before the path is quiet")
+	}
+	e.UpdatedAt = time.Now().Add(-indexEventDebounce)
+	q.consume()
+	if len(q.events) != 0 {
+		t.Fatalf("the event is left after being applied: %+v", q.events)
+	}
+	nodes, err := instance.Get(ctx, "/queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Name != "a.txt" {
+		t.Fatalf("indexed %+v", nodes)
+	}
+	if events, _ := db.GetIndexEvents(); len(events) != 0 {
+		t.Errorf("the applied event is left in the database: %+v", events)
+	}
+}
+
+func TestRecoverAndClear(t *testing.T) {
+	if err := ClearIndexQueue(); err != nil {
+		t.Fatal(err)
+	}
+	q := newTestQueue()
+	q.push("/recover/a", model.IndexEventUpsert, true)
+	q.push("/recover/b", model.IndexEventDelete, false)
+
+	// a restart loads the events left in the database
+	old := indexQueue
+	indexQueue = newTestQueue()
+	defer func() { indexQueue = old }()
+	InitIndexQueue()
+	events := IndexQueue()
+	if len(events) != 2 {
+		t.Fatalf("recovered %+v", events)
+	}
+	if events[0].Path != "/recover/a" || !events[0].Recursive || events[1].Type != model.IndexEventDelete {
+		t.Errorf("recovered %+v", events)
+	}
+
+	if err := ClearIndexQueue(); err != nil {
+		t.Fatal(err)
+	}
+	if events = IndexQueue(); len(events) != 0 {
+		t.Errorf("left %+v after clearing", events)
+	}
+	if events, _ = db.GetIndexEvents(); len(events) != 0 {
+		t.Errorf("left %+v in the database after clearing", events)
+	}
+}
@@ -3,12 +3,16 @@ package search
 import (
 	"context"
 	"fmt"
+	"path"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/media"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -43,11 +47,24 @@ func Init(mode string) error {
 		log.Errorf("init searcher error: %+v", err)
 	} else {
 		instance = i
+		// searchers that compute the diff themselves report the added nodes back
+		if h, ok := i.(interface {
+			SetAddedHook(func(nodes []model.SearchNode))
+		}); ok {
+			h.SetAddedHook(EnqueueContent)
+		}
 	}
 	return err
 }
 
 func Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
+	if req.Media != nil {
+		// the media info is kept in the database whatever the search index is
+		return db.SearchMediaInfos(req)
+	}
+	if _, ok := instance.(searcher.ContentSearcher); req.Content && !ok {
+		return nil, 0, errors.WithMessage(errs.NotSupport, "content search is not supported by current index")
+	}
 	return instance.Search(ctx, req)
 }
 
@@ -55,12 +72,18 @@ func Index(ctx context.Context, parent string, obj model.Obj) error {
 	if instance == nil {
 		return errs.SearchNotAvailable
 	}
-	return instance.Index(ctx, model.SearchNode{
+	node := model.SearchNode{
 		Parent: parent,
 		Name:   obj.GetName(),
 		IsDir:  obj.IsDir(),
 		Size:   obj.GetSize(),
-	})
+	}
+	err := instance.Index(ctx, node)
+	if err == nil {
+		EnqueueContent([]model.SearchNode{node})
+		enqueueMedia([]model.SearchNode{node})
+	}
+	return err
 }
 
 type ObjWithParent struct {
@@ -84,7 +107,22 @@ func BatchIndex(ctx context.Context, objs []ObjWithParent) error {
 			Size:   objs[i].GetSize(),
 		})
 	}
-	return instance.BatchIndex(ctx, searchNodes)
+	err := instance.BatchIndex(ctx, searchNodes)
+	if err == nil {
+		EnqueueContent(searchNodes)
+		enqueueMedia(searchNodes)
+	}
+	return err
+}
+
+func enqueueMedia(nodes []model.SearchNode) {
+	var paths []string
+	for _, node := range nodes {
+		if !node.IsDir {
+			paths = append(paths, path.Join(node.Parent, node.Name))
+		}
+	}
+	media.Enqueue(paths...)
 }
 
 func init() {
@@ -92,4 +130,11 @@ func init() {
 		log.Debugf("searcher init, mode: %s", item.Value)
 		return Init(item.Value)
 	})
+	// the search index is saved before this one, so the instance is already the new one
+	op.RegisterSettingItemHook(conf.AutoUpdateIndex, func(item *model.SettingItem) error {
+		if item.Value == "true" && instance != nil && !instance.Config().AutoUpdate {
+			return fmt.Errorf("auto update is not supported by %s index, rebuild the index instead", instance.Config().Name)
+		}
+		return nil
+	})
 }
@@ -29,3 +29,9 @@ type Searcher interface {
 	// Clear all index
 	Clear(ctx context.Context) error
 }
+
+// ContentSearcher is implemented by searchers that can index the text of files
+type ContentSearcher interface {
+	// IndexContent attach extracted text to the file node
+	IndexContent(ctx context.Context, node model.SearchNode, content string) error
+}
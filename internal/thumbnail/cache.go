@@ -0,0 +1,182 @@
+package thumbnail
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// store keeps the generated images as files named by the hash of their key,
+// the least recently used ones are evicted when the total size exceeds the limit
+type store struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	lru   *list.List // of *item, the front is the most recently used
+	items map[string]*list.Element
+	size  int64
+}
+
+type item struct {
+	name string
+	size int64
+}
+
+func newStore(dir string, maxSize int64) (*store, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("size of the thumbnail cache must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s := &store{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+	return s, nil
+}
+
+func (s *store) load() error {
+	type found struct {
+		item
+		modified time.Time
+	}
+	var items []found
+	err := filepath.WalkDir(s.dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.Contains(d.Name(), ".tmp") {
+			return os.Remove(p)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.dir, p)
+		items = append(items, found{
+			item:     item{name: filepath.ToSlash(rel), size: info.Size()},
+			modified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].modified.After(items[j].modified)
+	})
+	for i := range items {
+		it := items[i].item
+		s.items[it.name] = s.lru.PushBack(&it)
+		s.size += it.size
+	}
+	log.Infof("thumbnail cache: %d thumbnails, %dMB in %s", len(items), s.size/utils.MB, s.dir)
+	return nil
+}
+
+func itemName(key string) string {
+	sum := sha1.Sum([]byte(key))
+	h := hex.EncodeToString(sum[:])
+	return h[:2] + "/" + h
+}
+
+func (s *store) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+// get returns the cached thumbnail
+func (s *store) get(key string) ([]byte, bool) {
+	name := itemName(key)
+	s.mu.Lock()
+	e, ok := s.items[name]
+	if ok {
+		s.lru.MoveToFront(e)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	p := s.path(name)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		s.mu.Lock()
+		if e, ok := s.items[name]; ok {
+			s.removeLocked(name, e)
+		}
+		s.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return data, true
+}
+
+// put stores a thumbnail, the file is renamed into place so that readers never see a partial one
+func (s *store) put(key string, data []byte) error {
+	name := itemName(key)
+	p := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+		return errors.WithStack(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".tmp*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithStack(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[name]; ok {
+		s.removeLocked(name, e)
+	}
+	s.items[name] = s.lru.PushFront(&item{name: name, size: int64(len(data))})
+	s.size += int64(len(data))
+	s.evict()
+	return nil
+}
+
+func (s *store) removeLocked(name string, e *list.Element) {
+	s.lru.Remove(e)
+	delete(s.items, name)
+	s.size -= e.Value.(*item).size
+}
+
+func (s *store) evict() {
+	for s.size > s.maxSize && s.lru.Len() > 0 {
+		e := s.lru.Back()
+		name := e.Value.(*item).name
+		s.removeLocked(name, e)
+		if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to evict thumbnail: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package thumbnail
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newStore(dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err = s.put(key, bytes.Repeat([]byte(key), 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := s.get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	// a was used last, so b is evicted
+	if err = s.put("c", bytes.Repeat([]byte("c"), 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	if data, ok := s.get("a"); !ok || !bytes.Equal(data, bytes.Repeat([]byte("a"), 10)) {
+		t.Fatalf("unexpected a: %q", data)
+	}
+
+	// the thumbnails are indexed again after a restart
+	s, err = newStore(dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.size != 20 {
+		t.Fatalf("expected 20 bytes after reload, got %d", s.size)
+	}
+	if _, ok := s.get("c"); !ok {
+		t.Fatal("c should survive the reload")
+	}
+}
+
+func TestSize(t *testing.T) {
+	for in, want := range map[int]int{0: DefaultSize, 1: 128, 128: 128, 200: 256, 700: 1024, 5000: 1024} {
+		if got := Size(in); got != want {
+			t.Errorf("Size(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
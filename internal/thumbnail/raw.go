@@ -0,0 +1,280 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"io"
+	"sort"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/disintegration/imaging"
+	"github.com/pkg/errors"
+)
+
+const (
+	rawHeadSize = 256 * 1024 // the IFDs are near the start, read them at once
+	maxIFDs     = 32
+	maxEntries  = 1024
+	// maxPreviewSize bounds the jpeg read into memory, the location of it comes from the file
+	maxPreviewSize = 64 << 20
+)
+
+type preview struct {
+	offset      int64
+	length      int64
+	orientation int
+}
+
+// rawPreview decodes the largest jpeg embedded in a raw photo, most cameras store one
+// of the full resolution next to the sensor data which no Go decoder can read
+func rawPreview(ctx context.Context, rr model.RangeReaderIF, file model.Obj) (image.Image, error) {
+	r := &readerAt{ctx: ctx, rr: rr, size: file.GetSize()}
+	var (
+		p   preview
+		err error
+	)
+	if utils.Ext(file.GetName()) == "raf" {
+		p, err = rafPreview(r)
+	} else {
+		p, err = tiffPreview(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.offset < 0 || p.length <= 0 || p.length > maxPreviewSize || p.offset+p.length > r.size {
+		return nil, errors.Errorf("invalid preview of %d bytes at %d", p.length, p.offset)
+	}
+	data := make([]byte, p.length)
+	if _, err = r.ReadAt(data, p.offset); err != nil {
+		return nil, err
+	}
+	// the orientation of the previews is recorded in the raw file rather than in themselves
+	img, err := decodeLimited(bytes.NewReader(data), p.orientation == 0)
+	if err != nil {
+		return nil, err
+	}
+	return orient(img, p.orientation), nil
+}
+
+// rafPreview reads the location of the preview from the header of Fujifilm raw files
+func rafPreview(r io.ReaderAt) (preview, error) {
+	var hdr [92]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return preview{}, err
+	}
+	if string(hdr[:15]) != "FUJIFILMCCD-RAW" {
+		return preview{}, errors.New("not a raf file")
+	}
+	return preview{
+		offset: int64(binary.BigEndian.Uint32(hdr[84:])),
+		length: int64(binary.BigEndian.Uint32(hdr[88:])),
+	}, nil
+}
+
+type ifdEntry struct {
+	tag   uint16
+	count uint32
+	value uint32 // the value itself if it fits, the offset of it otherwise
+}
+
+// tiffPreview walks the IFDs of the tiff based raw files for the jpegs they point to
+func tiffPreview(r io.ReaderAt) (preview, error) {
+	var hdr [8]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return preview{}, err
+	}
+	var bo binary.ByteOrder
+	switch string(hdr[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return preview{}, errors.New("not a tiff based raw file")
+	}
+	// the magic number is not checked, ORF and RW2 use their own
+	var (
+		candidates  []preview
+		orientation = 1
+		queue       = []int64{int64(bo.Uint32(hdr[4:]))}
+		visited     = make(map[int64]bool)
+	)
+	for len(queue) > 0 && len(visited) < maxIFDs {
+		off := queue[0]
+		queue = queue[1:]
+		if off == 0 || visited[off] {
+			continue
+		}
+		visited[off] = true
+		entries, next, err := readIFD(r, bo, off)
+		if err != nil {
+			continue
+		}
+		var jpegOff, jpegLen, stripOff, stripLen, compression uint32
+		for _, e := range entries {
+			switch e.tag {
+			case 0x0112: // Orientation of the first IFD
+				if len(visited) == 1 {
+					orientation = int(e.value)
+				}
+			case 0x0103: // Compression
+				compression = e.value
+			case 0x0111: // StripOffsets
+				if e.count == 1 {
+					stripOff = e.value
+				}
+			case 0x0117: // StripByteCounts
+				if e.count == 1 {
+					stripLen = e.value
+				}
+			case 0x0201: // JPEGInterchangeFormat
+				jpegOff = e.value
+			case 0x0202: // JPEGInterchangeFormatLength
+				jpegLen = e.value
+			case 0x002e: // JpgFromRaw of RW2
+				candidates = append(candidates, preview{offset: int64(e.value), length: int64(e.count)})
+			case 0x014a: // SubIFDs
+				if e.count == 1 {
+					queue = append(queue, int64(e.value))
+				} else if e.count <= maxIFDs {
+					buf := make([]byte, 4*e.count)
+					if _, err := r.ReadAt(buf, int64(e.value)); err == nil {
+						for i := uint32(0); i < e.count; i++ {
+							queue = append(queue, int64(bo.Uint32(buf[4*i:])))
+						}
+					}
+				}
+			}
+		}
+		if jpegLen > 0 {
+			candidates = append(candidates, preview{offset: int64(jpegOff), length: int64(jpegLen)})
+		}
+		// the sensor data may be a lossless jpeg as well, it is told apart by baselineJPEG below
+		if (compression == 6 || compression == 7) && stripLen > 0 {
+			candidates = append(candidates, preview{offset: int64(stripOff), length: int64(stripLen)})
+		}
+		queue = append(queue, int64(next))
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].length > candidates[j].length
+	})
+	for _, c := range candidates {
+		if baselineJPEG(r, c) {
+			c.orientation = orientation
+			return c, nil
+		}
+	}
+	return preview{}, errors.New("no preview found in the raw file")
+}
+
+func readIFD(r io.ReaderAt, bo binary.ByteOrder, off int64) ([]ifdEntry, uint32, error) {
+	var n [2]byte
+	if _, err := r.ReadAt(n[:], off); err != nil {
+		return nil, 0, err
+	}
+	count := int(bo.Uint16(n[:]))
+	if count == 0 || count > maxEntries {
+		return nil, 0, errors.New("invalid IFD")
+	}
+	buf := make([]byte, count*12+4)
+	if _, err := r.ReadAt(buf, off+2); err != nil {
+		return nil, 0, err
+	}
+	entries := make([]ifdEntry, count)
+	for i := range entries {
+		b := buf[i*12:]
+		e := ifdEntry{tag: bo.Uint16(b), count: bo.Uint32(b[4:]), value: bo.Uint32(b[8:])}
+		if typ := bo.Uint16(b[2:]); typ == 3 && e.count == 1 { // SHORT
+			e.value = uint32(bo.Uint16(b[8:]))
+		}
+		entries[i] = e
+	}
+	return entries, bo.Uint32(buf[count*12:]), nil
+}
+
+// baselineJPEG reports whether the data is a jpeg that Go can decode,
+// the markers are walked up to the start of frame
+func baselineJPEG(r io.ReaderAt, p preview) bool {
+	buf := make([]byte, min(p.length, 64*1024))
+	if _, err := r.ReadAt(buf, p.offset); err != nil || len(buf) < 4 || buf[0] != 0xff || buf[1] != 0xd8 {
+		return false
+	}
+	for i := 2; i+4 <= len(buf); {
+		if buf[i] != 0xff {
+			return false
+		}
+		switch m := buf[i+1]; {
+		case m == 0xc0 || m == 0xc1 || m == 0xc2:
+			return true
+		case m >= 0xc3 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc:
+			return false
+		case m == 0xd9 || m == 0xda:
+			return false
+		}
+		i += 2 + int(binary.BigEndian.Uint16(buf[i+2:]))
+	}
+	return false
+}
+
+func orient(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// readerAt reads the head of the file once, the rest with range requests
+type readerAt struct {
+	ctx  context.Context
+	rr   model.RangeReaderIF
+	size int64
+	head []byte
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > r.size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if r.head == nil {
+		head, err := r.read(0, min(r.size, rawHeadSize))
+		if err != nil {
+			return 0, err
+		}
+		r.head = head
+	}
+	if off+int64(len(p)) <= int64(len(r.head)) {
+		return copy(p, r.head[off:]), nil
+	}
+	data, err := r.read(off, int64(len(p)))
+	return copy(p, data), err
+}
+
+func (r *readerAt) read(off, length int64) ([]byte, error) {
+	rc, err := r.rr.RangeRead(r.ctx, http_range.Range{Start: off, Length: length})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	buf := make([]byte, length)
+	if _, err = io.ReadFull(rc, buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf, nil
+}
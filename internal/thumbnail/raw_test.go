@@ -0,0 +1,108 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+// tiffWithPreview builds a little endian tiff whose first IFD points to a jpeg
+func tiffWithPreview(t *testing.T, orientation uint16) ([]byte, []byte) {
+	t.Helper()
+	var preview bytes.Buffer
+	if err := jpeg.Encode(&preview, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	const entries = 3
+	jpegOff := uint32(8 + 2 + entries*12 + 4)
+	buf := new(bytes.Buffer)
+	le := binary.LittleEndian
+	buf.WriteString("II")
+	_ = binary.Write(buf, le, uint16(42))
+	_ = binary.Write(buf, le, uint32(8))
+	_ = binary.Write(buf, le, uint16(entries))
+	entry := func(tag, typ uint16, value uint32) {
+		_ = binary.Write(buf, le, tag)
+		_ = binary.Write(buf, le, typ)
+		_ = binary.Write(buf, le, uint32(1))
+		_ = binary.Write(buf, le, value)
+	}
+	entry(0x0112, 3, uint32(orientation))
+	entry(0x0201, 4, jpegOff)
+	entry(0x0202, 4, uint32(preview.Len()))
+	_ = binary.Write(buf, le, uint32(0))
+	buf.Write(preview.Bytes())
+	return buf.Bytes(), preview.Bytes()
+}
+
+func TestTiffPreview(t *testing.T) {
+	data, want := tiffWithPreview(t, 6)
+	p, err := tiffPreview(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := data[p.offset : p.offset+p.length]; !bytes.Equal(got, want) {
+		t.Fatal("unexpected preview location")
+	}
+	if p.orientation != 6 {
+		t.Fatalf("expected orientation 6, got %d", p.orientation)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := orient(img, p.orientation).Bounds(); b.Dx() != 2 || b.Dy() != 4 {
+		t.Fatalf("expected the preview to be rotated, got %v", b)
+	}
+}
+
+func TestTiffPreviewLossless(t *testing.T) {
+	data, _ := tiffWithPreview(t, 1)
+	// turn the start of frame into the lossless one used for sensor data
+	i := bytes.Index(data, []byte{0xff, 0xc0})
+	data[i+1] = 0xc3
+	if _, err := tiffPreview(bytes.NewReader(data)); err == nil {
+		t.Fatal("lossless jpeg should not be taken as a preview")
+	}
+}
+
+func TestRawPreviewBounds(t *testing.T) {
+	data, _ := tiffWithPreview(t, 1)
+	// claim a preview far beyond the end of the file
+	binary.LittleEndian.PutUint32(data[8+2+2*12+8:], 1<<31)
+	rr := stream.RangeReaderFunc(func(ctx context.Context, r http_range.Range) (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(bytes.NewReader(data), r.Start, r.Length)), nil
+	})
+	file := &model.Object{Name: "a.dng", Size: int64(len(data))}
+	if _, err := rawPreview(context.Background(), rr, file); err == nil {
+		t.Fatal("the preview out of the file is read")
+	}
+}
+
+func TestDecodeLimited(t *testing.T) {
+	// a gif header claiming a screen of 65535x65535
+	header := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+	if _, err := decodeLimited(bytes.NewReader(header), true); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("decoded a huge image with %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	img, err := decodeLimited(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 2 {
+		t.Fatalf("decoded %v", b)
+	}
+}
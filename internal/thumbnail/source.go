@@ -0,0 +1,145 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/disintegration/imaging"
+	"github.com/pkg/errors"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	_ "golang.org/x/image/webp"
+)
+
+type kind int
+
+const (
+	kindNone kind = iota
+	kindImage
+	kindRaw
+	kindFFmpegImage
+	kindVideo
+)
+
+const (
+	videoPosition = 0.2 // of the duration
+	// maxPixels bounds the images decoded, a small file may claim to be huge and take all the memory
+	maxPixels = 128 << 20
+)
+
+var (
+	imageExts       = []string{"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp"}
+	rawExts         = []string{"dng", "cr2", "nef", "nrw", "arw", "srf", "sr2", "orf", "pef", "srw", "rw2", "raf"}
+	ffmpegImageExts = []string{"heic", "heif", "avif"}
+)
+
+func sourceKind(name string) kind {
+	ext := utils.Ext(name)
+	switch {
+	case utils.SliceContains(imageExts, ext):
+		return kindImage
+	case utils.SliceContains(rawExts, ext):
+		return kindRaw
+	case utils.SliceContains(ffmpegImageExts, ext):
+		return kindFFmpegImage
+	case utils.GetFileType(name) == conf.VIDEO:
+		return kindVideo
+	}
+	return kindNone
+}
+
+func decodeSource(ctx context.Context, rr model.RangeReaderIF, file model.Obj) (image.Image, error) {
+	switch sourceKind(file.GetName()) {
+	case kindImage:
+		return decode(ctx, rr)
+	case kindRaw:
+		return rawPreview(ctx, rr, file)
+	case kindFFmpegImage:
+		return decodeFFmpeg(ctx, rr, file)
+	case kindVideo:
+		return snapshot(ctx, rr, file)
+	}
+	return nil, errors.Errorf("unsupported file: %s", file.GetName())
+}
+
+func decode(ctx context.Context, rr model.RangeReaderIF) (image.Image, error) {
+	rc, err := rr.RangeRead(ctx, http_range.Range{Length: -1})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return decodeLimited(rc, true)
+}
+
+// decodeLimited reads the size of the image from its header before decoding it
+func decodeLimited(r io.Reader, autoOrientation bool) (image.Image, error) {
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, errors.Errorf("image of %dx%d is too large", cfg.Width, cfg.Height)
+	}
+	img, err := imaging.Decode(io.MultiReader(&head, r), imaging.AutoOrientation(autoOrientation))
+	return img, errors.WithStack(err)
+}
+
+// extractFrame runs ffmpeg on the url and decodes the single frame it outputs
+func extractFrame(url string, input ffmpeg.KwArgs) (image.Image, error) {
+	buf := bytes.NewBuffer(nil)
+	err := ffmpeg.Input(url, input).
+		Output("pipe:", ffmpeg.KwArgs{"vframes": 1, "format": "image2", "vcodec": "png"}).
+		GlobalArgs("-loglevel", "error").Silent(true).
+		WithTimeout(ffmpegTimeout).
+		WithOutput(buf).
+		Run()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	img, err := decodeLimited(buf, false)
+	return img, errors.WithStack(err)
+}
+
+// decodeFFmpeg decodes the formats Go has no decoder for, the primary image of a HEIF made of
+// tiles is only assembled by recent ffmpeg versions
+func decodeFFmpeg(ctx context.Context, rr model.RangeReaderIF, file model.Obj) (image.Image, error) {
+	url, stop, err := net.ServeLoopback(ctx, file.GetName(), file.ModTime(), file.GetSize(), rr)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	return extractFrame(url, ffmpeg.KwArgs{})
+}
+
+func snapshot(ctx context.Context, rr model.RangeReaderIF, file model.Obj) (image.Image, error) {
+	url, stop, err := net.ServeLoopback(ctx, file.GetName(), file.ModTime(), file.GetSize(), rr)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	jsonOutput, err := ffmpeg.ProbeWithTimeout(url, ffmpegTimeout, nil)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err = json.Unmarshal([]byte(jsonOutput), &probe); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	// noaccurate_seek keeps ffmpeg from failing when the position is within the last frame
+	return extractFrame(url, ffmpeg.KwArgs{"ss": fmt.Sprintf("%f", duration*videoPosition), "noaccurate_seek": ""})
+}
@@ -0,0 +1,156 @@
+// Package thumbnail generates thumbnails and resized copies of the images and videos of any storage.
+//
+// The source is read through the link of the storage. Common image formats are decoded in Go and the
+// previews embedded in raw photos are extracted, while videos, HEIF and AVIF are handed to ffmpeg over
+// a loopback http server so that it can seek with range requests. The results are kept in a
+// size-bounded disk cache keyed by the path, size and modified time of the source and the options.
+package thumbnail
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// Sizes are the widths thumbnails are generated in, requests are rounded up to one of them
+var Sizes = []int{128, 256, 512, 1024}
+
+const (
+	DefaultSize   = 256
+	ffmpegTimeout = time.Minute
+)
+
+var (
+	cache         *store
+	sem           *semaphore.Weighted
+	maxSourceSize int64
+	hasFFmpeg     bool
+	g             singleflight.Group[[]byte]
+)
+
+// Init opens the cache, thumbnails are not offered when it fails
+func Init(c conf.Thumbnail) error {
+	s, err := newStore(c.Dir, int64(c.MaxSize)*utils.MB)
+	if err != nil {
+		return err
+	}
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = 2
+	}
+	cache = s
+	sem = semaphore.NewWeighted(int64(concurrency))
+	maxSourceSize = int64(c.MaxSourceSize) * utils.MB
+	_, err = exec.LookPath("ffmpeg")
+	hasFFmpeg = err == nil
+	return nil
+}
+
+// Enabled reports whether the service has been initialized
+func Enabled() bool {
+	return cache != nil
+}
+
+// Supported reports whether a thumbnail can be generated for the object
+func Supported(obj model.Obj) bool {
+	if !Enabled() || obj.IsDir() {
+		return false
+	}
+	switch sourceKind(obj.GetName()) {
+	case kindImage:
+		return maxSourceSize <= 0 || obj.GetSize() <= maxSourceSize
+	case kindRaw:
+		return true
+	case kindVideo, kindFFmpegImage:
+		return hasFFmpeg
+	}
+	return false
+}
+
+// CanTransform reports whether the object is an image that Transform accepts
+func CanTransform(obj model.Obj) bool {
+	return sourceKind(obj.GetName()) != kindVideo && Supported(obj)
+}
+
+// Size rounds the requested width up to one of the Sizes
+func Size(width int) int {
+	if width <= 0 {
+		return DefaultSize
+	}
+	for _, s := range Sizes {
+		if width <= s {
+			return s
+		}
+	}
+	return Sizes[len(Sizes)-1]
+}
+
+// Get returns the jpeg thumbnail of the object at path, generating it if it is not cached
+func Get(ctx context.Context, path string, obj model.Obj, width int) ([]byte, error) {
+	if !Supported(obj) {
+		return nil, errors.New("thumbnail is not supported for this file")
+	}
+	return get(ctx, path, obj, Options{Width: Size(width), Fit: FitInside, Format: FormatJPEG, Quality: 80})
+}
+
+// Transform returns the image at path resized and converted as the options say,
+// the content type of the result is left to be sniffed as webp falls back to jpeg without ffmpeg
+func Transform(ctx context.Context, path string, obj model.Obj, opts Options) ([]byte, error) {
+	if !CanTransform(obj) {
+		return nil, errors.New("transforming is not supported for this file")
+	}
+	return get(ctx, path, obj, opts.normalize())
+}
+
+func get(ctx context.Context, path string, obj model.Obj, opts Options) ([]byte, error) {
+	key := fmt.Sprintf("%s\n%d\n%d\n%s", path, obj.GetSize(), obj.ModTime().UnixNano(), opts)
+	if data, ok := cache.get(key); ok {
+		return data, nil
+	}
+	data, err, _ := g.Do(key, func() ([]byte, error) {
+		// the other callers wait for the same result, so finish it even if this one leaves
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*ffmpegTimeout)
+		defer cancel()
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer sem.Release(1)
+		data, err := generate(ctx, path, opts)
+		if err != nil {
+			return nil, err
+		}
+		return data, cache.put(key, data)
+	})
+	return data, err
+}
+
+func generate(ctx context.Context, path string, opts Options) ([]byte, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return nil, err
+	}
+	link, file, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return nil, err
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(file.GetSize(), link)
+	if err != nil {
+		return nil, err
+	}
+	src, err := decodeSource(ctx, rr, file)
+	if err != nil {
+		return nil, err
+	}
+	return encode(ctx, opts.apply(src), opts)
+}
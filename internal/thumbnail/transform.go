@@ -0,0 +1,155 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+const (
+	FitInside = "inside" // scale down to fit in the box, keeping the aspect ratio
+	FitCover  = "cover"  // scale and crop to fill the box, keeping the aspect ratio
+	FitFill   = "fill"   // stretch to the box
+
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+
+	MaxDimension = 4096
+)
+
+// the options are snapped to these, so that the requests can't fill the cache with copies of an image
+// differing by a pixel or a point of quality
+var (
+	dimensionBuckets = []int{64, 128, 256, 384, 512, 768, 1024, 1536, 2048, 3072, MaxDimension}
+	qualityBuckets   = []int{50, 65, 80, 90, 100}
+)
+
+// snap rounds v up to one of the buckets, 0 is kept
+func snap(v int, buckets []int) int {
+	if v <= 0 {
+		return 0
+	}
+	for _, b := range buckets {
+		if v <= b {
+			return b
+		}
+	}
+	return buckets[len(buckets)-1]
+}
+
+// Options describe the image to produce, a zero width or height follows the aspect ratio of the source
+type Options struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+func (o Options) String() string {
+	return fmt.Sprintf("%dx%d,%s,%s,%d", o.Width, o.Height, o.Fit, o.Format, o.Quality)
+}
+
+func (o Options) normalize() Options {
+	o.Width = snap(o.Width, dimensionBuckets)
+	o.Height = snap(o.Height, dimensionBuckets)
+	switch o.Fit {
+	case FitCover, FitFill:
+		if o.Width == 0 || o.Height == 0 {
+			o.Fit = FitInside
+		}
+	default:
+		o.Fit = FitInside
+	}
+	switch o.Format {
+	case "jpg":
+		o.Format = FormatJPEG
+	case FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		o.Format = FormatJPEG
+	}
+	if o.Quality <= 0 || o.Quality > 100 {
+		o.Quality = 80
+	}
+	o.Quality = snap(o.Quality, qualityBuckets)
+	return o
+}
+
+// apply resizes the image, it is never scaled up when fitting inside the box
+func (o Options) apply(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	switch o.Fit {
+	case FitCover:
+		return imaging.Fill(img, o.Width, o.Height, imaging.Center, imaging.Lanczos)
+	case FitFill:
+		return imaging.Resize(img, o.Width, o.Height, imaging.Lanczos)
+	}
+	bw, bh := o.Width, o.Height
+	if bw == 0 || bw > w {
+		bw = w
+	}
+	if bh == 0 || bh > h {
+		bh = h
+	}
+	if bw == w && bh == h {
+		return img
+	}
+	return imaging.Fit(img, bw, bh, imaging.Lanczos)
+}
+
+func encode(ctx context.Context, img image.Image, o Options) ([]byte, error) {
+	var buf bytes.Buffer
+	switch o.Format {
+	case FormatPNG:
+		if err := imaging.Encode(&buf, img, imaging.PNG); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return buf.Bytes(), nil
+	case FormatWebP:
+		// there is no webp encoder in Go, ffmpeg is used when it is built with libwebp
+		if hasFFmpeg {
+			data, err := encodeWebP(ctx, img, o.Quality)
+			if err == nil {
+				return data, nil
+			}
+			log.Warnf("failed to encode webp, falling back to jpeg: %+v", err)
+		}
+	}
+	// jpeg has no alpha channel, flatten transparent images onto white
+	bg := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+	img = imaging.Overlay(bg, img, image.Pt(0, 0), 1)
+	if err := imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(o.Quality)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeWebP(ctx context.Context, img image.Image, quality int) ([]byte, error) {
+	var src bytes.Buffer
+	if err := imaging.Encode(&src, img, imaging.PNG); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(nil)
+	err := ffmpeg.Input("pipe:", ffmpeg.KwArgs{"f": "png_pipe"}).
+		Output("pipe:", ffmpeg.KwArgs{"vcodec": "libwebp", "quality": quality, "format": "webp"}).
+		GlobalArgs("-loglevel", "error").Silent(true).
+		WithTimeout(ffmpegTimeout).
+		WithInput(&src).
+		WithOutput(buf).
+		Run()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
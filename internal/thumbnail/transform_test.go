@@ -0,0 +1,18 @@
+package thumbnail
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		in, want Options
+	}{
+		{Options{}, Options{Fit: FitInside, Format: FormatJPEG, Quality: 80}},
+		{Options{Width: 300, Height: 201, Fit: FitCover, Format: "jpg", Quality: 81}, Options{Width: 384, Height: 256, Fit: FitCover, Format: FormatJPEG, Quality: 90}},
+		{Options{Width: 9999, Fit: FitCover, Format: FormatWebP, Quality: 1}, Options{Width: MaxDimension, Fit: FitInside, Format: FormatWebP, Quality: 50}},
+		{Options{Width: -1, Height: 64, Fit: FitFill, Format: "gif", Quality: 101}, Options{Height: 64, Fit: FitInside, Format: FormatJPEG, Quality: 80}},
+	} {
+		if got := c.in.normalize(); got != c.want {
+			t.Errorf("%s is normalized to %s, want %s", c.in, got, c.want)
+		}
+	}
+}
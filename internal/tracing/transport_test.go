@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// enable records the spans like Init does with an exporter
+func enable(t *testing.T, trust bool) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	enabled, trustIncoming = true, trust
+	t.Cleanup(func() { enabled, trustIncoming = false, false })
+	return recorder
+}
+
+func TestTransport(t *testing.T) {
+	recorder := enable(t, false)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	ctx, parent := Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/file", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	if len(recorder.Ended()) != 0 {
+		t.Errorf("the span ended before the body is closed")
+	}
+	_ = resp.Body.Close()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	client := spans[0]
+	if client.Name() != "HTTP GET" || client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("unexpected client span %s with parent %s", client.Name(), client.Parent().SpanID())
+	}
+	if want := "00-" + client.SpanContext().TraceID().String() + "-" + client.SpanContext().SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+func TestTransportDisabled(t *testing.T) {
+	traceparent := "unset"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer srv.Close()
+	resp, err := (&http.Client{Transport: Transport(nil)}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if traceparent != "" {
+		t.Errorf("traceparent %q is sent with tracing disabled", traceparent)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	header := http.Header{"Traceparent": {"00-" + traceID + "-00f067aa0ba902b7-01"}}
+	enable(t, false)
+	if id := TraceID(Extract(context.Background(), header)); id != "" {
+		t.Errorf("the trace %s of an untrusted client is continued", id)
+	}
+	trustIncoming = true
+	if id := TraceID(Extract(context.Background(), header)); id != traceID {
+		t.Errorf("extracted trace %q, want %s", id, traceID)
+	}
+}
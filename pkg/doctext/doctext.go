@@ -0,0 +1,267 @@
+// Package doctext extracts plain text from common document formats in pure Go.
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported document format")
+	ErrTooLarge    = errors.New("document expands beyond the size limit")
+)
+
+// maxUnzipSize limits the bytes read from the members of a zip container, against zip bombs
+const maxUnzipSize = 64 << 20
+
+// Extract returns the text content of a document, at most maxLen bytes when maxLen > 0.
+// Unknown extensions are treated as plain text and rejected if they look binary.
+func Extract(ext string, data []byte, maxLen int) (text string, err error) {
+	defer func() {
+		// third-party parsers may panic on malformed input
+		if r := recover(); r != nil {
+			err = fmt.Errorf("extract %s: %v", ext, r)
+		}
+	}()
+	switch ext {
+	case "pdf":
+		text, err = extractPDF(data)
+	case "docx":
+		text, err = extractZipXML(data, []string{"word/document.xml"}, docxBreaks)
+	case "odt", "odp", "ods":
+		text, err = extractZipXML(data, []string{"content.xml"}, odfBreaks)
+	case "epub":
+		text, err = extractEPUB(data)
+	case "htm", "html", "xhtml":
+		text, err = extractHTML(bytes.NewReader(data))
+	default:
+		text, err = extractPlain(data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return truncate(normalizeSpace(text), maxLen), nil
+}
+
+func extractPlain(data []byte) (string, error) {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return "", ErrUnsupported
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	return strings.ToValidUTF8(string(data), ""), nil
+}
+
+func extractPDF(data []byte) (string, error) {
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	reader, err := r.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+var (
+	docxBreaks = map[string]bool{"p": true, "br": true, "tab": true, "tr": true}
+	odfBreaks  = map[string]bool{"p": true, "h": true, "line-break": true, "tab": true, "s": true, "table-row": true}
+)
+
+// extractZipXML concatenates the character data of the given members of a zip
+// container, inserting a newline after each element whose local name is in breaks.
+func extractZipXML(data []byte, members []string, breaks map[string]bool) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	found := false
+	budget := int64(maxUnzipSize)
+	for _, name := range members {
+		f := findZipFile(zr, name)
+		if f == nil {
+			continue
+		}
+		found = true
+		rc, err := openZipFile(f, &budget)
+		if err != nil {
+			return "", err
+		}
+		err = xmlText(rc, breaks, &sb)
+		_ = rc.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if !found {
+		return "", ErrUnsupported
+	}
+	return sb.String(), nil
+}
+
+func xmlText(r io.Reader, breaks map[string]bool, sb *strings.Builder) error {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			if breaks[t.Name.Local] {
+				sb.WriteByte('\n')
+			}
+		case xml.StartElement:
+			// self-closing tags such as <w:br/> emit an EndElement as well
+		}
+	}
+}
+
+// openZipFile opens a member and takes its size from the budget shared by the members of a document,
+// archive/zip fails the read of a member that expands beyond its declared size
+func openZipFile(f *zip.File, budget *int64) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > uint64(*budget) {
+		return nil, ErrTooLarge
+	}
+	*budget -= int64(f.UncompressedSize64)
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, int64(f.UncompressedSize64)), rc}, nil
+}
+
+func findZipFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func extractEPUB(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var files []*zip.File
+	for _, f := range zr.File {
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".xhtml", ".html", ".htm":
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return "", ErrUnsupported
+	}
+	// without parsing the OPF spine, the file name order is the best guess of reading order
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	var sb strings.Builder
+	budget := int64(maxUnzipSize)
+	for _, f := range files {
+		rc, err := openZipFile(f, &budget)
+		if err != nil {
+			return "", err
+		}
+		text, err := extractHTML(rc)
+		_ = rc.Close()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+func extractHTML(r io.Reader) (string, error) {
+	z := html.NewTokenizer(r)
+	var sb strings.Builder
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return sb.String(), nil
+			}
+			return "", z.Err()
+		case html.TextToken:
+			if skip == 0 {
+				sb.Write(z.Text())
+			}
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				skip++
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				if skip > 0 {
+					skip--
+				}
+			case "p", "div", "br", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// normalizeSpace collapses runs of blanks and blank lines
+func normalizeSpace(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func truncate(s string, maxLen int) string {
+	if maxLen <= 0 || len(s) <= maxLen {
+		return s
+	}
+	// do not cut a multibyte rune in half
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	return s[:maxLen]
+}
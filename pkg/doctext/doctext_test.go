@@ -0,0 +1,91 @@
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	testCases := []struct {
+		ext  string
+		data []byte
+		want string
+	}{
+		{"txt", []byte("\xef\xbb\xbfhello   world\n\n\nbye"), "hello world\nbye"},
+		{"html", []byte(`<html><head><title>t</title></head><body><p>first</p><script>var x</script><p>second</p></body></html>`), "first\nsecond"},
+		{"docx", zipOf(t, map[string]string{
+			"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:t> docx</w:t></w:r></w:p><w:p><w:r><w:t>line two</w:t></w:r></w:p></w:body></w:document>`,
+		}), "Hello docx\nline two"},
+		{"odt", zipOf(t, map[string]string{
+			"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><text:h>Title</text:h><text:p>Body text</text:p></office:body></office:document-content>`,
+		}), "Title\nBody text"},
+		{"epub", zipOf(t, map[string]string{
+			"OEBPS/ch2.xhtml": `<html><body><p>chapter two</p></body></html>`,
+			"OEBPS/ch1.xhtml": `<html><body><p>chapter one</p></body></html>`,
+		}), "chapter one\nchapter two"},
+	}
+	for _, tc := range testCases {
+		got, err := Extract(tc.ext, tc.data, 0)
+		if err != nil {
+			t.Errorf("extract %s: %v", tc.ext, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("extract %s: got %q, want %q", tc.ext, got, tc.want)
+		}
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	_, err := Extract("bin", []byte{0x7f, 'E', 'L', 'F', 0, 0, 1}, 0)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expect ErrUnsupported, got %v", err)
+	}
+	_, err = Extract("pdf", []byte("not a pdf"), 0)
+	if err == nil {
+		t.Errorf("expect error for invalid pdf")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("你好世界", 4); got != "你" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncate("abc", 10); got != "abc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestExtractZipBomb(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(bytes.Repeat([]byte(" "), maxUnzipSize+1))
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Extract("docx", buf.Bytes(), 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expect ErrTooLarge, got %v", err)
+	}
+}
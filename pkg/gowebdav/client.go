@@ -331,6 +331,39 @@ func (c *Client) Copy(oldpath, newpath string, overwrite bool) error {
 	return c.copymove("COPY", oldpath, newpath, overwrite)
 }
 
+// SetModTime sets the last modified time of a remote file by PROPPATCH
+func (c *Client) SetModTime(path string, modTime time.Time) error {
+	status := ""
+	parse := func(resp interface{}) error {
+		r := resp.(*response)
+		for _, p := range r.Props {
+			if status == "" || !strings.Contains(p.Status, "200") {
+				status = p.Status
+			}
+		}
+		r.Props = nil
+		return nil
+	}
+
+	err := c.proppatch(path,
+		`<d:propertyupdate xmlns:d='DAV:'>
+			<d:set>
+				<d:prop>
+					<d:getlastmodified>`+modTime.UTC().Format(http.TimeFormat)+`</d:getlastmodified>
+				</d:prop>
+			</d:set>
+		</d:propertyupdate>`,
+		&response{},
+		parse)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(status, "200") {
+		return newPathErrorErr("SetModTime", path, fmt.Errorf("unexpected propstat status %q", status))
+	}
+	return nil
+}
+
 // Read reads the contents of a remote file
 func (c *Client) Read(path string) ([]byte, error) {
 	var stream io.ReadCloser
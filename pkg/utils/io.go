@@ -29,7 +29,6 @@ func CopyWithCtx(ctx context.Context, out io.Writer, in io.Reader, size int64, p
 	// I insert the ability to cancel before read time as it is the earliest
 	// possible in the call process.
 	var finish int64 = 0
-	s := size / 100
 	_, err := CopyWithBuffer(out, readerFunc(func(p []byte) (int, error) {
 		// golang non-blocking channel: https://gobyexample.com/non-blocking-channel-operations
 		select {
@@ -40,9 +39,9 @@ func CopyWithCtx(ctx context.Context, out io.Writer, in io.Reader, size int64, p
 		default:
 			// otherwise just run default io.Reader implementation
 			n, err := in.Read(p)
-			if s > 0 && (err == nil || err == io.EOF) {
+			if size > 0 && (err == nil || err == io.EOF) {
 				finish += int64(n)
-				progress(float64(finish) / float64(s))
+				progress(float64(finish) / float64(size) * 100)
 			}
 			return n, err
 		}
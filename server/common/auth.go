@@ -1,50 +1,86 @@
 package common
 
 import (
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
-	"github.com/OpenListTeam/go-cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/pkg/errors"
 )
 
 var SecretKey []byte
 
+// UserClaims is the payload of an access token, the jwt id is the id of its session
 type UserClaims struct {
 	Username string `json:"username"`
 	PwdTS    int64  `json:"pwd_ts"`
 	jwt.RegisteredClaims
 }
 
-var validTokenCache = cache.NewMemCache[bool]()
+// sessionExpiresIn is how long a session lives without being refreshed,
+// it is never shorter than the access tokens of the session
+func sessionExpiresIn() time.Duration {
+	return time.Duration(max(conf.Conf.RefreshExpiresIn, conf.Conf.TokenExpiresIn)) * time.Hour
+}
+
+// GenerateToken starts a new session of the user from the request,
+// and returns its access token and refresh token
+func GenerateToken(c *gin.Context, user *model.User) (token, refreshToken string, err error) {
+	ua := c.Request.UserAgent()
+	s := &model.Session{
+		UserId:    user.ID,
+		Device:    ParseDevice(ua),
+		IP:        c.ClientIP(),
+		UserAgent: ua,
+		ExpiresAt: time.Now().Add(sessionExpiresIn()),
+	}
+	if refreshToken, err = op.CreateSession(s); err != nil {
+		return "", "", err
+	}
+	token, err = signToken(user, s)
+	return token, refreshToken, err
+}
+
+// RefreshToken issues a new access token and a new refresh token of the session of the refresh token
+func RefreshToken(c *gin.Context, refreshToken string) (token, newRefresh string, err error) {
+	s, newRefresh, err := op.RefreshSession(refreshToken, c.ClientIP(), sessionExpiresIn())
+	if err != nil {
+		return "", "", err
+	}
+	user, err := op.GetUserById(s.UserId)
+	if err != nil {
+		return "", "", err
+	}
+	if user.Disabled {
+		return "", "", errors.New("current user is disabled")
+	}
+	token, err = signToken(user, s)
+	return token, newRefresh, err
+}
 
-func GenerateToken(user *model.User) (tokenString string, err error) {
+func signToken(user *model.User, s *model.Session) (string, error) {
+	now := time.Now()
 	claim := UserClaims{
 		Username: user.Username,
 		PwdTS:    user.PwdTS,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        s.ID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		}}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	tokenString, err = token.SignedString(SecretKey)
-	if err != nil {
-		return "", err
-	}
-	validTokenCache.Set(tokenString, true)
-	return tokenString, err
+	return token.SignedString(SecretKey)
 }
 
 func ParseToken(tokenString string) (*UserClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return SecretKey, nil
 	})
-	if IsTokenInvalidated(tokenString) {
-		return nil, errors.New("token is invalidated")
-	}
 	if err != nil {
 		if ve, ok := err.(*jwt.ValidationError); ok {
 			if ve.Errors&jwt.ValidationErrorMalformed != 0 {
@@ -58,21 +94,75 @@ func ParseToken(tokenString string) (*UserClaims, error) {
 			}
 		}
 	}
-	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*UserClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("couldn't handle this token")
+	}
+	if claims.ID == "" {
+		// issued before the sessions, can't be revoked so the client has to log in again
+		return nil, errors.New("token is invalidated")
 	}
-	return nil, errors.New("couldn't handle this token")
+	if s, err := op.GetSessionById(claims.ID); err != nil || s.Expired() {
+		return nil, errors.New("token is invalidated")
+	}
+	return claims, nil
 }
 
+// InvalidateToken revokes the session of the token
 func InvalidateToken(tokenString string) error {
 	if tokenString == "" {
 		return nil // don't invalidate empty guest token
 	}
-	validTokenCache.Del(tokenString)
-	return nil
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	return op.DeleteSessionById(claims.ID)
 }
 
-func IsTokenInvalidated(tokenString string) bool {
-	_, ok := validTokenCache.Get(tokenString)
-	return !ok
+// ParseDevice gives a short readable name of the client from its user agent, e.g. Chrome on Windows
+func ParseDevice(ua string) string {
+	browser := ""
+	for _, b := range []struct{ key, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"Wget/", "Wget"},
+		{"okhttp/", "OkHttp"},
+	} {
+		if strings.Contains(ua, b.key) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, s := range []struct{ key, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, s.key) {
+			system = s.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	if name, _, _ := strings.Cut(ua, "/"); name != "" {
+		return name
+	}
+	return "Unknown"
 }
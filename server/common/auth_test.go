@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestLegacyToken(t *testing.T) {
+	SecretKey = []byte("secret")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{
+		Username:         "admin",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(SecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tokens issued before the sessions can't be revoked, so they are no longer accepted
+	if _, err := ParseToken(token); err == nil {
+		t.Error("token without a session is accepted")
+	}
+	if err := InvalidateToken(token); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,10 +6,12 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/dlclark/regexp2"
+	"github.com/pkg/errors"
 )
 
 func IsStorageSignEnabled(rawPath string) bool {
@@ -72,3 +74,28 @@ func ShouldProxy(storage driver.Driver, filename string) bool {
 	}
 	return false
 }
+
+// CanDirectLink checks the user is able to share the path by a direct link, which is downloaded
+// without a password, on creating the link and again on every download of it
+func CanDirectLink(user *model.User, reqPath string) error {
+	if user.Disabled {
+		return errs.PermissionDenied
+	}
+	if user.IsAdmin() {
+		return nil
+	}
+	if !user.CanShare() {
+		return errs.PermissionDenied
+	}
+	if !utils.IsSubPath(user.BasePath, reqPath) {
+		return errors.Errorf("permission denied to link path [%s]", reqPath)
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return err
+	}
+	if !CanAccess(user, meta, reqPath, "") {
+		return errs.PermissionDenied
+	}
+	return nil
+}
@@ -142,6 +142,7 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 	}
 	ctx = context.WithValue(ctx, conf.ClientIPKey, cc.RemoteAddr().String())
 	ctx = context.WithValue(ctx, conf.ProxyHeaderKey, d.proxyHeader)
+	ctx = context.WithValue(ctx, conf.ProtocolKey, "ftp")
 	return ftp.NewAferoAdapter(ctx), nil
 }
 
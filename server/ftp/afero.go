@@ -77,8 +77,8 @@ func (a *AferoAdapter) Chown(_ string, _, _ int) error {
 	return errs.NotSupport
 }
 
-func (a *AferoAdapter) Chtimes(_ string, _ time.Time, _ time.Time) error {
-	return errs.NotSupport
+func (a *AferoAdapter) Chtimes(name string, _ time.Time, mtime time.Time) error {
+	return Chtimes(a.ctx, name, mtime)
 }
 
 func (a *AferoAdapter) ReadDir(name string) ([]os.FileInfo, error) {
@@ -3,6 +3,7 @@ package ftp
 import (
 	"context"
 	stdpath "path"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -33,6 +34,29 @@ func Mkdir(ctx context.Context, path string) error {
 	return fs.MakeDir(ctx, reqPath)
 }
 
+func Chtimes(ctx context.Context, path string, modTime time.Time) error {
+	user := ctx.Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(path)
+	if err != nil {
+		return err
+	}
+	if !user.CanWrite() || !user.CanFTPManage() {
+		meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
+		if err != nil {
+			if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+				return err
+			}
+		}
+		if !common.CanWrite(meta, reqPath) {
+			return errs.PermissionDenied
+		}
+	}
+	if err = ChtimesStage(reqPath, modTime); !errors.Is(err, errs.ObjectNotFound) {
+		return err
+	}
+	return fs.SetModTime(ctx, reqPath, modTime)
+}
+
 func Remove(ctx context.Context, path string) error {
 	user := ctx.Value(conf.UserKey).(*model.User)
 	if !user.CanRemove() || !user.CanFTPManage() {
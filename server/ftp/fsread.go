@@ -11,9 +11,11 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/pkg/errors"
 )
@@ -21,7 +23,8 @@ import (
 type FileDownloadProxy struct {
 	model.File
 	io.Closer
-	ctx context.Context
+	ctx      context.Context
+	protocol string
 }
 
 func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownloadProxy, error) {
@@ -57,11 +60,13 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 		_ = ss.Close()
 		return nil, err
 	}
-	return &FileDownloadProxy{File: reader, Closer: ss, ctx: ctx}, nil
+	protocol, _ := ctx.Value(conf.ProtocolKey).(string)
+	return &FileDownloadProxy{File: reader, Closer: ss, ctx: ctx, protocol: utils.GetNoneEmpty(protocol, "ftp")}, nil
 }
 
 func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 	n, err = f.File.Read(p)
+	metrics.ServedBytes(f.protocol, int64(n))
 	if err != nil {
 		return n, err
 	}
@@ -71,6 +76,7 @@ func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 
 func (f *FileDownloadProxy) ReadAt(p []byte, off int64) (n int, err error) {
 	n, err = f.File.ReadAt(p, off)
+	metrics.ServedBytes(f.protocol, int64(n))
 	if err != nil {
 		return n, err
 	}
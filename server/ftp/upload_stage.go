@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	log "github.com/sirupsen/logrus"
@@ -34,6 +35,7 @@ type UploadingFile struct {
 	name        string
 	size        int64
 	modTime     time.Time
+	modTimeSet  bool
 	refCount    int
 	currentPath string
 	softLinks   []patricia.Prefix
@@ -126,10 +128,17 @@ func drop(path patricia.Prefix) {
 			stage.Delete(sl)
 		}
 		stage.Delete(path)
-		if s.currentPath != string(path) {
-			if s.currentPath != "" {
-				go s.mvCallback(s.currentPath)
-			}
+		if s.currentPath != "" {
+			target, moved := s.currentPath, s.currentPath != string(path)
+			modTime, modTimeSet := s.modTime, s.modTimeSet
+			go func() {
+				if moved {
+					s.mvCallback(target)
+				}
+				if modTimeSet {
+					_ = fs.SetModTime(context.Background(), target, modTime)
+				}
+			}()
 		}
 	}
 }
@@ -208,6 +217,28 @@ func MoveStage(from, to string) error {
 	return nil
 }
 
+// ChtimesStage records the modification time of an uploading file,
+// it is applied once the upload has finished
+func ChtimesStage(path string, modTime time.Time) error {
+	stageMutex.Lock()
+	defer stageMutex.Unlock()
+	prefix := patricia.Prefix(path)
+	v := stage.Get(prefix)
+	if v == nil {
+		return errs.ObjectNotFound
+	}
+	s, ok := v.(*UploadingFile)
+	if !ok {
+		s = v.(*softLink).target
+	}
+	if s.currentPath != path {
+		return ErrStageMoved
+	}
+	s.modTime = modTime
+	s.modTimeSet = true
+	return nil
+}
+
 func RemoveStage(path string) error {
 	stageMutex.Lock()
 	defer stageMutex.Unlock()
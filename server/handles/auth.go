@@ -71,12 +71,12 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		}
 	}
 	// generate token
-	token, err := common.GenerateToken(user)
+	token, refreshToken, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
-	common.SuccessResp(c, gin.H{"token": token})
+	common.SuccessResp(c, gin.H{"token": token, "refresh_token": refreshToken})
 	model.LoginCache.Del(ip)
 }
 
@@ -187,3 +187,22 @@ func LogOut(c *gin.Context) {
 		common.SuccessResp(c)
 	}
 }
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken exchanges a refresh token for a new pair of tokens, the old refresh token becomes invalid
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	token, refreshToken, err := common.RefreshToken(c, req.RefreshToken)
+	if err != nil {
+		common.ErrorResp(c, err, 401)
+		return
+	}
+	common.SuccessResp(c, gin.H{"token": token, "refresh_token": refreshToken})
+}
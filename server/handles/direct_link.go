@@ -0,0 +1,173 @@
+package handles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type DirectLinkResp struct {
+	*model.DirectLink
+	URL string `json:"url"`
+}
+
+func directLinkResp(c *gin.Context, l *model.DirectLink) DirectLinkResp {
+	return DirectLinkResp{
+		DirectLink: l,
+		URL:        fmt.Sprintf("%s/d%s?link=%s", common.GetApiUrl(c), utils.EncodePath(l.Path, true), l.ID),
+	}
+}
+
+func getOwnDirectLink(c *gin.Context, id string) (*model.DirectLink, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	l, err := op.GetDirectLinkById(id, true)
+	if err != nil || (!user.IsAdmin() && l.CreatorId != user.ID) {
+		common.ErrorStrResp(c, "direct link not found", 404)
+		return nil, false
+	}
+	// the link is shared with the cache, do not modify it in place
+	cp := *l
+	return &cp, true
+}
+
+func GetDirectLink(c *gin.Context) {
+	l, ok := getOwnDirectLink(c, c.Query("id"))
+	if !ok {
+		return
+	}
+	common.SuccessResp(c, directLinkResp(c, l))
+}
+
+func ListDirectLinks(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var links []model.DirectLink
+	var total int64
+	var err error
+	if user.IsAdmin() {
+		links, total, err = op.GetDirectLinks(req.Page, req.PerPage)
+	} else {
+		links, total, err = op.GetDirectLinksByCreatorId(user.ID, req.Page, req.PerPage)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: utils.MustSliceConvert(links, func(l model.DirectLink) DirectLinkResp {
+			return directLinkResp(c, &l)
+		}),
+		Total: total,
+	})
+}
+
+type UpdateDirectLinkReq struct {
+	ID           string     `json:"id"`
+	Path         string     `json:"path"`
+	Expires      *time.Time `json:"expires"`
+	MaxDownloads int        `json:"max_downloads"`
+	AllowIPs     string     `json:"allow_ips"`
+	UserAgent    string     `json:"user_agent"`
+	Referers     string     `json:"referers"`
+	Disabled     bool       `json:"disabled"`
+	Remark       string     `json:"remark"`
+}
+
+func (req *UpdateDirectLinkReq) apply(l *model.DirectLink) error {
+	l.Path = utils.FixAndCleanPath(req.Path)
+	l.Expires = req.Expires
+	l.MaxDownloads = req.MaxDownloads
+	l.AllowIPs = req.AllowIPs
+	l.UserAgent = req.UserAgent
+	l.Referers = req.Referers
+	l.Disabled = req.Disabled
+	l.Remark = req.Remark
+	return l.Validate()
+}
+
+func CreateDirectLink(c *gin.Context) {
+	var req UpdateDirectLinkReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	l := &model.DirectLink{CreatorId: user.ID}
+	if err := req.apply(l); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := common.CanDirectLink(user, l.Path); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err := op.CreateDirectLink(l); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, directLinkResp(c, l))
+}
+
+func UpdateDirectLink(c *gin.Context) {
+	var req UpdateDirectLinkReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	l, ok := getOwnDirectLink(c, req.ID)
+	if !ok {
+		return
+	}
+	if err := req.apply(l); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := common.CanDirectLink(user, l.Path); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err := op.UpdateDirectLink(l); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, directLinkResp(c, l))
+}
+
+func DeleteDirectLink(c *gin.Context) {
+	id := c.Query("id")
+	if _, ok := getOwnDirectLink(c, id); !ok {
+		return
+	}
+	if err := op.DeleteDirectLink(id); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func SetEnableDirectLink(disable bool) func(ctx *gin.Context) {
+	return func(c *gin.Context) {
+		l, ok := getOwnDirectLink(c, c.Query("id"))
+		if !ok {
+			return
+		}
+		l.Disabled = disable
+		if err := op.UpdateDirectLink(l, "disabled"); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		common.SuccessResp(c)
+	}
+}
@@ -0,0 +1,29 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/diskcache"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func GetDiskCacheStats(c *gin.Context) {
+	cache := diskcache.Default()
+	if cache == nil {
+		common.ErrorStrResp(c, "disk cache is disabled", 400)
+		return
+	}
+	common.SuccessResp(c, cache.Stats())
+}
+
+func ClearDiskCache(c *gin.Context) {
+	cache := diskcache.Default()
+	if cache == nil {
+		common.ErrorStrResp(c, "disk cache is disabled", 400)
+		return
+	}
+	if err := cache.Clear(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
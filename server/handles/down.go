@@ -34,6 +34,10 @@ func Down(c *gin.Context) {
 		Proxy(c)
 		return
 	} else {
+		// the transformed image is served by this server, so only where proxying is allowed
+		if canProxy(storage, filename) && transformImage(c, rawPath) {
+			return
+		}
 		link, _, err := fs.Link(c.Request.Context(), rawPath, model.LinkArgs{
 			IP:       c.ClientIP(),
 			Header:   c.Request.Header,
@@ -57,6 +61,9 @@ func Proxy(c *gin.Context) {
 		return
 	}
 	if canProxy(storage, filename) {
+		if transformImage(c, rawPath) {
+			return
+		}
 		if _, ok := c.GetQuery("d"); !ok {
 			if url := common.GenerateDownProxyURL(storage.GetStorage(), rawPath); url != "" {
 				c.Redirect(302, url)
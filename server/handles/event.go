@@ -0,0 +1,204 @@
+package handles
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/message"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+)
+
+// types of the live events pushed through the websocket
+const (
+	EventTask       = "task"
+	EventStorage    = "storage"
+	EventIndex      = "index"
+	EventDirChanged = "dir_changed"
+)
+
+// eventInterval is how often the task, storage and index states are checked for changes
+const eventInterval = time.Second
+
+type TaskEvent struct {
+	Type string `json:"type"` // upload, copy, offline_download...
+	TaskInfo
+}
+
+type StorageEvent struct {
+	ID        uint   `json:"id"`
+	MountPath string `json:"mount_path"`
+	Status    string `json:"status"`
+	Disabled  bool   `json:"disabled"`
+	Deleted   bool   `json:"deleted"`
+}
+
+type DirChangedEvent struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+var setupEventsOnce sync.Once
+
+// SetupLiveEvents starts pushing the changes of tasks, storages, index and directories to the websocket clients,
+// the states are only watched while there is a client
+func SetupLiveEvents() {
+	setupEventsOnce.Do(func() {
+		op.RegisterFsChangeHook(pushDirChanged)
+		watchers := []func(){
+			taskWatcher("upload", fs.UploadTaskManager),
+			taskWatcher("copy", fs.CopyTaskManager),
+			taskWatcher("move", fs.MoveTaskManager),
+			taskWatcher("offline_download", tool.DownloadTaskManager),
+			taskWatcher("offline_download_transfer", tool.TransferTaskManager),
+			taskWatcher("decompress", fs.ArchiveDownloadTaskManager),
+			taskWatcher("decompress_upload", fs.ArchiveContentUploadTaskManager),
+			storageWatcher(),
+			indexWatcher(),
+		}
+		go func() {
+			ticker := time.NewTicker(eventInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				if !message.WsInstance.HasClients() {
+					continue
+				}
+				for _, watch := range watchers {
+					watch()
+				}
+			}
+		}()
+	})
+}
+
+type taskState struct {
+	state    tache.State
+	status   string
+	progress float64
+}
+
+// taskWatcher pushes the tasks whose state, status or progress changed since the last check
+func taskWatcher[T task.TaskExtensionInfo](typ string, manager task.Manager[T]) func() {
+	last := make(map[string]taskState)
+	return func() {
+		if manager == nil {
+			return
+		}
+		tasks := manager.GetAll()
+		current := make(map[string]taskState, len(tasks))
+		for _, t := range tasks {
+			info := getTaskInfo(t)
+			s := taskState{state: info.State, status: info.Status, progress: info.Progress}
+			current[info.ID] = s
+			if old, ok := last[info.ID]; ok && old == s {
+				continue
+			}
+			var creatorId uint
+			if t.GetCreator() != nil {
+				creatorId = t.GetCreator().ID
+			}
+			message.WsInstance.Broadcast(message.Message{
+				Type:    EventTask,
+				Content: TaskEvent{Type: typ, TaskInfo: info},
+			}, func(user *model.User) bool {
+				return user.IsAdmin() || (!user.IsGuest() && user.ID == creatorId)
+			})
+		}
+		last = current
+	}
+}
+
+func storageWatcher() func() {
+	last := make(map[uint]StorageEvent)
+	return func() {
+		storages := op.GetAllStorages()
+		current := make(map[uint]StorageEvent, len(storages))
+		for _, d := range storages {
+			s := d.GetStorage()
+			e := StorageEvent{ID: s.ID, MountPath: s.MountPath, Status: s.Status, Disabled: s.Disabled}
+			current[s.ID] = e
+			if old, ok := last[s.ID]; ok && old == e {
+				continue
+			}
+			message.WsInstance.Broadcast(message.Message{Type: EventStorage, Content: e}, (*model.User).IsAdmin)
+		}
+		for id, e := range last {
+			if _, ok := current[id]; !ok {
+				e.Deleted = true
+				message.WsInstance.Broadcast(message.Message{Type: EventStorage, Content: e}, (*model.User).IsAdmin)
+			}
+		}
+		last = current
+	}
+}
+
+func indexWatcher() func() {
+	last := ""
+	return func() {
+		progress, err := search.Progress()
+		if err != nil {
+			return
+		}
+		current, _ := utils.Json.MarshalToString(progress)
+		if current == last {
+			return
+		}
+		last = current
+		message.WsInstance.Broadcast(message.Message{Type: EventIndex, Content: progress}, (*model.User).IsAdmin)
+	}
+}
+
+// pushDirChanged tells the users who can see the parent directories of the change to refresh them
+func pushDirChanged(ctx context.Context, change model.FsChange) {
+	if !message.WsInstance.HasClients() {
+		return
+	}
+	var dirs []string
+	switch change.Op {
+	case model.FsChangeRename, model.FsChangeMove:
+		dirs = []string{path.Dir(change.Path), path.Dir(change.DstPath)}
+	case model.FsChangeCopy:
+		dirs = []string{path.Dir(change.DstPath)}
+	case model.FsChangeDecompress:
+		dirs = []string{path.Dir(change.DstPath)}
+		if change.IsDir {
+			dirs = append(dirs, change.DstPath)
+		}
+	default:
+		dirs = []string{path.Dir(change.Path)}
+	}
+	for i, dir := range dirs {
+		if i > 0 && dir == dirs[0] {
+			continue
+		}
+		meta, _ := op.GetNearestMeta(dir)
+		message.WsInstance.Publish(func(user *model.User) (message.Message, bool) {
+			if !utils.IsSubPath(user.BasePath, dir) || !common.CanAccess(user, meta, dir, "") {
+				return message.Message{}, false
+			}
+			return message.Message{
+				Type:    EventDirChanged,
+				Content: DirChangedEvent{Path: relativeToBase(user, dir), Op: change.Op},
+			}, true
+		})
+	}
+}
+
+// relativeToBase converts a full path to the path seen by the user
+func relativeToBase(user *model.User, p string) string {
+	base := utils.FixAndCleanPath(user.BasePath)
+	if base == "/" {
+		return p
+	}
+	return utils.FixAndCleanPath(strings.TrimPrefix(p, base))
+}
@@ -1,6 +1,7 @@
 package handles
 
 import (
+	"context"
 	"fmt"
 	stdpath "path"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/media"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -117,7 +119,7 @@ func FsList(c *gin.Context, req *ListReq, user *model.User) {
 		}
 	}
 	common.SuccessResp(c, FsListResp{
-		Content:           toObjsResp(objs, reqPath, isEncrypt(meta, reqPath)),
+		Content:           toObjsResp(c, objs, reqPath, isEncrypt(meta, reqPath)),
 		Total:             int64(total),
 		Readme:            getReadme(meta, reqPath),
 		Header:            getHeader(meta, reqPath),
@@ -228,10 +230,13 @@ func pagination(objs []model.Obj, req *model.PageReq) (int, []model.Obj) {
 	return total, objs[start:end]
 }
 
-func toObjsResp(objs []model.Obj, parent string, encrypt bool) []ObjResp {
+func toObjsResp(ctx context.Context, objs []model.Obj, parent string, encrypt bool) []ObjResp {
 	var resp []ObjResp
 	for _, obj := range objs {
 		thumb, _ := model.GetThumb(obj)
+		if thumb == "" {
+			thumb = thumbURL(ctx, obj, parent, encrypt)
+		}
 		mountDetails, _ := model.GetStorageDetails(obj)
 		resp = append(resp, ObjResp{
 			Id:           obj.GetID(),
@@ -259,11 +264,12 @@ type FsGetReq struct {
 
 type FsGetResp struct {
 	ObjResp
-	RawURL   string    `json:"raw_url"`
-	Readme   string    `json:"readme"`
-	Header   string    `json:"header"`
-	Provider string    `json:"provider"`
-	Related  []ObjResp `json:"related"`
+	RawURL   string           `json:"raw_url"`
+	Readme   string           `json:"readme"`
+	Header   string           `json:"header"`
+	Provider string           `json:"provider"`
+	Related  []ObjResp        `json:"related"`
+	Media    *model.MediaInfo `json:"media,omitempty"`
 }
 
 func FsGetSplit(c *gin.Context) {
@@ -362,7 +368,16 @@ func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
 	}
 	parentMeta, _ := op.GetNearestMeta(parentPath)
 	thumb, _ := model.GetThumb(obj)
+	if thumb == "" {
+		thumb = thumbURL(c, obj, parentPath, isEncrypt(meta, reqPath))
+	}
 	mountDetails, _ := model.GetStorageDetails(obj)
+	var mediaInfo *model.MediaInfo
+	if !obj.IsDir() && media.Kind(obj.GetName()) != "" {
+		if mediaInfo = media.Get(reqPath, obj); mediaInfo == nil {
+			media.Enqueue(reqPath)
+		}
+	}
 	common.SuccessResp(c, FsGetResp{
 		ObjResp: ObjResp{
 			Id:           obj.GetID(),
@@ -383,7 +398,8 @@ func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
 		Readme:   getReadme(meta, reqPath),
 		Header:   getHeader(meta, reqPath),
 		Provider: provider,
-		Related:  toObjsResp(related, parentPath, isEncrypt(parentMeta, parentPath)),
+		Related:  toObjsResp(c, related, parentPath, isEncrypt(parentMeta, parentPath)),
+		Media:    mediaInfo,
 	})
 }
 
@@ -0,0 +1,131 @@
+package handles
+
+import (
+	"fmt"
+	"net/url"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type FsVersionReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+	Version  string `json:"version" form:"version"`
+}
+
+type FsVersionResp struct {
+	model.FileVersion
+	RawURL string `json:"raw_url"`
+}
+
+// checkVersionAccess joins the path of the request and checks the access of the user to it
+func checkVersionAccess(c *gin.Context, req *FsVersionReq) (*model.User, *model.Meta, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var err error
+	req.Path, err = user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return nil, nil, false
+	}
+	meta, err := op.GetNearestMeta(req.Path)
+	if err != nil {
+		if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500)
+			return nil, nil, false
+		}
+	}
+	common.GinWithValue(c, conf.MetaKey, meta)
+	if !common.CanAccess(user, meta, req.Path, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return nil, nil, false
+	}
+	return user, meta, true
+}
+
+// versionURL is the download of the version through its file, so that the metas of the file apply
+func versionURL(c *gin.Context, path, version string) string {
+	return fmt.Sprintf("%s/api/fs/versions/download?path=%s&version=%s",
+		common.GetApiUrl(c),
+		url.QueryEscape(path),
+		url.QueryEscape(version))
+}
+
+func FsVersions(c *gin.Context) {
+	var req FsVersionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user, _, ok := checkVersionAccess(c, &req)
+	if !ok {
+		return
+	}
+	versions, err := fs.Versions(c.Request.Context(), req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	resp := make([]FsVersionResp, 0, len(versions))
+	path := relativeToBase(user, req.Path)
+	for _, v := range versions {
+		resp = append(resp, FsVersionResp{FileVersion: v, RawURL: versionURL(c, path, v.Name)})
+	}
+	common.SuccessResp(c, resp)
+}
+
+func FsRestoreVersion(c *gin.Context) {
+	var req FsVersionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user, meta, ok := checkVersionAccess(c, &req)
+	if !ok {
+		return
+	}
+	if !user.CanWrite() && !common.CanWrite(meta, stdpath.Dir(req.Path)) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	if err := checkRelativePath(req.Version); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := fs.RestoreVersion(c.Request.Context(), req.Path, req.Version); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// FsDownloadVersion proxies the download of a version of the file
+func FsDownloadVersion(c *gin.Context) {
+	var req FsVersionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, _, ok := checkVersionAccess(c, &req); !ok {
+		return
+	}
+	link, obj, err := fs.VersionLink(c.Request.Context(), req.Path, req.Version, model.LinkArgs{
+		Header: c.Request.Header,
+	})
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			common.ErrorResp(c, err, 404)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
+	proxy(c, link, obj, false)
+}
@@ -0,0 +1,69 @@
+package handles
+
+import (
+	"errors"
+	"net/url"
+	stdpath "path"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/hls"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// HLS replies with the master playlist of the video, the playlist of a profile or a segment of it.
+// The entries are relative to the video and carry on its sign or direct link.
+func HLS(c *gin.Context) {
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	obj, err := fs.Get(c.Request.Context(), rawPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	if !hls.Supported(obj) {
+		common.ErrorPage(c, errors.New("hls is not available for this file"), 404)
+		return
+	}
+	uri := func(q url.Values) string {
+		for _, k := range []string{"sign", "link"} {
+			if v := c.Query(k); v != "" {
+				q.Set(k, v)
+			}
+		}
+		return url.PathEscape(stdpath.Base(rawPath)) + "?" + q.Encode()
+	}
+	profile := c.Query("profile")
+	seg, hasSeg := c.GetQuery("seg")
+	if hasSeg {
+		index, err := strconv.Atoi(seg)
+		if err != nil {
+			common.ErrorPage(c, err, 400)
+			return
+		}
+		file, err := hls.Segment(c.Request.Context(), rawPath, obj, profile, index)
+		if err != nil {
+			common.ErrorPage(c, err, 500)
+			return
+		}
+		c.Header("Content-Type", "video/mp2t")
+		c.File(file)
+		return
+	}
+	var playlist string
+	if profile == "" {
+		playlist, err = hls.MasterPlaylist(c.Request.Context(), rawPath, obj, func(profile string) string {
+			return uri(url.Values{"profile": {profile}})
+		})
+	} else {
+		playlist, err = hls.MediaPlaylist(c.Request.Context(), rawPath, obj, profile, func(index int) string {
+			return uri(url.Values{"profile": {profile}, "seg": {strconv.Itoa(index)}})
+		})
+	}
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	c.Data(200, "application/vnd.apple.mpegurl", []byte(playlist))
+}
@@ -107,3 +107,15 @@ func GetProgress(c *gin.Context) {
 	}
 	common.SuccessResp(c, progress)
 }
+
+func GetIndexQueue(c *gin.Context) {
+	common.SuccessResp(c, search.IndexQueue())
+}
+
+func ClearIndexQueue(c *gin.Context) {
+	if err := search.ClearIndexQueue(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -112,12 +112,12 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 	}
 
 	// generate token
-	token, err := common.GenerateToken(user)
+	token, refreshToken, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
-	common.SuccessResp(c, gin.H{"token": token})
+	common.SuccessResp(c, gin.H{"token": token, "refresh_token": refreshToken})
 	model.LoginCache.Del(ip)
 }
 
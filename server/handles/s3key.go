@@ -0,0 +1,199 @@
+package handles
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/s3"
+	"github.com/gin-gonic/gin"
+)
+
+type S3KeyAddReq struct {
+	Title string `json:"title" binding:"required"`
+}
+
+type S3KeyAddResp struct {
+	*model.S3AccessKey
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+func AddMyS3Key(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	var req S3KeyAddReq
+	if err := c.ShouldBind(&req); err != nil || req.Title == "" {
+		common.ErrorStrResp(c, "request invalid", 400)
+		return
+	}
+	key := &model.S3AccessKey{
+		Title:  req.Title,
+		UserId: userObj.ID,
+	}
+	if err := op.CreateS3AccessKey(key); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	// the secret is only shown once
+	common.SuccessResp(c, S3KeyAddResp{
+		S3AccessKey:     key,
+		SecretAccessKey: key.SecretAccessKey,
+	})
+}
+
+func ListMyS3Keys(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	listS3Keys(c, userObj)
+}
+
+func DeleteMyS3Key(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	keyId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	key, err := op.GetS3AccessKeyByIdAndUserId(uint(keyId), userObj.ID)
+	if err != nil {
+		common.ErrorStrResp(c, "failed to get s3 key", 404)
+		return
+	}
+	if err = op.DeleteS3AccessKeyById(key.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type S3PresignReq struct {
+	AccessKeyId string `json:"access_key_id" binding:"required"`
+	Bucket      string `json:"bucket" binding:"required"`
+	Key         string `json:"key" binding:"required"`
+	Method      string `json:"method"`
+	// Expires in seconds, 1 hour by default
+	Expires int64 `json:"expires"`
+}
+
+// PresignMyS3Url signs an object url with one of the user's keys,
+// the permission is checked when the url is used
+func PresignMyS3Url(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	if !conf.Conf.S3.Enable {
+		common.ErrorStrResp(c, "S3 server is not enabled", 403)
+		return
+	}
+	var req S3PresignReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Method = strings.ToUpper(req.Method)
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.Method != "GET" && req.Method != "PUT" {
+		common.ErrorStrResp(c, "only GET and PUT can be presigned", 400)
+		return
+	}
+	if req.Expires == 0 {
+		req.Expires = 3600
+	}
+	key, err := op.GetS3AccessKeyByAccessKeyId(req.AccessKeyId)
+	if err != nil || key.UserId != userObj.ID {
+		common.ErrorStrResp(c, "failed to get s3 key", 404)
+		return
+	}
+	u, err := s3.Presign(s3Endpoint(c), req.Method, req.Bucket, req.Key, key.AccessKeyId, key.SecretAccessKey,
+		time.Duration(req.Expires)*time.Second, time.Now())
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{"url": u})
+}
+
+// s3Endpoint is the address of the gateway as seen by the client of the current request
+func s3Endpoint(c *gin.Context) string {
+	api := common.GetApiUrl(c)
+	if conf.Conf.S3.Port == -1 {
+		return api + "/s3"
+	}
+	scheme := "http"
+	if conf.Conf.S3.SSL {
+		scheme = "https"
+	}
+	host := c.Request.Host
+	if u, err := url.Parse(api); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, conf.Conf.S3.Port)
+}
+
+func ListS3Keys(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	userObj, err := op.GetUserById(uint(userId))
+	if err != nil {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	listS3Keys(c, userObj)
+}
+
+func DeleteS3Key(c *gin.Context) {
+	keyId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err = op.DeleteS3AccessKeyById(uint(keyId)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listS3Keys(c *gin.Context, userObj *model.User) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	keys, total, err := op.GetS3AccessKeysByUserId(userObj.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: keys,
+		Total:   total,
+	})
+}
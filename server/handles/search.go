@@ -47,6 +47,10 @@ func Search(c *gin.Context) {
 	}
 	nodes, total, err := search.Search(c, req.SearchReq)
 	if err != nil {
+		if errors.Is(err, errs.NotSupport) {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		common.ErrorResp(c, err, 500)
 		return
 	}
@@ -0,0 +1,86 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionResp struct {
+	model.Session
+	Current bool `json:"current"`
+}
+
+func ListMySessions(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	current, _ := c.Request.Context().Value(conf.SessionKey).(string)
+	listSessions(c, user, current)
+}
+
+func RevokeMySession(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	s, err := op.GetSessionById(c.Query("id"))
+	if err != nil || s.UserId != user.ID {
+		common.ErrorStrResp(c, "session not found", 404)
+		return
+	}
+	if err = op.DeleteSessionById(s.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ListSessions(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	user, err := op.GetUserById(uint(userId))
+	if err != nil {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	listSessions(c, user, "")
+}
+
+// RevokeSessions logs the user out on all devices
+func RevokeSessions(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	if err = op.DeleteSessionsByUserId(uint(userId)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listSessions(c *gin.Context, user *model.User, current string) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	sessions, total, err := op.GetSessionsByUserId(user.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	resp := make([]SessionResp, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, SessionResp{Session: s, Current: s.ID == current})
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: resp,
+		Total:   total,
+	})
+}
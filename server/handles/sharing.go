@@ -219,6 +219,10 @@ func SharingDown(c *gin.Context) {
 	if dealErrorPage(c, err) {
 		return
 	}
+	if (setting.GetBool(conf.ShareForceProxy) || canProxy(storage, stdpath.Base(actualPath))) && transformImage(c, unwrapPath) {
+		_ = countAccess(c.ClientIP(), s)
+		return
+	}
 	if setting.GetBool(conf.ShareForceProxy) || common.ShouldProxy(storage, stdpath.Base(actualPath)) {
 		if _, ok := c.GetQuery("d"); !ok {
 			if url := common.GenerateDownProxyURL(storage.GetStorage(), unwrapPath); url != "" {
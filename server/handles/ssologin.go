@@ -258,7 +258,7 @@ func OIDCLoginCallback(c *gin.Context) {
 				common.ErrorResp(c, err, 400)
 			}
 		}
-		token, err := common.GenerateToken(user)
+		token, _, err := common.GenerateToken(c, user)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
 		}
@@ -424,7 +424,7 @@ func SSOLoginCallback(c *gin.Context) {
 			return
 		}
 	}
-	token, err := common.GenerateToken(user)
+	token, _, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 	}
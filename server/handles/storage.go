@@ -10,6 +10,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/health"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -21,6 +22,7 @@ import (
 type StorageResp struct {
 	model.Storage
 	MountDetails *model.StorageDetails `json:"mount_details,omitempty"`
+	Health       *model.StorageHealth  `json:"health,omitempty"`
 }
 
 type detailWithIndex struct {
@@ -36,6 +38,7 @@ func makeStorageResp(ctx *gin.Context, storages []model.Storage) []*StorageResp
 		ret[i] = &StorageResp{
 			Storage:      s,
 			MountDetails: nil,
+			Health:       health.Get(s.ID),
 		}
 		if setting.GetBool(conf.HideStorageDetailsInManagePage) {
 			continue
@@ -90,6 +93,27 @@ func ListStorages(c *gin.Context) {
 	})
 }
 
+func ListStorageStatusHistory(c *gin.Context) {
+	var req struct {
+		model.PageReq
+		ID uint `json:"id" form:"id" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	history, total, err := db.GetStorageStatusHistory(req.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: history,
+		Total:   total,
+	})
+}
+
 func CreateStorage(c *gin.Context) {
 	var req model.Storage
 	if err := c.ShouldBind(&req); err != nil {
@@ -0,0 +1,87 @@
+package handles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	stdpath "path"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/thumbnail"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func Thumbnail(c *gin.Context) {
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	obj, err := fs.Get(c.Request.Context(), rawPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	if !thumbnail.Supported(obj) {
+		common.ErrorPage(c, errors.New("thumbnail is not available for this file"), 404)
+		return
+	}
+	size, _ := strconv.Atoi(c.Query("size"))
+	data, err := thumbnail.Get(c.Request.Context(), rawPath, obj, size)
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("Cache-Control", "max-age=86400")
+	http.ServeContent(c.Writer, c.Request, "", obj.ModTime(), bytes.NewReader(data))
+}
+
+// thumbURL points to the generated thumbnail of the object, for the drivers that provide none
+func thumbURL(ctx context.Context, obj model.Obj, parent string, encrypt bool) string {
+	if !thumbnail.Supported(obj) {
+		return ""
+	}
+	u := common.GetApiUrl(ctx) + "/t" + utils.EncodePath(stdpath.Join(parent, obj.GetName()), true)
+	if s := common.Sign(obj, parent, encrypt); s != "" {
+		u += "?sign=" + s
+	}
+	return u
+}
+
+// imageOptions parses the resizing parameters of /d, /p and /sd, ok is false when none is given
+func imageOptions(c *gin.Context) (opts thumbnail.Options, ok bool) {
+	if !thumbnail.Enabled() {
+		return opts, false
+	}
+	opts.Width, _ = strconv.Atoi(c.Query("w"))
+	opts.Height, _ = strconv.Atoi(c.Query("h"))
+	opts.Quality, _ = strconv.Atoi(c.Query("q"))
+	opts.Fit = c.Query("fit")
+	opts.Format = c.Query("fmt")
+	return opts, opts.Width > 0 || opts.Height > 0 || opts.Format != ""
+}
+
+// transformImage replies with the resized image if it is asked for and the file can be transformed,
+// otherwise the original is served by the caller
+func transformImage(c *gin.Context, path string) bool {
+	opts, ok := imageOptions(c)
+	if !ok {
+		return false
+	}
+	obj, err := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true})
+	if err != nil || !thumbnail.CanTransform(obj) {
+		return false
+	}
+	data, err := thumbnail.Transform(c.Request.Context(), path, obj, opts)
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return true
+	}
+	c.Header("Content-Type", http.DetectContentType(data))
+	c.Header("Cache-Control", "max-age=86400")
+	http.ServeContent(c.Writer, c.Request, "", obj.ModTime(), bytes.NewReader(data))
+	return true
+}
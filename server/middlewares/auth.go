@@ -4,11 +4,13 @@ import (
 	"crypto/subtle"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/message"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -70,11 +72,24 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 			return
 		}
 		common.GinWithValue(c, conf.UserKey, user)
+		setSession(c, userClaims)
 		log.Debugf("use login token: %+v", user)
 		c.Next()
 	}
 }
 
+// setSession records the activity of the session of the token and puts it into the context
+func setSession(c *gin.Context, claims *common.UserClaims) {
+	s, err := op.GetSessionById(claims.ID)
+	if err != nil {
+		return
+	}
+	if err := op.TouchSession(s, c.ClientIP()); err != nil {
+		log.Warnf("failed to update last seen of session: %+v", err)
+	}
+	common.GinWithValue(c, conf.SessionKey, s.ID)
+}
+
 func Authn(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if subtle.ConstantTimeCompare([]byte(token), []byte(setting.GetStr(conf.Token))) == 1 {
@@ -125,6 +140,7 @@ func Authn(c *gin.Context) {
 		return
 	}
 	common.GinWithValue(c, conf.UserKey, user)
+	setSession(c, userClaims)
 	log.Debugf("use login token: %+v", user)
 	c.Next()
 }
@@ -148,3 +164,15 @@ func AuthAdmin(c *gin.Context) {
 		c.Next()
 	}
 }
+
+// TokenFromWsProtocol takes the token of a browser web socket from its subprotocols,
+// offered as the one after message.WsProtocol, which keeps it out of the url and the logs
+func TokenFromWsProtocol(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		protocols := websocket.Subprotocols(c.Request)
+		if len(protocols) == 2 && protocols[0] == message.WsProtocol {
+			c.Request.Header.Set("Authorization", protocols[1])
+		}
+	}
+	c.Next()
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -9,10 +10,12 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 func PathParse(c *gin.Context) {
@@ -23,29 +26,106 @@ func PathParse(c *gin.Context) {
 
 func Down(verifyFunc func(string, string) error) func(c *gin.Context) {
 	return func(c *gin.Context) {
+		_, span := tracing.Start(c, "sign check")
 		rawPath := c.Request.Context().Value(conf.PathKey).(string)
 		meta, err := op.GetNearestMeta(rawPath)
 		if err != nil {
 			if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+				tracing.End(span, err)
 				common.ErrorPage(c, err, 500, true)
 				return
 			}
 		}
 		common.GinWithValue(c, conf.MetaKey, meta)
-		// verify sign
-		if needSign(meta, rawPath) {
+		// verify sign, a verified direct link takes the place of it
+		if c.Request.Context().Value(conf.DirectLinkKey) == nil && needSign(meta, rawPath) {
 			s := c.Query("sign")
 			err = verifyFunc(rawPath, strings.TrimSuffix(s, "/"))
 			if err != nil {
+				tracing.End(span, err)
 				common.ErrorPage(c, err, 401)
 				c.Abort()
 				return
 			}
 		}
+		span.End()
 		c.Next()
 	}
 }
 
+// CountPolicy tells whether a request through a direct link is a download counted against its limit
+type CountPolicy func(c *gin.Context) bool
+
+// CountDownload counts the GET requests whatever their ranges, and all the playlists and segments of a stream,
+// the requests of a client are counted once for a while, see op.CountDirectLinkDownload
+func CountDownload(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet
+}
+
+// CountNever is for the previews of the file, e.g. thumbnails, which are not downloads of it
+func CountNever(*gin.Context) bool {
+	return false
+}
+
+// DirectLink verifies the `link` query if present, the sign check is skipped afterwards
+func DirectLink(count CountPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		directLink(c, count)
+	}
+}
+
+func directLink(c *gin.Context, count CountPolicy) {
+	id := c.Query("link")
+	if id == "" {
+		c.Next()
+		return
+	}
+	_, span := tracing.Start(c, "direct link check")
+	defer span.End()
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	l, err := op.GetDirectLinkById(id)
+	if err != nil || !utils.IsSubPath(l.Path, rawPath) {
+		common.ErrorPage(c, errors.New("the link does not exist"), 404)
+		c.Abort()
+		return
+	}
+	// the downloads limit is checked when counting, a client counted already keeps downloading
+	if !l.Active() || (!count(c) && !l.Valid()) {
+		common.ErrorPage(c, errors.New("the link has expired or is no longer valid"), 403)
+		c.Abort()
+		return
+	}
+	if err = l.Allow(c.ClientIP(), c.Request.UserAgent(), c.Request.Referer()); err != nil {
+		common.ErrorPage(c, err, 403)
+		c.Abort()
+		return
+	}
+	// the creator may have lost the access to the path since the link was created
+	creator, err := op.GetUserById(l.CreatorId)
+	if err == nil {
+		err = common.CanDirectLink(creator, rawPath)
+	}
+	if err != nil {
+		common.ErrorPage(c, errors.New("the link is no longer valid"), 403)
+		c.Abort()
+		return
+	}
+	if count(c) {
+		counted, err := op.CountDirectLinkDownload(l.ID, c.ClientIP())
+		if err != nil {
+			log.Errorf("failed count download of direct link %s: %+v", l.ID, err)
+		} else if !counted {
+			common.ErrorPage(c, errors.New("the link has expired or is no longer valid"), 403)
+			c.Abort()
+			return
+		}
+	}
+	common.GinWithValue(c, conf.DirectLinkKey, l)
+	// end before the following handlers, the deferred End is a no-op then
+	span.End()
+	c.Next()
+}
+
 // TODO: implement
 // path maybe contains # ? etc.
 func parsePath(path string) string {
@@ -1,10 +1,13 @@
 package middlewares
 
 import (
+	"fmt"
 	"net/netip"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -93,7 +96,35 @@ func FilteredLogger() gin.HandlerFunc {
 	initFilterList()
 
 	return gin.LoggerWithConfig(gin.LoggerConfig{
-		Output: log.StandardLogger().Out,
-		Skip:   skiperDecider,
+		Formatter: traceLogFormatter,
+		Output:    log.StandardLogger().Out,
+		Skip:      skiperDecider,
 	})
 }
+
+// traceLogFormatter is the default format of gin with the trace id of the request appended
+func traceLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	var traceID string
+	if id := tracing.TraceID(param.Request.Context()); id != "" {
+		traceID = " | trace_id=" + id
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v%s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		traceID,
+		param.ErrorMessage,
+	)
+}
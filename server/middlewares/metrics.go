@@ -0,0 +1,14 @@
+package middlewares
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ServedBytes counts the bytes of the responses as served by protocol
+func ServedBytes(protocol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		metrics.ServedBytes(protocol, int64(c.Writer.Size()))
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Tracing starts the server span of the request, the following handlers get it from the request context
+func Tracing(c *gin.Context) {
+	if !tracing.Enabled() {
+		c.Next()
+		return
+	}
+	ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+	name := c.FullPath()
+	if name == "" {
+		name = c.Request.URL.Path
+	}
+	ctx, span := tracing.Start(ctx, c.Request.Method+" "+name,
+		attribute.String("http.request.method", c.Request.Method),
+		attribute.String("url.path", c.Request.URL.Path),
+		attribute.String("client.address", c.ClientIP()),
+	)
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= 500 {
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+	}
+	if len(c.Errors) > 0 {
+		span.RecordError(c.Errors.Last())
+	}
+}
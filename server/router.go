@@ -4,6 +4,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/message"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -17,6 +18,7 @@ import (
 
 func Init(e *gin.Engine) {
 	e.ContextWithFallback = true
+	e.Use(middlewares.Tracing)
 	if !utils.SliceContains([]string{"", "/"}, conf.URL.Path) {
 		e.GET("/", func(c *gin.Context) {
 			c.Redirect(302, conf.URL.Path)
@@ -34,6 +36,13 @@ func Init(e *gin.Engine) {
 	g.GET("/robots.txt", handles.Robots)
 	g.GET("/manifest.json", static.ManifestJSON)
 	g.GET("/i/:link_name", handles.Plist)
+	if conf.Conf.Metrics.Enable && conf.Conf.Metrics.Listen == "" {
+		if conf.Conf.Metrics.Token == "" {
+			utils.Log.Warnf("metrics on the main listener require a token, /metrics is disabled")
+		} else {
+			g.GET("/metrics", gin.WrapH(metrics.Handler(conf.Conf.Metrics.Token)))
+		}
+	}
 	common.SecretKey = []byte(conf.Conf.JwtSecret)
 	g.Use(middlewares.StoragesLoaded)
 	if conf.Conf.MaxConnections > 0 {
@@ -44,10 +53,12 @@ func Init(e *gin.Engine) {
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
-	g.GET("/d/*path", middlewares.PathParse, signCheck, downloadLimiter, handles.Down)
-	g.GET("/p/*path", middlewares.PathParse, signCheck, downloadLimiter, handles.Proxy)
-	g.HEAD("/d/*path", middlewares.PathParse, signCheck, handles.Down)
-	g.HEAD("/p/*path", middlewares.PathParse, signCheck, handles.Proxy)
+	g.GET("/d/*path", middlewares.ServedBytes("d"), middlewares.PathParse, middlewares.DirectLink(middlewares.CountDownload), signCheck, downloadLimiter, handles.Down)
+	g.GET("/p/*path", middlewares.ServedBytes("p"), middlewares.PathParse, middlewares.DirectLink(middlewares.CountDownload), signCheck, downloadLimiter, handles.Proxy)
+	g.HEAD("/d/*path", middlewares.PathParse, middlewares.DirectLink(middlewares.CountDownload), signCheck, handles.Down)
+	g.HEAD("/p/*path", middlewares.PathParse, middlewares.DirectLink(middlewares.CountDownload), signCheck, handles.Proxy)
+	g.GET("/t/*path", middlewares.PathParse, middlewares.DirectLink(middlewares.CountNever), signCheck, handles.Thumbnail)
+	g.GET("/hls/*path", middlewares.ServedBytes("hls"), middlewares.PathParse, middlewares.DirectLink(middlewares.CountDownload), signCheck, downloadLimiter, handles.HLS)
 	archiveSignCheck := middlewares.Down(sign.VerifyArchive)
 	g.GET("/ad/*path", middlewares.PathParse, archiveSignCheck, downloadLimiter, handles.ArchiveDown)
 	g.GET("/ap/*path", middlewares.PathParse, archiveSignCheck, downloadLimiter, handles.ArchiveProxy)
@@ -72,11 +83,20 @@ func Init(e *gin.Engine) {
 	api.POST("/auth/login", handles.Login)
 	api.POST("/auth/login/hash", handles.LoginHash)
 	api.POST("/auth/login/ldap", handles.LoginLdap)
+	api.POST("/auth/refresh", handles.RefreshToken)
+	api.GET("/ws", middlewares.TokenFromWsProtocol, middlewares.Auth(false), message.WsInstance.Handle)
+	handles.SetupLiveEvents()
 	auth.GET("/me", handles.CurrentUser)
 	auth.POST("/me/update", handles.UpdateCurrent)
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
 	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
 	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
+	auth.GET("/me/s3key/list", handles.ListMyS3Keys)
+	auth.POST("/me/s3key/add", handles.AddMyS3Key)
+	auth.POST("/me/s3key/delete", handles.DeleteMyS3Key)
+	auth.POST("/me/s3key/presign", handles.PresignMyS3Url)
+	auth.GET("/me/sessions", middlewares.AuthNotGuest, handles.ListMySessions)
+	auth.POST("/me/sessions/revoke", middlewares.AuthNotGuest, handles.RevokeMySession)
 	auth.POST("/auth/2fa/generate", handles.Generate2FA)
 	auth.POST("/auth/2fa/verify", handles.Verify2FA)
 	auth.GET("/auth/logout", handles.LogOut)
@@ -105,6 +125,7 @@ func Init(e *gin.Engine) {
 	fsAndShare(api.Group("/fs", middlewares.Auth(true)))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
 	_sharing(auth.Group("/share", middlewares.AuthNotGuest))
+	_directLink(auth.Group("/direct_link", middlewares.AuthNotGuest))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
@@ -133,10 +154,15 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/del_cache", handles.DelUserCache)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
+	user.GET("/s3key/list", handles.ListS3Keys)
+	user.POST("/s3key/delete", handles.DeleteS3Key)
+	user.GET("/session/list", handles.ListSessions)
+	user.POST("/session/revoke", handles.RevokeSessions)
 
 	storage := g.Group("/storage")
 	storage.GET("/list", handles.ListStorages)
 	storage.GET("/get", handles.GetStorage)
+	storage.GET("/status_history", handles.ListStorageStatusHistory)
 	storage.POST("/create", handles.CreateStorage)
 	storage.POST("/update", handles.UpdateStorage)
 	storage.POST("/delete", handles.DeleteStorage)
@@ -180,11 +206,17 @@ func admin(g *gin.RouterGroup) {
 	index.POST("/stop", middlewares.SearchIndex, handles.StopIndex)
 	index.POST("/clear", middlewares.SearchIndex, handles.ClearIndex)
 	index.GET("/progress", middlewares.SearchIndex, handles.GetProgress)
+	index.GET("/queue", middlewares.SearchIndex, handles.GetIndexQueue)
+	index.POST("/queue/clear", middlewares.SearchIndex, handles.ClearIndexQueue)
 
 	scan := g.Group("/scan")
 	scan.POST("/start", handles.StartManualScan)
 	scan.POST("/stop", handles.StopManualScan)
 	scan.GET("/progress", handles.GetManualScanProgress)
+
+	diskCache := g.Group("/disk_cache")
+	diskCache.GET("/stats", handles.GetDiskCacheStats)
+	diskCache.POST("/clear", handles.ClearDiskCache)
 }
 
 func fsAndShare(g *gin.RouterGroup) {
@@ -217,6 +249,9 @@ func _fs(g *gin.RouterGroup) {
 	// g.POST("/add_transmission", handles.SetTransmission)
 	g.POST("/add_offline_download", handles.AddOfflineDownload)
 	g.POST("/archive/decompress", handles.FsArchiveDecompress)
+	g.Any("/versions", handles.FsVersions)
+	g.POST("/versions/restore", handles.FsRestoreVersion)
+	g.GET("/versions/download", handles.FsDownloadVersion)
 	// Direct upload (client-side upload to storage)
 	g.POST("/get_direct_upload_info", middlewares.FsUp, handles.FsGetDirectUploadInfo)
 }
@@ -225,6 +260,16 @@ func _task(g *gin.RouterGroup) {
 	handles.SetupTaskRoute(g)
 }
 
+func _directLink(g *gin.RouterGroup) {
+	g.Any("/list", handles.ListDirectLinks)
+	g.GET("/get", handles.GetDirectLink)
+	g.POST("/create", handles.CreateDirectLink)
+	g.POST("/update", handles.UpdateDirectLink)
+	g.POST("/delete", handles.DeleteDirectLink)
+	g.POST("/revoke", handles.SetEnableDirectLink(true))
+	g.POST("/enable", handles.SetEnableDirectLink(false))
+}
+
 func _sharing(g *gin.RouterGroup) {
 	g.Any("/list", handles.ListSharings)
 	g.GET("/get", handles.GetSharing)
@@ -245,6 +290,8 @@ func Cors(r *gin.Engine) {
 }
 
 func InitS3(e *gin.Engine) {
+	e.Use(middlewares.Tracing)
 	Cors(e)
+	e.Use(middlewares.ServedBytes("s3"))
 	S3Server(e.Group("/"))
 }
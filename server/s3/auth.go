@@ -0,0 +1,216 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/itsHenry35/gofakes3/signature"
+	log "github.com/sirupsen/logrus"
+)
+
+var errAccessDenied = signature.APIError{
+	Code:           "AccessDenied",
+	Description:    "Access Denied.",
+	HTTPStatusCode: http.StatusForbidden,
+}
+
+var errInvalidAccessKeyId = signature.APIError{
+	Code:           "InvalidAccessKeyId",
+	Description:    "The access key ID you provided does not exist in our records.",
+	HTTPStatusCode: http.StatusForbidden,
+}
+
+func writeAPIError(w http.ResponseWriter, err signature.APIError) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(err.HTTPStatusCode)
+	_, _ = w.Write(signature.EncodeAPIErrorToResponse(err))
+}
+
+// withAuth authenticates the request by the global key pair or the keys of users,
+// and checks the permission of the user on the requested object.
+// Unsigned requests are only allowed to read public buckets.
+func withAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, apiErr := authenticate(r)
+		if apiErr != nil {
+			log.Warnf("s3 access denied: %s => %s, %s", r.RemoteAddr, r.URL, apiErr.Description)
+			writeAPIError(w, *apiErr)
+			return
+		}
+		if !authorize(r, user) {
+			log.Warnf("s3 access denied: %s => %s", r.RemoteAddr, r.URL)
+			writeAPIError(w, errAccessDenied)
+			return
+		}
+		if user != nil {
+			r = r.WithContext(context.WithValue(r.Context(), conf.UserKey, user))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isSigned(r *http.Request) bool {
+	q := r.URL.Query()
+	return r.Header.Get("Authorization") != "" || q.Get("X-Amz-Signature") != "" || q.Get("Signature") != ""
+}
+
+// authenticate returns the user of the request, nil for anonymous requests.
+// Signatures are verified against the stored secret of the access key only, so removing a key revokes it at once.
+func authenticate(r *http.Request) (*model.User, *signature.APIError) {
+	if !isSigned(r) {
+		return nil, nil
+	}
+	sig, apiErr := parseSigV4(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	globalKey, globalSecret := setting.GetStr(conf.S3AccessKeyId), setting.GetStr(conf.S3SecretAccessKey)
+	var (
+		user   *model.User
+		key    *model.S3AccessKey
+		secret string
+		err    error
+	)
+	if globalKey != "" && globalSecret != "" && sig.accessKey == globalKey {
+		secret = globalSecret
+		if user, err = op.GetAdmin(); err != nil {
+			return nil, &errAccessDenied
+		}
+	} else {
+		if key, err = op.GetS3AccessKeyByAccessKeyId(sig.accessKey); err != nil {
+			return nil, &errInvalidAccessKeyId
+		}
+		secret = key.SecretAccessKey
+		if user, err = op.GetUserById(key.UserId); err != nil || user.Disabled {
+			return nil, &errAccessDenied
+		}
+	}
+	if secret == "" {
+		return nil, &errInvalidAccessKeyId
+	}
+	if apiErr = verifySigV4(r, sig, secret, time.Now()); apiErr != nil {
+		return nil, apiErr
+	}
+	if key != nil {
+		if err := op.UpdateS3AccessKeyLastUsedTime(key); err != nil {
+			log.Warnf("failed update last used time of s3 key: %+v", err)
+		}
+	}
+	return user, nil
+}
+
+type s3Action int
+
+const (
+	actionRead s3Action = iota
+	actionWrite
+	actionRemove
+)
+
+func actionOf(r *http.Request) s3Action {
+	q := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return actionRead
+	case http.MethodDelete:
+		// aborting a multipart upload removes nothing visible
+		if q.Has("uploadId") {
+			return actionWrite
+		}
+		return actionRemove
+	case http.MethodPost:
+		if q.Has("delete") {
+			return actionRemove
+		}
+	}
+	return actionWrite
+}
+
+func authorize(r *http.Request, user *model.User) bool {
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	action := actionOf(r)
+	if !validKey(key) {
+		return false
+	}
+	if bucketName == "" {
+		// list buckets
+		return user != nil && action == actionRead
+	}
+	b, err := findBucket(bucketName)
+	if err != nil {
+		// only admins may learn whether a bucket exists
+		return user != nil && user.IsAdmin()
+	}
+	if user == nil {
+		if !b.PublicRead || action != actionRead {
+			return false
+		}
+		// anonymous reads are checked as the guest, so hidden and protected objects stay so
+		guest, err := op.GetGuest()
+		if err != nil {
+			return false
+		}
+		return canDo(r.Context(), guest, b, key, actionRead)
+	}
+	if user.IsAdmin() {
+		return true
+	}
+	ctx := context.WithValue(r.Context(), conf.UserKey, user)
+	if !canDo(ctx, user, b, key, action) {
+		return false
+	}
+	// the source of a copy has to be readable as well
+	if src := r.Header.Get("X-Amz-Copy-Source"); src != "" && r.Method == http.MethodPut {
+		if unescaped, err := url.PathUnescape(src); err == nil {
+			src = unescaped
+		}
+		srcBucketName, srcKey, _ := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+		srcBucket, err := findBucket(srcBucketName)
+		if err != nil {
+			return false
+		}
+		return canDo(ctx, user, srcBucket, srcKey, actionRead)
+	}
+	return true
+}
+
+func canDo(ctx context.Context, user *model.User, b Bucket, key string, action s3Action) bool {
+	bp, err := bucketPath(ctx, b)
+	if err != nil {
+		return false
+	}
+	p, err := objectPath(bp, key)
+	if err != nil {
+		return false
+	}
+	meta, _ := op.GetNearestMeta(p)
+	if !common.CanAccess(user, meta, p, "") {
+		return false
+	}
+	switch action {
+	case actionWrite:
+		if !user.CanWrite() && !common.CanWrite(meta, path.Dir(p)) {
+			return false
+		}
+		// overwriting an existing object removes its old content
+		if key != "" && !strings.HasSuffix(key, "/") {
+			if _, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true}); err == nil {
+				return user.CanRemove()
+			}
+		}
+		return true
+	case actionRemove:
+		return user.CanRemove()
+	}
+	return true
+}
@@ -9,7 +9,6 @@ import (
 	"io"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -35,15 +34,11 @@ var (
 
 // s3Backend implements the gofacess3.Backend interface to make an S3
 // backend for gofakes3
-type s3Backend struct {
-	meta *sync.Map
-}
+type s3Backend struct{}
 
 // newBackend creates a new SimpleBucketBackend.
 func newBackend() gofakes3.Backend {
-	return &s3Backend{
-		meta: new(sync.Map),
-	}
+	return &s3Backend{}
 }
 
 // ListBuckets always returns the default bucket.
@@ -54,7 +49,14 @@ func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, err
 	}
 	var response []gofakes3.BucketInfo
 	for _, b := range buckets {
-		node, _ := fs.Get(ctx, b.Path, &fs.GetArgs{})
+		p, err := bucketPath(ctx, b)
+		if err != nil {
+			continue
+		}
+		node, err := fs.Get(ctx, p, &fs.GetArgs{})
+		if err != nil {
+			continue
+		}
 		response = append(response, gofakes3.BucketInfo{
 			// Name:         gofakes3.URLEncode(b.Name),
 			Name:         b.Name,
@@ -66,7 +68,7 @@ func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, err
 
 // ListBucket lists the objects in the given bucket.
 func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *gofakes3.Prefix, page gofakes3.ListBucketPage) (*gofakes3.ObjectList, error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +88,18 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 
 	response := gofakes3.NewObjectList()
 	path, remaining := prefixParser(prefix)
+	if _, err = objectPath(bucketPath, path); err != nil {
+		return nil, err
+	}
 
-	err = b.entryListR(bucketPath, path, remaining, prefix.HasDelimiter, response)
+	// anonymous requests of public buckets list as the guest
+	user, ok := ctx.Value(conf.UserKey).(*model.User)
+	if !ok {
+		if user, err = op.GetGuest(); err != nil {
+			return nil, err
+		}
+	}
+	err = b.entryListR(user, bucketPath, path, remaining, prefix.HasDelimiter, response)
 	if err == gofakes3.ErrNoSuchKey {
 		// AWS just returns an empty list
 		response = gofakes3.NewObjectList()
@@ -99,16 +111,17 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 }
 
 // HeadObject returns the fileinfo for the given object name.
-//
-// Note that the metadata is not supported yet.
 func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName string) (*gofakes3.Object, error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
 	bucketPath := bucket.Path
 
-	fp := path.Join(bucketPath, objectName)
+	fp, err := objectPath(bucketPath, objectName)
+	if err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -127,11 +140,8 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 		"Content-Type":  utils.GetMimeType(fp),
 	}
 
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range loadObjectMeta(fp) {
+		meta[k] = v
 	}
 
 	return &gofakes3.Object{
@@ -145,13 +155,16 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 
 // GetObject fetchs the object from the filesystem.
 func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string, rangeRequest *gofakes3.ObjectRangeRequest) (s3Obj *gofakes3.Object, err error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return nil, err
 	}
 	bucketPath := bucket.Path
 
-	fp := path.Join(bucketPath, objectName)
+	fp, err := objectPath(bucketPath, objectName)
+	if err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -202,11 +215,8 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 		"Content-Type":        utils.GetMimeType(fp),
 	}
 
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range loadObjectMeta(fp) {
+		meta[k] = v
 	}
 
 	return &gofakes3.Object{
@@ -232,7 +242,7 @@ func (b *s3Backend) PutObject(
 	meta map[string]string,
 	input io.Reader, size int64,
 ) (result gofakes3.PutObjectResult, err error) {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return result, err
 	}
@@ -241,7 +251,10 @@ func (b *s3Backend) PutObject(
 	isDir := strings.HasSuffix(objectName, "/")
 	log.Debugf("isDir: %v", isDir)
 
-	fp := path.Join(bucketPath, objectName)
+	fp, err := objectPath(bucketPath, objectName)
+	if err != nil {
+		return result, err
+	}
 	log.Debugf("fp: %s, bucketPath: %s, objectName: %s", fp, bucketPath, objectName)
 
 	var reqPath string
@@ -308,15 +321,27 @@ func (b *s3Backend) PutObject(
 	// 	return result, err
 	// }
 
-	b.meta.Store(fp, meta)
+	saveObjectMeta(fp, meta)
 
 	return result, nil
 }
 
 // DeleteMulti deletes multiple objects in a single request.
 func (b *s3Backend) DeleteMulti(ctx context.Context, bucketName string, objects ...string) (result gofakes3.MultiDeleteResult, rerr error) {
+	// the keys of the body are not seen by the authorization of the request
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	bucket, err := findBucket(bucketName)
+	if err != nil {
+		return result, err
+	}
 	for _, object := range objects {
-		if err := b.deleteObject(ctx, bucketName, object); err != nil {
+		if user != nil && !user.IsAdmin() && !canDo(ctx, user, bucket, object, actionRemove) {
+			result.Error = append(result.Error, gofakes3.ErrorResult{
+				Code:    gofakes3.ErrorCode(errAccessDenied.Code),
+				Message: errAccessDenied.Description,
+				Key:     object,
+			})
+		} else if err := b.deleteObject(ctx, bucketName, object); err != nil {
 			log.Errorf("delete object failed: %v", err)
 			result.Error = append(result.Error, gofakes3.ErrorResult{
 				Code:    gofakes3.ErrInternal,
@@ -340,13 +365,16 @@ func (b *s3Backend) DeleteObject(ctx context.Context, bucketName, objectName str
 
 // deleteObject deletes the object from the filesystem.
 func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName string) error {
-	bucket, err := getBucketByName(bucketName)
+	bucket, err := getBucketByName(ctx, bucketName)
 	if err != nil {
 		return err
 	}
 	bucketPath := bucket.Path
 
-	fp := path.Join(bucketPath, objectName)
+	fp, err := objectPath(bucketPath, objectName)
+	if err != nil {
+		return err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	// S3 does not report an error when attemping to delete a key that does not exist, so
 	// we need to skip IsNotExist errors.
@@ -389,13 +417,16 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		return result, nil
 	}
 
-	srcB, err := getBucketByName(srcBucket)
+	srcB, err := getBucketByName(ctx, srcBucket)
 	if err != nil {
 		return result, err
 	}
 	srcBucketPath := srcB.Path
 
-	srcFp := path.Join(srcBucketPath, srcKey)
+	srcFp, err := objectPath(srcBucketPath, srcKey)
+	if err != nil {
+		return result, err
+	}
 	fmeta, _ := op.GetNearestMeta(srcFp)
 	srcNode, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), srcFp, &fs.GetArgs{})
 
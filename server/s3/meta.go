@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// storedMetaHeaders are kept with the object besides the x-amz-meta-* ones
+var storedMetaHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+}
+
+// filterObjectMeta picks the metadata worth persisting from the request headers
+func filterObjectMeta(meta map[string]string) map[string]string {
+	res := make(map[string]string)
+	for k, v := range meta {
+		k = http.CanonicalHeaderKey(k)
+		if strings.HasPrefix(k, "X-Amz-Meta-") {
+			res[k] = v
+			continue
+		}
+		for _, h := range storedMetaHeaders {
+			if k == h {
+				res[k] = v
+				break
+			}
+		}
+	}
+	return res
+}
+
+func loadObjectMeta(fp string) map[string]string {
+	m, err := db.GetS3ObjectMeta(fp)
+	if err != nil {
+		return nil
+	}
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(m.MetaRaw), &meta); err != nil {
+		log.Warnf("failed to parse s3 object meta of %s: %+v", fp, err)
+		return nil
+	}
+	return meta
+}
+
+func saveObjectMeta(fp string, meta map[string]string) {
+	meta = filterObjectMeta(meta)
+	var err error
+	if len(meta) == 0 {
+		err = db.DeleteS3ObjectMeta(fp)
+	} else {
+		raw, _ := json.Marshal(meta)
+		err = db.SaveS3ObjectMeta(&model.S3ObjectMeta{Path: fp, MetaRaw: string(raw)})
+	}
+	if err != nil {
+		log.Warnf("failed to save s3 object meta of %s: %+v", fp, err)
+	}
+}
+
+// onFsChange keeps the stored metadata along with the objects changed outside the gateway
+func onFsChange(ctx context.Context, change model.FsChange) {
+	var err error
+	switch change.Op {
+	case model.FsChangePut, model.FsChangeRemove:
+		// an object put by the gateway saves its metadata after the put
+		err = db.DeleteS3ObjectMeta(change.Path)
+	case model.FsChangeRename, model.FsChangeMove:
+		err = db.MoveS3ObjectMeta(change.Path, change.DstPath)
+	}
+	if err != nil {
+		log.Warnf("failed to update s3 object meta of %s: %+v", change.Path, err)
+	}
+}
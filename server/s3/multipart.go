@@ -0,0 +1,540 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/itsHenry35/gofakes3"
+	xml "github.com/minio/xxml"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// uploadExpiration is how long an unfinished multipart upload is kept
+	uploadExpiration = 7 * 24 * time.Hour
+	uploadIDLength   = 32
+)
+
+// multipartHandler serves the multipart upload requests instead of gofakes3,
+// whose uploads only live in memory. Parts are spooled to the temp dir and
+// tracked in the database, so uploads survive restarts.
+type multipartHandler struct {
+	next    http.Handler
+	backend gofakes3.Backend
+}
+
+func withMultipart(h http.Handler, backend gofakes3.Backend) http.Handler {
+	return &multipartHandler{next: h, backend: backend}
+}
+
+func (m *multipartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		query          = r.URL.Query()
+		bucket, object = splitBucketObject(r.URL.Path)
+		uploadID       = query.Get("uploadId")
+		err            error
+	)
+	if (uploadID != "" || query.Has("uploads")) && currentUser(r) == nil {
+		// multipart uploads belong to the user who started them, anonymous requests see none
+		writeAPIError(w, errAccessDenied)
+		return
+	}
+	if uploadID != "" {
+		switch r.Method {
+		case http.MethodGet:
+			err = m.listParts(w, r, bucket, object, uploadID)
+		case http.MethodPut:
+			err = m.putPart(w, r, bucket, object, uploadID)
+		case http.MethodPost:
+			err = m.complete(w, r, bucket, object, uploadID)
+		case http.MethodDelete:
+			err = m.abort(w, r, bucket, object, uploadID)
+		default:
+			err = gofakes3.ErrMethodNotAllowed
+		}
+	} else if query.Has("uploads") {
+		switch r.Method {
+		case http.MethodGet:
+			err = m.listUploads(w, r, bucket)
+		case http.MethodPost:
+			err = m.initiate(w, r, bucket, object)
+		default:
+			err = gofakes3.ErrMethodNotAllowed
+		}
+	} else {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+	if err != nil {
+		writeS3Error(w, r, err)
+	}
+}
+
+func splitBucketObject(p string) (string, string) {
+	bucket, object, _ := strings.Cut(strings.Trim(p, "/"), "/")
+	return bucket, object
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error) {
+	resp, ok := err.(*gofakes3.ErrorResponse)
+	if !ok {
+		var e gofakes3.Error
+		if !errors.As(err, &e) {
+			log.Errorf("s3 multipart upload: %+v", err)
+			e = gofakes3.ErrInternal
+		}
+		resp = &gofakes3.ErrorResponse{Code: e.ErrorCode(), Message: e.ErrorCode().Message()}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(resp.Code.Status())
+	if r.Method != http.MethodHead {
+		_ = encodeXML(w, resp)
+	}
+}
+
+func encodeXML(w io.Writer, v any) error {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set("Content-Type", "application/xml")
+	}
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	xe := xml.NewEncoder(w)
+	xe.Indent("", "  ")
+	return xe.Encode(v)
+}
+
+func currentUser(r *http.Request) *model.User {
+	user, _ := r.Context().Value(conf.UserKey).(*model.User)
+	return user
+}
+
+func uploadDir(id string) string {
+	return filepath.Join(conf.Conf.TempDir, "s3-uploads", id)
+}
+
+func partFile(id string, partNumber int) string {
+	return filepath.Join(uploadDir(id), strconv.Itoa(partNumber))
+}
+
+// getUpload returns the upload if it belongs to the object and is visible to the request user
+func getUpload(r *http.Request, bucket, object, id string) (*model.S3Upload, error) {
+	u, err := db.GetS3UploadById(id)
+	if err != nil || u.Bucket != bucket || u.Key != object {
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	if user := currentUser(r); user == nil || !user.IsAdmin() && user.ID != u.UserId {
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	return u, nil
+}
+
+func removeUpload(id string) error {
+	if err := db.DeleteS3Upload(id); err != nil {
+		return err
+	}
+	return errors.WithStack(os.RemoveAll(uploadDir(id)))
+}
+
+func (m *multipartHandler) initiate(w http.ResponseWriter, r *http.Request, bucket, object string) error {
+	if object == "" {
+		return gofakes3.ErrInvalidURI
+	}
+	b, err := getBucketByName(r.Context(), bucket)
+	if err != nil {
+		return err
+	}
+	if _, err = objectPath(b.Path, object); err != nil {
+		return err
+	}
+	meta := make(map[string]string)
+	for k, v := range r.Header {
+		meta[k] = v[0]
+	}
+	raw, _ := json.Marshal(filterObjectMeta(meta))
+	u := &model.S3Upload{
+		ID:      random.String(uploadIDLength),
+		Bucket:  bucket,
+		Key:     object,
+		MetaRaw: string(raw),
+	}
+	if user := currentUser(r); user != nil {
+		u.UserId = user.ID
+	}
+	if err := db.CreateS3Upload(u); err != nil {
+		return err
+	}
+	return encodeXML(w, gofakes3.InitiateMultipartUpload{
+		Bucket:   bucket,
+		Key:      object,
+		UploadID: gofakes3.UploadID(u.ID),
+	})
+}
+
+func (m *multipartHandler) putPart(w http.ResponseWriter, r *http.Request, bucket, object, id string) (err error) {
+	defer r.Body.Close()
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber <= 0 || partNumber > gofakes3.MaxUploadPartNumber {
+		return gofakes3.ErrInvalidPart
+	}
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		return gofakes3.ErrorMessage(gofakes3.ErrNotImplemented, "UploadPartCopy is not supported")
+	}
+	if _, err = getUpload(r, bucket, object, id); err != nil {
+		return err
+	}
+	size := r.ContentLength
+	var rdr io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		rdr = newChunkedReader(r.Body)
+		size, err = strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return gofakes3.ErrMissingContentLength
+		}
+	}
+	if size < 0 {
+		return gofakes3.ErrMissingContentLength
+	}
+
+	dir := uploadDir(id)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = f.Close()
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(rdr, size))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if n != size {
+		return gofakes3.ErrIncompleteBody
+	}
+	sum := h.Sum(nil)
+	if md5Base64 := r.Header.Get("Content-MD5"); md5Base64 != "" {
+		expected, e := base64.StdEncoding.DecodeString(md5Base64)
+		if e != nil || !bytes.Equal(expected, sum) {
+			return gofakes3.ErrBadDigest
+		}
+	}
+	if err = f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	// replace the previous upload of the same part
+	if err = os.Rename(f.Name(), partFile(id, partNumber)); err != nil {
+		return errors.WithStack(err)
+	}
+	etag := `"` + hex.EncodeToString(sum) + `"`
+	if err = db.SaveS3UploadPart(&model.S3UploadPart{
+		UploadID:   id,
+		PartNumber: partNumber,
+		Size:       size,
+		ETag:       etag,
+	}); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	return nil
+}
+
+func (m *multipartHandler) complete(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	u, err := getUpload(r, bucket, object, id)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var in gofakes3.CompleteMultipartUploadRequest
+	if err := xml.Unmarshal(body, &in); err != nil || len(in.Parts) == 0 {
+		return gofakes3.ErrMalformedXML
+	}
+	stored, err := db.GetS3UploadParts(id)
+	if err != nil {
+		return err
+	}
+	partsMap := make(map[int]model.S3UploadPart, len(stored))
+	for _, p := range stored {
+		partsMap[p.PartNumber] = p
+	}
+
+	var (
+		total int64
+		files = make([]string, 0, len(in.Parts))
+		sums  = md5.New()
+		last  = 0
+	)
+	for _, p := range in.Parts {
+		if p.PartNumber <= last {
+			return gofakes3.ErrInvalidPartOrder
+		}
+		last = p.PartNumber
+		sp, ok := partsMap[p.PartNumber]
+		if !ok || strings.Trim(sp.ETag, `"`) != strings.Trim(p.ETag, `"`) {
+			return gofakes3.ErrInvalidPart
+		}
+		sum, _ := hex.DecodeString(strings.Trim(sp.ETag, `"`))
+		sums.Write(sum)
+		total += sp.Size
+		files = append(files, partFile(id, p.PartNumber))
+	}
+
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(u.MetaRaw), &meta); err != nil || meta == nil {
+		meta = make(map[string]string)
+	}
+	rdr := &multiFileReader{files: files}
+	defer rdr.Close()
+	if _, err := m.backend.PutObject(r.Context(), bucket, object, meta, rdr, total); err != nil {
+		return err
+	}
+	if err := removeUpload(id); err != nil {
+		log.Warnf("failed to remove completed s3 upload %s: %+v", id, err)
+	}
+	return encodeXML(w, gofakes3.CompleteMultipartUploadResult{
+		Bucket: bucket,
+		Key:    object,
+		ETag:   fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sums.Sum(nil)), len(files)),
+	})
+}
+
+func (m *multipartHandler) abort(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	if _, err := getUpload(r, bucket, object, id); err != nil {
+		return err
+	}
+	if err := removeUpload(id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (m *multipartHandler) listParts(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	if _, err := getUpload(r, bucket, object, id); err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	marker, _ := strconv.Atoi(query.Get("part-number-marker"))
+	maxParts, err := strconv.Atoi(query.Get("max-parts"))
+	if err != nil || maxParts <= 0 || maxParts > gofakes3.MaxUploadPartsLimit {
+		maxParts = gofakes3.DefaultMaxUploadParts
+	}
+	parts, err := db.GetS3UploadParts(id)
+	if err != nil {
+		return err
+	}
+	out := gofakes3.ListMultipartUploadPartsResult{
+		Bucket:           bucket,
+		Key:              object,
+		UploadID:         gofakes3.UploadID(id),
+		PartNumberMarker: marker,
+		MaxParts:         int64(maxParts),
+	}
+	for _, p := range parts {
+		if p.PartNumber <= marker {
+			continue
+		}
+		if len(out.Parts) == maxParts {
+			out.IsTruncated = true
+			break
+		}
+		out.Parts = append(out.Parts, gofakes3.ListMultipartUploadPartItem{
+			PartNumber:   p.PartNumber,
+			LastModified: gofakes3.NewContentTime(p.UpdatedAt),
+			ETag:         p.ETag,
+			Size:         p.Size,
+		})
+		out.NextPartNumberMarker = p.PartNumber
+	}
+	return encodeXML(w, out)
+}
+
+func (m *multipartHandler) listUploads(w http.ResponseWriter, r *http.Request, bucket string) error {
+	if _, err := getBucketByName(r.Context(), bucket); err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	keyMarker, idMarker := query.Get("key-marker"), query.Get("upload-id-marker")
+	maxUploads, err := strconv.Atoi(query.Get("max-uploads"))
+	if err != nil || maxUploads <= 0 || maxUploads > gofakes3.MaxUploadsLimit {
+		maxUploads = gofakes3.DefaultMaxUploads
+	}
+	uploads, err := db.GetS3UploadsByBucket(bucket)
+	if err != nil {
+		return err
+	}
+	user := currentUser(r)
+	out := gofakes3.ListMultipartUploadsResult{
+		Bucket:         bucket,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: gofakes3.UploadID(idMarker),
+		MaxUploads:     int64(maxUploads),
+		Prefix:         prefix,
+	}
+	for _, u := range uploads {
+		if !strings.HasPrefix(u.Key, prefix) {
+			continue
+		}
+		if !user.IsAdmin() && user.ID != u.UserId {
+			continue
+		}
+		if keyMarker != "" && (u.Key < keyMarker || u.Key == keyMarker && (idMarker == "" || u.ID <= idMarker)) {
+			continue
+		}
+		if len(out.Uploads) == maxUploads {
+			out.IsTruncated = true
+			break
+		}
+		out.Uploads = append(out.Uploads, gofakes3.ListMultipartUploadItem{
+			Key:       u.Key,
+			UploadID:  gofakes3.UploadID(u.ID),
+			Initiated: gofakes3.NewContentTime(u.CreatedAt),
+		})
+		out.NextKeyMarker, out.NextUploadIDMarker = u.Key, gofakes3.UploadID(u.ID)
+	}
+	return encodeXML(w, out)
+}
+
+var cleanUploadsOnce sync.Once
+
+// initMultipart starts removing the expired uploads and keeps the object metadata in sync with the fs
+func initMultipart() {
+	cleanUploadsOnce.Do(func() {
+		op.RegisterFsChangeHook(onFsChange)
+		go func() {
+			for {
+				cleanExpiredUploads()
+				time.Sleep(time.Hour)
+			}
+		}()
+	})
+}
+
+func cleanExpiredUploads() {
+	uploads, err := db.GetS3UploadsCreatedBefore(time.Now().Add(-uploadExpiration))
+	if err != nil {
+		log.Errorf("failed to get expired s3 uploads: %+v", err)
+		return
+	}
+	for _, u := range uploads {
+		if err := removeUpload(u.ID); err != nil {
+			log.Warnf("failed to remove expired s3 upload %s: %+v", u.ID, err)
+		}
+	}
+}
+
+// multiFileReader reads the part files one after another, opening each only when reached
+type multiFileReader struct {
+	files []string
+	cur   *os.File
+}
+
+func (m *multiFileReader) Read(p []byte) (int, error) {
+	for {
+		if m.cur == nil {
+			if len(m.files) == 0 {
+				return 0, io.EOF
+			}
+			f, err := os.Open(m.files[0])
+			if err != nil {
+				return 0, err
+			}
+			m.cur, m.files = f, m.files[1:]
+		}
+		n, err := m.cur.Read(p)
+		if err == io.EOF {
+			_ = m.cur.Close()
+			m.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (m *multiFileReader) Close() error {
+	if m.cur != nil {
+		return m.cur.Close()
+	}
+	return nil
+}
+
+// chunkedReader decodes the aws-chunked body of streaming signed requests,
+// the chunk signatures are not verified
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{r: bufio.NewReader(r)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining == 0 {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		sizeStr, _, _ := strings.Cut(strings.TrimRight(line, "\r\n"), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 64)
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("invalid chunk size: %q", sizeStr)
+		}
+		if size == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+		c.remaining = size
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining == 0 {
+		// skip the CRLF after the chunk data
+		if _, e := c.r.Discard(2); e != nil && err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
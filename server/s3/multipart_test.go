@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChunkedReader(t *testing.T) {
+	sig := ";chunk-signature=" + strings.Repeat("0", 64) + "\r\n"
+	body := "5" + sig + "hello\r\n" + "6" + sig + " world\r\n" + "0" + sig + "\r\n"
+	b, err := io.ReadAll(newChunkedReader(strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello world" {
+		t.Errorf("got %q", b)
+	}
+	// unsigned streaming payloads have no chunk extension
+	b, err = io.ReadAll(newChunkedReader(strings.NewReader("3\r\nabc\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n")))
+	if err != nil || string(b) != "abc" {
+		t.Errorf("got %q, %v", b, err)
+	}
+	if _, err = io.ReadAll(newChunkedReader(strings.NewReader("5" + sig + "hel"))); err == nil {
+		t.Error("truncated body is not reported")
+	}
+}
+
+func TestMultipartAnonymous(t *testing.T) {
+	m := &multipartHandler{next: http.NotFoundHandler()}
+	for _, target := range []string{
+		"/bucket?uploads",
+		"/bucket/a.txt?uploadId=x",
+		"/bucket/a.txt?uploadId=x&partNumber=1",
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete} {
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%s %s: got %d", method, target, w.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const presignRegion = "us-east-1"
+
+// Presign returns a SigV4 query-signed url of an object on the gateway.
+// endpoint is where the gateway is reachable, e.g. http://host:5246 or http://host/s3,
+// its path prefix is not part of the signature since it is stripped before the gateway sees the request.
+func Presign(endpoint, method, bucket, key, accessKeyId, secret string, expires time.Duration, now time.Time) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("invalid expires: %s", expires)
+	}
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, presignRegion, "s3", "aws4_request"}, "/")
+	objectPath := "/" + bucket + "/" + strings.TrimPrefix(key, "/")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", accessKeyId+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		encodeObjectPath(objectPath),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+	signingKey := hmacSHA256([]byte("AWS4"+secret), date)
+	signingKey = hmacSHA256(signingKey, presignRegion)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	query.Set("X-Amz-Signature", hex.EncodeToString(hmacSHA256(signingKey, stringToSign)))
+
+	return u.Scheme + "://" + u.Host + u.Path + encodeObjectPath(objectPath) + "?" +
+		strings.ReplaceAll(query.Encode(), "+", "%20"), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// encodeObjectPath escapes everything but the unreserved characters and slashes, as SigV4 requires
+func encodeObjectPath(p string) string {
+	var sb strings.Builder
+	for _, b := range []byte(p) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' ||
+			b == '-' || b == '_' || b == '.' || b == '~' || b == '/' {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
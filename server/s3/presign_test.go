@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsHenry35/gofakes3/signature"
+)
+
+func TestPresign(t *testing.T) {
+	now := time.Now()
+	for _, endpoint := range []string{"http://example.com:5246", "http://example.com/s3"} {
+		u, err := Presign(endpoint, "GET", "bucket", "dir/a file+.txt", "OLTESTKEY", "secret", time.Hour, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verify := func(method, secret string, at time.Time) *signature.APIError {
+			r := httptest.NewRequest(method, u, nil)
+			// the gateway sees the path without the prefix of the endpoint
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, "/s3")
+			sig, apiErr := parseSigV4(r)
+			if apiErr == nil {
+				apiErr = verifySigV4(r, sig, secret, at)
+			}
+			return apiErr
+		}
+		if err := verify("GET", "secret", now); err != nil {
+			t.Errorf("verify presigned url %s: %+v", u, err)
+		}
+		if verify("PUT", "secret", now) == nil {
+			t.Errorf("method of presigned url %s is not bound", u)
+		}
+		if verify("GET", "other", now) == nil {
+			t.Errorf("presigned url %s verified with a wrong secret", u)
+		}
+		if verify("GET", "secret", now.Add(2*time.Hour)) == nil {
+			t.Errorf("expired presigned url %s verified", u)
+		}
+	}
+}
+
+func TestVerifyHeader(t *testing.T) {
+	now := time.Now().UTC()
+	r := httptest.NewRequest("GET", "http://example.com/bucket/a.txt?list-type=2", nil)
+	date := now.Format(iso8601Format)
+	r.Header.Set("X-Amz-Date", date)
+	r.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	s := &sigV4{accessKey: "OLTESTKEY", date: now.Format("20060102"), region: "us-east-1",
+		signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"}}
+	headers, _ := canonicalHeaders(r, s.signedHeaders)
+	canonical := strings.Join([]string{"GET", "/bucket/a.txt", "list-type=2", headers, strings.Join(s.signedHeaders, ";"), emptySHA256}, "\n")
+	hashed := sha256.Sum256([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4secret"), s.date)
+	for _, v := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	sig := hex.EncodeToString(hmacSHA256(key, strings.Join([]string{signV4Algorithm, date, s.scope(), hex.EncodeToString(hashed[:])}, "\n")))
+	r.Header.Set("Authorization", signV4Algorithm+" Credential=OLTESTKEY/"+s.scope()+", SignedHeaders="+strings.Join(s.signedHeaders, ";")+", Signature="+sig)
+
+	parsed, apiErr := parseSigV4(r)
+	if apiErr != nil {
+		t.Fatalf("parse: %+v", apiErr)
+	}
+	if parsed.accessKey != "OLTESTKEY" {
+		t.Errorf("access key: %s", parsed.accessKey)
+	}
+	if apiErr := verifySigV4(r, parsed, "secret", now); apiErr != nil {
+		t.Errorf("verify: %+v", apiErr)
+	}
+	if verifySigV4(r, parsed, "other", now) == nil {
+		t.Error("verified with a wrong secret")
+	}
+	if verifySigV4(r, parsed, "secret", now.Add(time.Hour)) == nil {
+		t.Error("verified an old request")
+	}
+	r.Header.Set("Authorization", "AWS OLTESTKEY:c2lnbmF0dXJl")
+	if _, apiErr := parseSigV4(r); apiErr == nil {
+		t.Error("accepted a v2 signature")
+	}
+}
+
+func TestVerifyPayload(t *testing.T) {
+	now := time.Now().UTC()
+	signed := func(body, payload string) *http.Request {
+		r := httptest.NewRequest("PUT", "http://example.com/bucket/a.txt", strings.NewReader(body))
+		date := now.Format(iso8601Format)
+		r.Header.Set("X-Amz-Date", date)
+		r.Header.Set("X-Amz-Content-Sha256", payload)
+		s := &sigV4{date: now.Format("20060102"), region: "us-east-1",
+			signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"}}
+		headers, _ := canonicalHeaders(r, s.signedHeaders)
+		canonical := strings.Join([]string{"PUT", "/bucket/a.txt", "", headers, strings.Join(s.signedHeaders, ";"), payload}, "\n")
+		hashed := sha256.Sum256([]byte(canonical))
+		key := hmacSHA256([]byte("AWS4secret"), s.date)
+		for _, v := range []string{s.region, "s3", "aws4_request"} {
+			key = hmacSHA256(key, v)
+		}
+		sig := hex.EncodeToString(hmacSHA256(key, strings.Join([]string{signV4Algorithm, date, s.scope(), hex.EncodeToString(hashed[:])}, "\n")))
+		r.Header.Set("Authorization", signV4Algorithm+" Credential=OLTESTKEY/"+s.scope()+", SignedHeaders="+strings.Join(s.signedHeaders, ";")+", Signature="+sig)
+		return r
+	}
+	verify := func(r *http.Request) ([]byte, error) {
+		parsed, apiErr := parseSigV4(r)
+		if apiErr == nil {
+			apiErr = verifySigV4(r, parsed, "secret", now)
+		}
+		if apiErr != nil {
+			return nil, errors.New(apiErr.Description)
+		}
+		return io.ReadAll(r.Body)
+	}
+	sum := sha256.Sum256([]byte("hello"))
+	if b, err := verify(signed("hello", hex.EncodeToString(sum[:]))); err != nil || string(b) != "hello" {
+		t.Errorf("got %q, %v", b, err)
+	}
+	if _, err := verify(signed("hellO", hex.EncodeToString(sum[:]))); err == nil {
+		t.Error("body not matching the signed hash is read")
+	}
+	if b, err := verify(signed("hellO", unsignedPayload)); err != nil || string(b) != "hellO" {
+		t.Errorf("unsigned payload: got %q, %v", b, err)
+	}
+	if _, err := verify(signed("hello", "not a hash")); err == nil {
+		t.Error("malformed payload hash is accepted")
+	}
+	// a reader stopping at the length still sees the mismatch
+	r := signed("hellO", hex.EncodeToString(sum[:]))
+	parsed, _ := parseSigV4(r)
+	if apiErr := verifySigV4(r, parsed, "secret", now); apiErr != nil {
+		t.Fatal(apiErr.Description)
+	}
+	if _, err := r.Body.Read(make([]byte, 5)); err == nil {
+		t.Error("mismatch at the declared length is not reported")
+	}
+}
@@ -0,0 +1,249 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itsHenry35/gofakes3"
+	"github.com/itsHenry35/gofakes3/signature"
+)
+
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	iso8601Format   = "20060102T150405Z"
+	emptySHA256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// maxClockSkew is how far the date of a header-signed request may be from now
+	maxClockSkew = 15 * time.Minute
+	maxPresign   = 7 * 24 * time.Hour
+)
+
+var (
+	errSignatureVersion = signature.APIError{
+		Code:           "InvalidRequest",
+		Description:    "Only AWS Signature Version 4 is supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	errMalformedSignature = signature.APIError{
+		Code:           "AuthorizationHeaderMalformed",
+		Description:    "The authorization header or query is malformed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+	errSignatureDoesNotMatch = signature.APIError{
+		Code:           "SignatureDoesNotMatch",
+		Description:    "The request signature we calculated does not match the signature you provided.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+	errRequestExpired = signature.APIError{
+		Code:           "AccessDenied",
+		Description:    "Request has expired.",
+		HTTPStatusCode: http.StatusForbidden,
+	}
+)
+
+// sigV4 is a parsed SigV4 signature, from the Authorization header or the query of a presigned url
+type sigV4 struct {
+	accessKey     string
+	date          string // yyyymmdd of the scope
+	region        string
+	signedHeaders []string
+	signature     string
+	presigned     bool
+}
+
+func (s *sigV4) scope() string {
+	return strings.Join([]string{s.date, s.region, "s3", "aws4_request"}, "/")
+}
+
+func parseSigV4(r *http.Request) (*sigV4, *signature.APIError) {
+	var cred, signedHeaders, sig string
+	s := &sigV4{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		rest, ok := strings.CutPrefix(auth, signV4Algorithm)
+		if !ok {
+			return nil, &errSignatureVersion
+		}
+		fields := strings.Split(strings.ReplaceAll(rest, " ", ""), ",")
+		if len(fields) != 3 {
+			return nil, &errMalformedSignature
+		}
+		var ok1, ok2, ok3 bool
+		cred, ok1 = strings.CutPrefix(fields[0], "Credential=")
+		signedHeaders, ok2 = strings.CutPrefix(fields[1], "SignedHeaders=")
+		sig, ok3 = strings.CutPrefix(fields[2], "Signature=")
+		if !ok1 || !ok2 || !ok3 {
+			return nil, &errMalformedSignature
+		}
+	} else {
+		q := r.URL.Query()
+		if q.Get("X-Amz-Algorithm") != signV4Algorithm {
+			return nil, &errSignatureVersion
+		}
+		cred, signedHeaders, sig = q.Get("X-Amz-Credential"), q.Get("X-Amz-SignedHeaders"), q.Get("X-Amz-Signature")
+		s.presigned = true
+	}
+	parts := strings.Split(cred, "/")
+	if len(parts) != 5 || parts[0] == "" || parts[3] != "s3" || parts[4] != "aws4_request" || sig == "" {
+		return nil, &errMalformedSignature
+	}
+	if _, err := time.Parse("20060102", parts[1]); err != nil {
+		return nil, &errMalformedSignature
+	}
+	s.accessKey, s.date, s.region = parts[0], parts[1], parts[2]
+	s.signedHeaders = strings.Split(signedHeaders, ";")
+	s.signature = sig
+	return s, nil
+}
+
+// verifySigV4 checks the signature against the secret of its access key
+func verifySigV4(r *http.Request, s *sigV4, secret string, now time.Time) *signature.APIError {
+	q := r.URL.Query()
+	date := r.Header.Get("X-Amz-Date")
+	if date == "" {
+		date = r.Header.Get("Date")
+	}
+	if s.presigned {
+		date = q.Get("X-Amz-Date")
+	}
+	t, err := time.Parse(iso8601Format, date)
+	if err != nil || t.Format("20060102") != s.date {
+		return &errMalformedSignature
+	}
+	if s.presigned {
+		expires, err := strconv.ParseInt(q.Get("X-Amz-Expires"), 10, 64)
+		if err != nil || expires <= 0 || time.Duration(expires)*time.Second > maxPresign {
+			return &errMalformedSignature
+		}
+		if now.Before(t.Add(-maxClockSkew)) || now.After(t.Add(time.Duration(expires)*time.Second)) {
+			return &errRequestExpired
+		}
+	} else if now.Sub(t) > maxClockSkew || t.Sub(now) > maxClockSkew {
+		return &errRequestExpired
+	}
+
+	headers, ok := canonicalHeaders(r, s.signedHeaders)
+	if !ok {
+		return &errMalformedSignature
+	}
+	q.Del("X-Amz-Signature")
+	payload := unsignedPayload
+	var payloadHash []byte
+	if !s.presigned {
+		payload = r.Header.Get("X-Amz-Content-Sha256")
+		if payload == "" {
+			payload = emptySHA256
+		}
+		if payload != unsignedPayload && !strings.HasPrefix(payload, "STREAMING-") {
+			if payloadHash, err = hex.DecodeString(payload); err != nil || len(payloadHash) != sha256.Size {
+				return &errMalformedSignature
+			}
+		}
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		encodeObjectPath(r.URL.Path),
+		strings.ReplaceAll(q.Encode(), "+", "%20"),
+		headers,
+		strings.Join(s.signedHeaders, ";"),
+		payload,
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{signV4Algorithm, date, s.scope(), hex.EncodeToString(hashed[:])}, "\n")
+	signingKey := hmacSHA256([]byte("AWS4"+secret), s.date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	expected := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(s.signature)) {
+		return &errSignatureDoesNotMatch
+	}
+	if payloadHash != nil && r.Body != nil {
+		r.Body = &payloadReader{ReadCloser: r.Body, hash: sha256.New(), expected: payloadHash, size: r.ContentLength}
+	}
+	return nil
+}
+
+var errContentSHA256Mismatch = gofakes3.ErrorMessage(gofakes3.ErrBadDigest,
+	"The provided 'x-amz-content-sha256' header does not match what was computed.")
+
+// payloadReader fails the read of the body once it is complete but differs from the signed hash.
+// The check is done at the declared length too, as readers may stop there without seeing EOF.
+type payloadReader struct {
+	io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+	size     int64
+	read     int64
+	checked  bool
+	err      error
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	if p.checked {
+		return p.ReadCloser.Read(b)
+	}
+	n, err := p.ReadCloser.Read(b)
+	p.hash.Write(b[:n])
+	p.read += int64(n)
+	if err == io.EOF || (p.size >= 0 && p.read >= p.size) {
+		p.checked = true
+		if !bytes.Equal(p.hash.Sum(nil), p.expected) {
+			p.err = errContentSHA256Mismatch
+			return n, p.err
+		}
+	}
+	return n, err
+}
+
+// canonicalHeaders builds the canonical headers from the signed ones, which must be sorted and include host
+func canonicalHeaders(r *http.Request, signed []string) (string, bool) {
+	if !sort.StringsAreSorted(signed) {
+		return "", false
+	}
+	var sb strings.Builder
+	hasHost := false
+	for _, name := range signed {
+		var values []string
+		switch name {
+		case "host":
+			hasHost = true
+			values = []string{r.Host}
+		case "content-length":
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		case "transfer-encoding":
+			values = r.TransferEncoding
+		default:
+			v, ok := r.Header[http.CanonicalHeaderKey(name)]
+			if !ok && name == "expect" {
+				// the http server removes it
+				v, ok = []string{"100-continue"}, true
+			}
+			if !ok {
+				return "", false
+			}
+			values = v
+		}
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		for i, v := range values {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(strings.Join(strings.Fields(v), " "))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), hasHost
+}
@@ -5,6 +5,8 @@ package s3
 import (
 	"context"
 	"encoding/json"
+	"net/url"
+	"path"
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -13,12 +15,16 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/itsHenry35/gofakes3"
 )
 
 type Bucket struct {
 	Name string `json:"name"`
 	Path string `json:"path"`
+	// PublicRead allows anonymous requests to list and get objects of the bucket
+	PublicRead bool `json:"public_read"`
 }
 
 const emptyObjectName = "ThisIsAnEmptyFolderInTheS3Bucket"
@@ -29,7 +35,7 @@ func getAndParseBuckets() ([]Bucket, error) {
 	return res, err
 }
 
-func getBucketByName(name string) (Bucket, error) {
+func findBucket(name string) (Bucket, error) {
 	buckets, err := getAndParseBuckets()
 	if err != nil {
 		return Bucket{}, err
@@ -42,9 +48,56 @@ func getBucketByName(name string) (Bucket, error) {
 	return Bucket{}, gofakes3.BucketNotFound(name)
 }
 
-func getDirEntries(path string) ([]model.Obj, error) {
-	ctx := context.Background()
+// getBucketByName returns the bucket with its path mapped into the base path of the request user
+func getBucketByName(ctx context.Context, name string) (Bucket, error) {
+	b, err := findBucket(name)
+	if err != nil {
+		return b, err
+	}
+	b.Path, err = bucketPath(ctx, b)
+	return b, err
+}
+
+func bucketPath(ctx context.Context, b Bucket) (string, error) {
+	user, ok := ctx.Value(conf.UserKey).(*model.User)
+	if !ok {
+		return b.Path, nil
+	}
+	return user.JoinPath(b.Path)
+}
+
+// validKey reports whether the key has no ".." segment, escaped or not
+func validKey(key string) bool {
+	keys := []string{key}
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		keys = append(keys, unescaped)
+	}
+	for _, k := range keys {
+		for _, seg := range strings.FieldsFunc(k, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if seg == ".." {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// objectPath joins the key to the bucket path, keys leaving the bucket are refused
+func objectPath(bucketPath, key string) (string, error) {
+	p := path.Join(bucketPath, key)
+	if !validKey(key) || !utils.IsSubPath(bucketPath, p) {
+		return "", gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "the key leaves the bucket")
+	}
+	return p, nil
+}
+
+// getDirEntries lists the dir as the user, the dirs the user can't access are treated as missing
+func getDirEntries(user *model.User, path string) ([]model.Obj, error) {
+	ctx := context.WithValue(context.Background(), conf.UserKey, user)
 	meta, _ := op.GetNearestMeta(path)
+	if !common.CanAccess(user, meta, path, "") {
+		return nil, gofakes3.ErrNoSuchKey
+	}
 	fi, err := fs.Get(context.WithValue(ctx, conf.MetaKey, meta), path, &fs.GetArgs{})
 	if errs.IsNotFoundError(err) {
 		return nil, gofakes3.ErrNoSuchKey
@@ -149,14 +202,3 @@ func prefixParser(p *gofakes3.Prefix) (path, remaining string) {
 // 		rmdirRecursive(dir, VFS)
 // 	}
 // }
-
-func authlistResolver() map[string]string {
-	s3accesskeyid := setting.GetStr(conf.S3AccessKeyId)
-	s3secretaccesskey := setting.GetStr(conf.S3SecretAccessKey)
-	if s3accesskeyid == "" && s3secretaccesskey == "" {
-		return nil
-	}
-	authList := make(map[string]string)
-	authList[s3accesskeyid] = s3secretaccesskey
-	return authList
-}
@@ -0,0 +1,19 @@
+package s3
+
+import "testing"
+
+func TestObjectPath(t *testing.T) {
+	for _, key := range []string{"a/b.txt", "dir/", "", "a..b/c", "..a"} {
+		if _, err := objectPath("/bucket", key); err != nil {
+			t.Errorf("key %q is refused: %v", key, err)
+		}
+	}
+	for _, key := range []string{"..", "../x", "a/../../x", "a/../b", "%2e%2e/x", "a/%2E%2E/x", `..\x`} {
+		if p, err := objectPath("/bucket", key); err == nil {
+			t.Errorf("key %q is joined to %s", key, p)
+		}
+	}
+	if p, _ := objectPath("/bucket", "a/./b"); p != "/bucket/a/b" {
+		t.Errorf("got %s", p)
+	}
+}
@@ -48,8 +48,12 @@ func (s *DriverAdapter) Stat(name string, _ bool) (*sftpd.Attr, error) {
 	return fileInfoToSftpAttr(stat), nil
 }
 
-func (s *DriverAdapter) SetStat(_ string, _ *sftpd.Attr) error {
-	return errs.NotSupport
+func (s *DriverAdapter) SetStat(name string, attr *sftpd.Attr) error {
+	// only the modification time can be changed, the other attributes are ignored
+	if attr.Flags&sftpd.ATTR_TIME == 0 {
+		return errs.NotSupport
+	}
+	return s.FtpDriver.Chtimes(name, attr.ATime, attr.MTime)
 }
 
 func (s *DriverAdapter) ReadLink(_ string) (string, error) {
@@ -29,7 +29,7 @@ func WebDav(dav *gin.RouterGroup) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
 	}
-	dav.Use(WebDAVAuth)
+	dav.Use(middlewares.ServedBytes("webdav"), WebDAVAuth)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
@@ -42,6 +42,7 @@ func WebDav(dav *gin.RouterGroup) {
 	dav.Handle("PROPPATCH", "/*path", ServeWebDAV)
 	dav.Handle("COPY", "/*path", ServeWebDAV)
 	dav.Handle("MOVE", "/*path", ServeWebDAV)
+	dav.Handle("REPORT", "/*path", ServeWebDAV)
 }
 
 func ServeWebDAV(c *gin.Context) {
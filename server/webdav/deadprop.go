@@ -0,0 +1,72 @@
+package webdav
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	log "github.com/sirupsen/logrus"
+)
+
+// The dead properties are kept in the database by the full path of the resources, and follow
+// them on the writes made through op, whichever server the writes come from.
+
+type deadProp struct {
+	Space    string `json:"space"`
+	Local    string `json:"local"`
+	Lang     string `json:"lang,omitempty"`
+	InnerXML string `json:"inner_xml"`
+}
+
+func loadDeadProps(name string) map[xml.Name]Property {
+	p, err := db.GetWebdavProps(name)
+	if err != nil {
+		return nil
+	}
+	var props []deadProp
+	if err := json.Unmarshal([]byte(p.PropsRaw), &props); err != nil {
+		log.Warnf("failed to parse webdav props of %s: %+v", name, err)
+		return nil
+	}
+	res := make(map[xml.Name]Property, len(props))
+	for _, dp := range props {
+		pn := xml.Name{Space: dp.Space, Local: dp.Local}
+		res[pn] = Property{XMLName: pn, Lang: dp.Lang, InnerXML: []byte(dp.InnerXML)}
+	}
+	return res
+}
+
+func saveDeadProps(name string, props map[xml.Name]Property) error {
+	if len(props) == 0 {
+		return db.DeleteWebdavProps(name)
+	}
+	list := make([]deadProp, 0, len(props))
+	for pn, p := range props {
+		list = append(list, deadProp{Space: pn.Space, Local: pn.Local, Lang: p.Lang, InnerXML: string(p.InnerXML)})
+	}
+	raw, _ := json.Marshal(list)
+	return db.SaveWebdavProps(&model.WebdavProps{Path: name, PropsRaw: string(raw)})
+}
+
+// onFsChange moves, copies and deletes the dead properties along with the resources
+func onFsChange(ctx context.Context, change model.FsChange) {
+	var err error
+	switch change.Op {
+	case model.FsChangeRemove:
+		err = db.DeleteWebdavPropsUnder(change.Path)
+	case model.FsChangeRename, model.FsChangeMove:
+		err = db.MoveWebdavProps(change.Path, change.DstPath)
+	case model.FsChangeCopy:
+		err = db.CopyWebdavProps(change.Path, change.DstPath)
+	}
+	if err != nil {
+		log.Warnf("failed to update webdav props of %s: %+v", change.Path, err)
+	}
+}
+
+func init() {
+	op.RegisterFsChangeHook(onFsChange)
+}
@@ -16,7 +16,9 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 )
@@ -109,6 +111,9 @@ var liveProps = map[xml.Name]struct {
 	findFn func(context.Context, LockSystem, string, model.Obj) (string, error)
 	// dir is true if the property applies to directories.
 	dir bool
+	// explicit is true if the property is left out of allprop, as it is
+	// expensive to find. See RFC 4331 section 3.
+	explicit bool
 }{
 	{Space: "DAV:", Local: "resourcetype"}: {
 		findFn: findResourceType,
@@ -165,34 +170,47 @@ var liveProps = map[xml.Name]struct {
 		findFn: findChecksums,
 		dir:    false,
 	},
+	{Space: "DAV:", Local: "quota-available-bytes"}: {
+		findFn:   findQuotaAvailableBytes,
+		dir:      true,
+		explicit: true,
+	},
+	{Space: "DAV:", Local: "quota-used-bytes"}: {
+		findFn:   findQuotaUsedBytes,
+		dir:      true,
+		explicit: true,
+	},
+}
+
+// modTimeProps are the properties clients set the modification time with, Windows
+// sets the Win32 one after every upload. They are applied to the storages that can
+// set the modification time, the Win32 one is kept as a dead property by the others.
+var modTimeProps = map[xml.Name]bool{
+	{Space: "DAV:", Local: "getlastmodified"}:                             true,
+	{Space: "urn:schemas-microsoft-com:", Local: "Win32LastModifiedTime"}: true,
 }
 
+// errPropNotFound is returned by the findFn of properties that are not
+// available for a resource, they are reported as 404 Not Found.
+var errPropNotFound = errors.New("property not found")
+
 // TODO(nigeltao) merge props and allprop?
 
 // Props returns the status of the properties named pnames for resource name.
 //
 // Each Propstat has a unique status and each property name will only be part
 // of one Propstat element.
-func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name) ([]Propstat, error) {
-	//f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//fi, err := f.Stat()
-	//if err != nil {
-	//	return nil, err
-	//}
+func props(ctx context.Context, ls LockSystem, name string, fi model.Obj, pnames []xml.Name) ([]Propstat, error) {
 	isDir := fi.IsDir()
 
 	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//}
+	// Only look the dead properties up if some of the names are not live ones.
+	for _, pn := range pnames {
+		if _, ok := liveProps[pn]; !ok {
+			deadProps = loadDeadProps(name)
+			break
+		}
+	}
 
 	pstatOK := Propstat{Status: http.StatusOK}
 	pstatNotFound := Propstat{Status: http.StatusNotFound}
@@ -204,7 +222,13 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 		}
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
-			innerXML, err := prop.findFn(ctx, ls, fi.GetName(), fi)
+			innerXML, err := prop.findFn(ctx, ls, name, fi)
+			if errors.Is(err, errPropNotFound) {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{
+					XMLName: pn,
+				})
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -222,26 +246,9 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 }
 
 // Propnames returns the property names defined for resource name.
-func propnames(ctx context.Context, ls LockSystem, fi model.Obj) ([]xml.Name, error) {
-	//f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//fi, err := f.Stat()
-	//if err != nil {
-	//	return nil, err
-	//}
+func propnames(ctx context.Context, ls LockSystem, name string, fi model.Obj) ([]xml.Name, error) {
 	isDir := fi.IsDir()
-
-	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//}
+	deadProps := loadDeadProps(name)
 
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
@@ -263,91 +270,153 @@ func propnames(ctx context.Context, ls LockSystem, fi model.Obj) ([]xml.Name, er
 // returned if they are named in 'include'.
 //
 // See http://www.webdav.org/specs/rfc4918.html#METHOD_PROPFIND
-func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Name) ([]Propstat, error) {
-	pnames, err := propnames(ctx, ls, fi)
+func allprop(ctx context.Context, ls LockSystem, name string, fi model.Obj, include []xml.Name) ([]Propstat, error) {
+	names, err := propnames(ctx, ls, name, fi)
 	if err != nil {
 		return nil, err
 	}
 	// Add names from include if they are not already covered in pnames.
+	pnames := make([]xml.Name, 0, len(names)+len(include))
 	nameset := make(map[xml.Name]bool)
-	for _, pn := range pnames {
-		nameset[pn] = true
+	for _, pn := range names {
+		if !liveProps[pn].explicit {
+			pnames = append(pnames, pn)
+			nameset[pn] = true
+		}
 	}
 	for _, pn := range include {
 		if !nameset[pn] {
 			pnames = append(pnames, pn)
 		}
 	}
-	return props(ctx, ls, fi, pnames)
+	return props(ctx, ls, name, fi, pnames)
 }
 
 // Patch patches the properties of resource name. The return values are
 // constrained in the same manner as DeadPropsHolder.Patch.
 func patch(ctx context.Context, ls LockSystem, name string, patches []Proppatch) ([]Propstat, error) {
-	conflict := false
-loop:
+	pstatForbidden := Propstat{
+		Status:   http.StatusForbidden,
+		XMLError: `<D:cannot-modify-protected-property xmlns:D="DAV:"/>`,
+	}
+	pstatConflict := Propstat{Status: http.StatusConflict}
+	var modTime time.Time
 	for _, patch := range patches {
 		for _, p := range patch.Props {
-			if _, ok := liveProps[p.XMLName]; ok {
-				conflict = true
-				break loop
+			_, live := liveProps[p.XMLName]
+			switch {
+			case modTimeProps[p.XMLName] && !patch.Remove:
+				t, err := parseModTime(string(p.InnerXML))
+				if err != nil {
+					pstatConflict.Props = append(pstatConflict.Props, Property{XMLName: p.XMLName})
+				} else {
+					modTime = t
+				}
+			case live:
+				pstatForbidden.Props = append(pstatForbidden.Props, Property{XMLName: p.XMLName})
 			}
 		}
 	}
-	if conflict {
-		pstatForbidden := Propstat{
-			Status:   http.StatusForbidden,
-			XMLError: `<D:cannot-modify-protected-property xmlns:D="DAV:"/>`,
-		}
-		pstatFailedDep := Propstat{
-			Status: StatusFailedDependency,
-		}
-		for _, patch := range patches {
-			for _, p := range patch.Props {
-				if _, ok := liveProps[p.XMLName]; ok {
-					pstatForbidden.Props = append(pstatForbidden.Props, Property{XMLName: p.XMLName})
-				} else {
-					pstatFailedDep.Props = append(pstatFailedDep.Props, Property{XMLName: p.XMLName})
+	if len(pstatForbidden.Props) != 0 || len(pstatConflict.Props) != 0 {
+		return failedPatch(patches, pstatForbidden, pstatConflict), nil
+	}
+
+	modTimeSet := false
+	if !modTime.IsZero() {
+		err := setModTime(ctx, name, modTime)
+		switch {
+		case err == nil:
+			modTimeSet = true
+		case errs.IsNotImplementError(err):
+			// DAV:getlastmodified stays protected, the Win32 one is kept as a dead property
+			for _, patch := range patches {
+				for _, p := range patch.Props {
+					if _, live := liveProps[p.XMLName]; live {
+						pstatForbidden.Props = append(pstatForbidden.Props, Property{XMLName: p.XMLName})
+					}
 				}
 			}
+			if len(pstatForbidden.Props) != 0 {
+				return failedPatch(patches, pstatForbidden), nil
+			}
+		default:
+			return nil, err
 		}
-		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	// ------------------------------------------------------------
-	//f, err := fs.OpenFile(ctx, name, os.O_RDWR, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	ret, err := dph.Patch(patches)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
-	//	// "The contents of the prop XML element must only list the names of
-	//	// properties to which the result in the status element applies."
-	//	for _, pstat := range ret {
-	//		for i, p := range pstat.Props {
-	//			pstat.Props[i] = Property{XMLName: p.XMLName}
-	//		}
-	//	}
-	//	return ret, nil
-	//}
-	// ------------------------------------------------------------
-
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
-	pstat := Propstat{Status: http.StatusForbidden}
+	deadProps := loadDeadProps(name)
+	if deadProps == nil {
+		deadProps = make(map[xml.Name]Property)
+	}
+	pstat := Propstat{Status: http.StatusOK}
 	for _, patch := range patches {
 		for _, p := range patch.Props {
+			// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
+			// "The contents of the prop XML element must only list the names of
+			// properties to which the result in the status element applies."
 			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+			switch _, live := liveProps[p.XMLName]; {
+			case live:
+			case modTimeSet && modTimeProps[p.XMLName]:
+				// the modification time of the resource is what it reads now
+				delete(deadProps, p.XMLName)
+			case patch.Remove:
+				delete(deadProps, p.XMLName)
+			default:
+				deadProps[p.XMLName] = p
+			}
 		}
 	}
+	if err := saveDeadProps(name, deadProps); err != nil {
+		return nil, err
+	}
 	return []Propstat{pstat}, nil
 }
 
+// failedPatch reports the failed properties of a patch, the others fail with
+// 424 Failed Dependency as patching is atomic.
+func failedPatch(patches []Proppatch, failed ...Propstat) []Propstat {
+	var pstats []Propstat
+	names := make(map[xml.Name]bool)
+	for _, pstat := range failed {
+		if len(pstat.Props) == 0 {
+			continue
+		}
+		pstats = append(pstats, pstat)
+		for _, p := range pstat.Props {
+			names[p.XMLName] = true
+		}
+	}
+	pstatFailedDep := Propstat{Status: StatusFailedDependency}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			if !names[p.XMLName] {
+				pstatFailedDep.Props = append(pstatFailedDep.Props, Property{XMLName: p.XMLName})
+			}
+		}
+	}
+	if len(pstatFailedDep.Props) != 0 {
+		pstats = append(pstats, pstatFailedDep)
+	}
+	return pstats
+}
+
+func parseModTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := http.ParseTime(s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func setModTime(ctx context.Context, name string, modTime time.Time) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(name)
+	if err != nil {
+		return errs.NotImplement
+	}
+	return op.SetModTime(ctx, storage, actualPath, modTime)
+}
+
 func escapeXML(s string) string {
 	for i := 0; i < len(s); i++ {
 		// As an optimization, if s contains only ASCII letters, digits or a
@@ -493,3 +562,36 @@ func findChecksums(ctx context.Context, ls LockSystem, name string, fi model.Obj
 	}
 	return checksums, nil
 }
+
+// quota returns the disk usage of the storage the resource is in. The mount
+// points listed in a virtual dir carry the usage of their storages already.
+func quota(ctx context.Context, name string, fi model.Obj) (*model.DiskUsage, error) {
+	if details, ok := model.GetStorageDetails(fi); ok && details.StorageDetails != nil {
+		return &details.DiskUsage, nil
+	}
+	storage, _, err := op.GetStorageAndActualPath(name)
+	if err != nil {
+		return nil, errPropNotFound
+	}
+	details, err := op.GetStorageDetails(ctx, storage)
+	if err != nil || details.TotalSpace == 0 {
+		return nil, errPropNotFound
+	}
+	return &details.DiskUsage, nil
+}
+
+func findQuotaAvailableBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	du, err := quota(ctx, name, fi)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(du.FreeSpace, 10), nil
+}
+
+func findQuotaUsedBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	du, err := quota(ctx, name, fi)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(du.TotalSpace-min(du.FreeSpace, du.TotalSpace), 10), nil
+}
@@ -0,0 +1,66 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPatchRejected(t *testing.T) {
+	custom := xml.Name{Space: "http://example.com/ns", Local: "color"}
+	win32 := xml.Name{Space: "urn:schemas-microsoft-com:", Local: "Win32LastModifiedTime"}
+	length := xml.Name{Space: "DAV:", Local: "getcontentlength"}
+	testCases := []struct {
+		desc    string
+		patches []Proppatch
+		want    map[int][]xml.Name
+	}{{
+		desc: "live property",
+		patches: []Proppatch{{Props: []Property{
+			{XMLName: length, InnerXML: []byte("1")},
+			{XMLName: custom, InnerXML: []byte("red")},
+		}}},
+		want: map[int][]xml.Name{
+			http.StatusForbidden:   {length},
+			StatusFailedDependency: {custom},
+		},
+	}, {
+		desc: "invalid modification time",
+		patches: []Proppatch{{Props: []Property{
+			{XMLName: custom, InnerXML: []byte("red")},
+			{XMLName: win32, InnerXML: []byte("yesterday")},
+		}}},
+		want: map[int][]xml.Name{
+			http.StatusConflict:    {win32},
+			StatusFailedDependency: {custom},
+		},
+	}}
+	for _, tc := range testCases {
+		pstats, err := patch(context.Background(), nil, "/test", tc.patches)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		got := make(map[int][]xml.Name)
+		for _, pstat := range pstats {
+			for _, p := range pstat.Props {
+				got[pstat.Status] = append(got[pstat.Status], p.XMLName)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestParseModTime(t *testing.T) {
+	want := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	for _, s := range []string{"Wed, 21 Oct 2015 07:28:00 GMT", " 2015-10-21T07:28:00Z "} {
+		got, err := parseModTime(s)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseModTime(%q) = %v, %v", s, got, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	ixml "github.com/OpenListTeam/OpenList/v4/server/webdav/internal/xml"
+)
+
+// A lite DeltaV (RFC 3253) view of the file versions, only the version-tree REPORT is supported,
+// each version is reported with the href of its file with a version query and a DAV:version-name property,
+// and GET of the href downloads the version.
+
+var versionNameProp = xml.Name{Space: "DAV:", Local: "version-name"}
+
+type versionTree struct {
+	XMLName ixml.Name     `xml:"DAV: version-tree"`
+	Prop    propfindProps `xml:"DAV: prop"`
+}
+
+func readVersionTree(r io.Reader) (vt versionTree, status int, err error) {
+	c := countingReader{r: r}
+	if err = ixml.NewDecoder(&c).Decode(&vt); err != nil {
+		if err == io.EOF && c.n == 0 {
+			// An empty body reports the version names only
+			return versionTree{Prop: propfindProps{versionNameProp}}, 0, nil
+		}
+		return versionTree{}, http.StatusBadRequest, err
+	}
+	return vt, 0, nil
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) (status int, err error) {
+	reqPath, status, err := h.stripPrefix(r.URL.Path)
+	if err != nil {
+		return status, err
+	}
+	ctx := r.Context()
+	user := ctx.Value(conf.UserKey).(*model.User)
+	reqPath, err = user.JoinPath(reqPath)
+	if err != nil {
+		return 403, err
+	}
+	vt, status, err := readVersionTree(r.Body)
+	if err != nil {
+		return status, err
+	}
+	versions, err := fs.Versions(ctx, reqPath)
+	if err != nil {
+		if errs.IsNotFoundError(err) {
+			return http.StatusNotFound, err
+		}
+		if err == errs.NotSupport || errs.IsNotImplementError(err) {
+			return http.StatusNotImplemented, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	var pnames []xml.Name
+	withName := false
+	for _, pn := range vt.Prop {
+		if pn == versionNameProp {
+			withName = true
+		} else {
+			pnames = append(pnames, pn)
+		}
+	}
+
+	mw := multistatusWriter{w: w}
+	fileHref := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.BasePath))
+	for _, v := range versions {
+		obj := &model.Object{
+			Name:     path.Base(reqPath),
+			Path:     reqPath,
+			Size:     v.Size,
+			Modified: v.Modified,
+		}
+		pstats, err := props(ctx, h.LockSystem, reqPath, obj, pnames)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if withName {
+			prop := Property{XMLName: versionNameProp, InnerXML: []byte(escape(v.Name))}
+			if len(pstats) > 0 && pstats[0].Status == http.StatusOK {
+				pstats[0].Props = append(pstats[0].Props, prop)
+			} else {
+				pstats = append([]Propstat{{Status: http.StatusOK, Props: []Property{prop}}}, pstats...)
+			}
+		}
+		href := fileHref + "?version=" + url.QueryEscape(v.Name)
+		if err = mw.write(makePropstatResponse(href, pstats)); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	if err = mw.close(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return 0, nil
+}
+
+// serveVersion downloads a version of the file at the full path
+func serveVersion(w http.ResponseWriter, r *http.Request, reqPath, version string) (int, error) {
+	link, obj, err := fs.VersionLink(r.Context(), reqPath, version, model.LinkArgs{Header: r.Header})
+	if err != nil {
+		if errs.IsNotFoundError(err) {
+			return http.StatusNotFound, err
+		}
+		if err == errs.NotSupport || errs.IsNotImplementError(err) {
+			return http.StatusNotImplemented, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	defer link.Close()
+	if err = common.Proxy(w, r, link, obj); err != nil {
+		if statusCode, ok := errs.UnwrapOrSelf(err).(net.HttpStatusCodeError); ok {
+			return int(statusCode), err
+		}
+		return http.StatusInternalServerError, fmt.Errorf("webdav proxy error: %+v", err)
+	}
+	return 0, nil
+}